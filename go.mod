@@ -6,9 +6,6 @@ require (
 	github.com/aquilax/go-perlin v1.1.0
 	github.com/fogleman/gg v1.3.0
 	github.com/go-gl/mathgl v1.2.0
-)
-
-require (
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	golang.org/x/image v0.31.0 // indirect
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	golang.org/x/image v0.31.0
 )
@@ -18,6 +18,11 @@ type BspNode struct {
 	xp               []float32
 	yp               []float32
 	normalIndex      int
+	// vertexNormalIndices holds a vertex normal index per corner once
+	// Model.ComputeVertexNormals has run.
+	vertexNormalIndices []int
+	// uvs holds a texture coordinate per corner, or nil.
+	uvs []UV
 }
 
 // NewBspNode creates a new BSP node.
@@ -45,12 +50,13 @@ func (b *BspNode) SetColor(r, g, b1, a uint8) {
 }
 
 // PaintWithoutShading paints the BSP tree without lighting effects.
-func (b *BspNode) PaintWithoutShading(batcher PolygonBatcher, x, y int, transPoints []Vector3, transNormals []Vector3, linesOnly bool, screenWidth, screenHeight float32, dontDrawOutlines bool, nearPlane float64, ctx *RenderContext) {
-	b.PaintWithShading(batcher, x, y, transPoints, transNormals, false, linesOnly, screenWidth, screenHeight, dontDrawOutlines, nearPlane, ctx)
+func (b *BspNode) PaintWithoutShading(batcher PolygonBatcher, x, y int, transPoints []Vector3, transNormals []Vector3, linesOnly bool, screenWidth, screenHeight float32, dontDrawOutlines bool, proj *Projection, ctx *RenderContext) {
+	b.PaintWithShading(batcher, x, y, transPoints, transNormals, false, false, linesOnly, screenWidth, screenHeight, dontDrawOutlines, proj, ctx)
 }
 
 // PaintWithShading recursively traverses the BSP tree and paints the polygons.
-func (b *BspNode) PaintWithShading(batcher PolygonBatcher, x, y int, transPoints []Vector3, transNormals []Vector3, doShading bool, linesOnly bool, screenWidth, screenHeight float32, dontDrawOutlines bool, nearPlane float64, ctx *RenderContext) {
+// With smooth set, polygons that have vertex normals are Gouraud shaded.
+func (b *BspNode) PaintWithShading(batcher PolygonBatcher, x, y int, transPoints []Vector3, transNormals []Vector3, doShading bool, smooth bool, linesOnly bool, screenWidth, screenHeight float32, dontDrawOutlines bool, proj *Projection, ctx *RenderContext) {
 	if len(b.facePointIndices) == 0 {
 		return
 	}
@@ -59,29 +65,30 @@ func (b *BspNode) PaintWithShading(batcher PolygonBatcher, x, y int, transPoints
 	firstTransformedPoint := transPoints[b.facePointIndices[0]]
 
 	// Determine if the polygon is facing the camera
-	where := transformedNormal.X*firstTransformedPoint.X +
-		transformedNormal.Y*firstTransformedPoint.Y +
-		transformedNormal.Z*firstTransformedPoint.Z
+	where := proj.FacingAmount(transformedNormal, firstTransformedPoint)
 
 	if where <= 0 { // Facing away from the camera
 		if b.Left != nil {
-			b.Left.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, linesOnly, screenWidth, screenHeight, dontDrawOutlines, nearPlane, ctx)
+			b.Left.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, smooth, linesOnly, screenWidth, screenHeight, dontDrawOutlines, proj, ctx)
+		}
+		if !ctx.Options.cullBackFaces(true) {
+			b.paintPoly(batcher, x, y, transPoints, transNormals, doShading, smooth, firstTransformedPoint, transformedNormal, linesOnly, screenWidth, screenHeight, dontDrawOutlines, proj, ctx)
 		}
 		if b.Right != nil {
-			b.Right.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, linesOnly, screenWidth, screenHeight, dontDrawOutlines, nearPlane, ctx)
+			b.Right.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, smooth, linesOnly, screenWidth, screenHeight, dontDrawOutlines, proj, ctx)
 		}
 	} else { // Facing towards the camera
 		if b.Right != nil {
-			b.Right.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, linesOnly, screenWidth, screenHeight, dontDrawOutlines, nearPlane, ctx)
+			b.Right.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, smooth, linesOnly, screenWidth, screenHeight, dontDrawOutlines, proj, ctx)
 		}
 
-		shouldReturn := b.paintPoly(batcher, x, y, transPoints, transNormals, doShading, firstTransformedPoint, transformedNormal, linesOnly, screenWidth, screenHeight, dontDrawOutlines, nearPlane, ctx)
+		shouldReturn := b.paintPoly(batcher, x, y, transPoints, transNormals, doShading, smooth, firstTransformedPoint, transformedNormal, linesOnly, screenWidth, screenHeight, dontDrawOutlines, proj, ctx)
 		if shouldReturn {
 			return // Z-clipping occurred, no need to paint left side
 		}
 
 		if b.Left != nil {
-			b.Left.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, linesOnly, screenWidth, screenHeight, dontDrawOutlines, nearPlane, ctx)
+			b.Left.PaintWithShading(batcher, x, y, transPoints, transNormals, doShading, smooth, linesOnly, screenWidth, screenHeight, dontDrawOutlines, proj, ctx)
 		}
 	}
 }
@@ -143,12 +150,13 @@ func (b *BspNode) paintPoly(
 	verticesInCameraSpace []Vector3,
 	normalsInCameraSpace []Vector3,
 	shadePoly bool,
+	smooth bool,
 	firstTransformedPoint Vector3,
 	transformedNormal Vector3,
 	linesOnly bool,
 	screenWidth, screenHeight float32,
 	dontDrawwOutlines bool,
-	nearPlane float64,
+	proj *Projection,
 	ctx *RenderContext,
 ) bool {
 
@@ -157,111 +165,68 @@ func (b *BspNode) paintPoly(
 		initial3DPoints = append(initial3DPoints, verticesInCameraSpace[pointIndex])
 	}
 
-	buffer := ctx.Buffer3D[len(initial3DPoints):len(initial3DPoints)]
-	pointsToUse := clipPolygonAgainstNearPlane(initial3DPoints, nearPlane, buffer)
-
-	// If clipping results in a polygon with too few vertices, don't draw it.
-	if len(pointsToUse) < 3 {
+	if ctx.texture != nil && !linesOnly && len(b.uvs) == len(initial3DPoints) {
+		var vertexNormals []int
+		if smooth {
+			vertexNormals = b.vertexNormalIndices
+		}
+		attrs := lightTexturedVertices(initial3DPoints, b.uvs, transformedNormal, vertexNormals, normalsInCameraSpace, shadePoly, ctx)
+		if dontDrawwOutlines {
+			addTexturedPolygon(batcher, initial3DPoints, attrs, ctx.texture, proj, screenWidth, screenHeight, color.RGBA{}, 0, false, ctx)
+		} else {
+			grey := color.RGBA{R: 100, G: 100, B: 100, A: 25}
+			strokeClr, strokeWidth, hasStroke := ctx.outline(grey)
+			addTexturedPolygon(batcher, initial3DPoints, attrs, ctx.texture, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
+		}
 		return false
 	}
 
-	ctx.BufferPoints = ctx.BufferPoints[:0]
-	for _, point := range pointsToUse {
-		// At this stage, point[2] (z) is guaranteed to be >= nearPlaneZ,
-		// so perspective division is safe.
-		z := float32(point.Z)
-		ctx.BufferPoints = append(ctx.BufferPoints, Point{
-			X: ConvertToScreenX(float64(screenWidth), float64(screenHeight), point.X, float64(z)),
-			Y: ConvertToScreenY(float64(screenWidth), float64(screenHeight), point.Y, float64(z)),
-		})
+	if shadePoly && smooth && !linesOnly && b.vertexNormalIndices != nil {
+		polyColor := color.RGBA{R: b.colRed, G: b.colGreen, B: b.colBlue, A: b.colAlpha}
+		attrs := shadeVertices(initial3DPoints, b.vertexNormalIndices, normalsInCameraSpace, polyColor, ctx)
+		if dontDrawwOutlines {
+			addGouraudPolygon(batcher, initial3DPoints, attrs, proj, screenWidth, screenHeight, color.RGBA{}, 0, false, ctx)
+		} else {
+			grey := color.RGBA{R: 100, G: 100, B: 100, A: 25}
+			strokeClr, strokeWidth, hasStroke := ctx.outline(grey)
+			addGouraudPolygon(batcher, initial3DPoints, attrs, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
+		}
+		return false
 	}
 
-	clippedPoints := batcher.ClipPolygon(ctx.BufferPoints, screenWidth, screenHeight)
+	pointsToUse := proj.Frustum.ClipPolygon(initial3DPoints, ctx.ClipBufA, ctx.ClipBufB)
 
-	if len(clippedPoints) < 3 {
+	// If clipping results in a polygon with too few vertices, don't draw it.
+	if len(pointsToUse) < 3 {
 		return false
 	}
 
-	ctx.BufferFloatX = ctx.BufferFloatX[:0]
-	ctx.BufferFloatY = ctx.BufferFloatY[:0]
-	for _, p := range clippedPoints {
-		ctx.BufferFloatX = append(ctx.BufferFloatX, p.X)
-		ctx.BufferFloatY = append(ctx.BufferFloatY, p.Y)
-	}
-
-	// Copy the data to new slices so the batcher can store them
-	finalScreenPointsX := make([]float32, len(ctx.BufferFloatX))
-	copy(finalScreenPointsX, ctx.BufferFloatX)
-	finalScreenPointsY := make([]float32, len(ctx.BufferFloatY))
-	copy(finalScreenPointsY, ctx.BufferFloatY)
-
 	polyColor := color.RGBA{R: b.colRed, G: b.colGreen, B: b.colBlue, A: b.colAlpha}
 	if shadePoly {
 		shadingRefPoint := verticesInCameraSpace[b.facePointIndices[0]]
-		polyColor = b.calcColor(shadingRefPoint, transformedNormal, polyColor)
+		polyColor = ctx.shade(shadingRefPoint, transformedNormal, polyColor)
 	}
+	polyColor = ctx.fog.apply(polyColor, firstTransformedPoint.Z)
 
 	if !linesOnly {
 		if dontDrawwOutlines {
-			batcher.AddPolygon(finalScreenPointsX, finalScreenPointsY, polyColor)
+			addPolygon(batcher, pointsToUse, proj, screenWidth, screenHeight, polyColor, color.RGBA{}, 0, false, ctx)
 		} else {
 			black := color.RGBA{R: 100, G: 100, B: 100, A: 25}
-			batcher.AddPolygonAndOutline(finalScreenPointsX, finalScreenPointsY, polyColor, black, 1.0)
+			strokeClr, strokeWidth, hasStroke := ctx.outline(black)
+			addPolygon(batcher, pointsToUse, proj, screenWidth, screenHeight, polyColor, strokeClr, strokeWidth, hasStroke, ctx)
 		}
 	} else {
 
 		black := color.RGBA{R: 0, G: 0, B: 0, A: 255}
-		batcher.AddPolygonAndOutline(finalScreenPointsX, finalScreenPointsY, black, polyColor, 1.0)
+		lineClr := ctx.wireframeColor(polyColor, firstTransformedPoint.Z)
+		addPolygon(batcher, pointsToUse, proj, screenWidth, screenHeight, black, lineClr, ctx.outlineWidth(), true, ctx)
 
 	}
 
 	return false
 }
 
-// calcColor calculates the color of a polygon based on simple lighting.
-func (b *BspNode) calcColor(
-	firstTransformedPoint Vector3,
-	transformedNormal Vector3,
-	polyColor color.RGBA,
-) color.RGBA {
-	const ambientLight = 0.65
-	const spotlightConePower = 10.0
-	const spotlightLightAmount = 1.0 - ambientLight
-
-	diffuseFactor := transformedNormal.Z
-	if diffuseFactor < 0 {
-		diffuseFactor = 0
-	}
-
-	var spotlightFactor float64
-	lenVecToPoint := GetLength2(firstTransformedPoint)
-
-	if lenVecToPoint > 0 {
-		cosAngle := firstTransformedPoint.Z / lenVecToPoint
-		if cosAngle < 0 {
-			cosAngle = 0
-		}
-		v2 := cosAngle * cosAngle
-		v4 := v2 * v2
-		v8 := v4 * v4
-		spotlightFactor = v8 * v2
-	} else {
-		spotlightFactor = 1.0
-	}
-
-	spotlightBrightness := diffuseFactor * spotlightFactor * spotlightLightAmount
-	finalBrightness := ambientLight + spotlightBrightness
-
-	c := 240 - int(finalBrightness*240)
-	min := 7
-	r1 := clamp2(int(b.colRed)-c, min, 255)
-	g1 := clamp2(int(b.colGreen)-c, min, 255)
-	b1 := clamp2(int(b.colBlue)-c, min, 255)
-	polyColor = color.RGBA{R: uint8(r1), G: uint8(g1), B: uint8(b1), A: polyColor.A}
-
-	return polyColor
-}
-
 // --- Helper functions ---
 
 func clamp2(value, min, max int) int {
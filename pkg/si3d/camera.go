@@ -11,12 +11,113 @@ type Camera struct {
 	cameraPosition Vector3
 	cameraRotation mgl64.Quat
 	NearPlane      float64
+	// FarPlane is the far clipping distance. 0 disables far-plane clipping.
+	FarPlane float64
+	// FieldOfView is the vertical field of view in radians. 0 keeps the
+	// legacy projection where the focal length equals the screen width.
+	FieldOfView float64
+	// AspectRatio is the horizontal to vertical view extent used with
+	// FieldOfView. 0 uses the aspect ratio of the target image.
+	AspectRatio float64
+	// PrincipalOffsetX and PrincipalOffsetY move the principal point away
+	// from the centre of the screen, in pixels.
+	PrincipalOffsetX float64
+	PrincipalOffsetY float64
+	// Orthographic switches to a parallel projection whose view volume is
+	// OrthoHeight world units tall.
+	Orthographic bool
+	OrthoHeight  float64
 }
 
 func (c *Camera) GetNearPlane() float64 {
 	return c.NearPlane
 }
 
+func (c *Camera) GetFarPlane() float64 {
+	return c.FarPlane
+}
+
+// SetFieldOfViewDegrees sets the vertical field of view in degrees.
+func (c *Camera) SetFieldOfViewDegrees(degrees float64) {
+	c.FieldOfView = degreesToRadians(degrees)
+}
+
+// SetPrincipalPoint sets the principal point offset from the screen centre.
+func (c *Camera) SetPrincipalPoint(offsetX, offsetY float64) {
+	c.PrincipalOffsetX = offsetX
+	c.PrincipalOffsetY = offsetY
+}
+
+// SetOrthographic switches the camera to a parallel projection with a view
+// volume viewHeight world units tall. A viewHeight that is not positive
+// renders one world unit per pixel, as NewOrthographicProjection does.
+func (c *Camera) SetOrthographic(viewHeight float64) {
+	c.Orthographic = true
+	c.OrthoHeight = viewHeight
+}
+
+// SetPerspective switches the camera back to a perspective projection.
+func (c *Camera) SetPerspective() {
+	c.Orthographic = false
+}
+
+// Projection returns the projection and clipping volume for a screen of the
+// given size.
+func (c *Camera) Projection(screenWidth, screenHeight float32) *Projection {
+	return c.scaledProjection(screenWidth, screenHeight, 1)
+}
+
+// scaledProjection is Projection for a screen with scale pixels per pixel of
+// the screen the principal point offsets were given for, as in supersampled
+// renders.
+func (c *Camera) scaledProjection(screenWidth, screenHeight float32, scale float64) *Projection {
+	offsetX, offsetY := c.PrincipalOffsetX*scale, c.PrincipalOffsetY*scale
+	if c.Orthographic {
+		return NewOrthographicProjection(screenWidth, screenHeight, c.OrthoHeight, c.AspectRatio,
+			offsetX, offsetY, c.NearPlane, c.FarPlane)
+	}
+	return NewProjection(screenWidth, screenHeight, c.FieldOfView, c.AspectRatio,
+		offsetX, offsetY, c.NearPlane, c.FarPlane)
+}
+
+// Project finds where a world space point lands on a screen of the given
+// size, using the same projection as a render. x and y are in pixels, with
+// pixel (i, j) covering [i, i+1) by [j, j+1), and depth is the point's
+// distance along the view axis. visible reports whether the point is in
+// front of the near plane, not beyond the far plane and on the screen.
+func (c *Camera) Project(world Vector3, width, height int) (x, y, depth float64, visible bool) {
+	p := c.camMatrixRev.TransformPoint(world)
+	proj := c.Projection(float32(width), float32(height))
+
+	depth = p.Z
+	if proj.Orthographic {
+		x = proj.FocalX*p.X + proj.CenterX
+		y = proj.FocalY*p.Y + proj.CenterY
+	} else {
+		x = proj.FocalX*p.X/p.Z + proj.CenterX
+		y = proj.FocalY*p.Y/p.Z + proj.CenterY
+	}
+
+	visible = depth >= c.NearPlane && (c.FarPlane <= 0 || depth <= c.FarPlane) &&
+		x >= 0 && x < float64(width) && y >= 0 && y < float64(height)
+	return x, y, depth, visible
+}
+
+// Unproject returns the world space view ray through screen point (x, y) of
+// a screen of the given size, the inverse of Project. dir is a unit vector.
+// Perspective rays start at the camera; orthographic rays start on the plane
+// through the camera facing along the view axis. Add 0.5 to pixel
+// coordinates for the ray through a pixel's centre.
+func (c *Camera) Unproject(x, y float64, width, height int) (origin, dir Vector3) {
+	proj := c.Projection(float32(width), float32(height))
+	camOrigin, camDir := proj.ray(x, y)
+
+	camToWorld := c.camMatrixRev.Inverse()
+	origin = camToWorld.TransformPoint(camOrigin)
+	dir = camToWorld.RotateVector3(camDir).Normalize()
+	return origin, dir
+}
+
 func NewCamera(xp, yp, zp, xa, ya, za float64) *Camera {
 	c := &Camera{}
 	c.NearPlane = 10.0
@@ -156,3 +257,74 @@ func (c *Camera) AddAngle(x, y, z float64) {
 
 	c.updateMatrix()
 }
+
+// OrthographicView selects one of the preset orthographic camera directions.
+type OrthographicView int
+
+const (
+	// OrthoFront looks along +Z at the front of the model.
+	OrthoFront OrthographicView = iota
+	// OrthoTop looks down on the model from above (-Y is up).
+	OrthoTop
+	// OrthoSide looks along -X at the right-hand side of the model.
+	OrthoSide
+	// OrthoIsometric looks down at the front right corner of the model.
+	OrthoIsometric
+)
+
+// NewOrthographicCamera creates an orthographic camera for one of the preset
+// engineering views. The camera is placed distance units from target and its
+// view volume is viewHeight world units tall.
+func NewOrthographicCamera(view OrthographicView, target Vector3, distance, viewHeight float64) *Camera {
+	var forward, down Vector3
+	switch view {
+	case OrthoTop:
+		forward = NewVector3(0, 1, 0)
+		down = NewVector3(0, 0, -1)
+	case OrthoSide:
+		forward = NewVector3(-1, 0, 0)
+		down = NewVector3(0, 1, 0)
+	case OrthoIsometric:
+		forward = NewVector3(-1, 1, 1).Normalize()
+		down = NewVector3(0, 1, 0)
+	default:
+		forward = NewVector3(0, 0, 1)
+		down = NewVector3(0, 1, 0)
+	}
+
+	pos := NewVector3(
+		target.X-forward.X*distance,
+		target.Y-forward.Y*distance,
+		target.Z-forward.Z*distance,
+	)
+
+	c := &Camera{
+		camMatrixRev:   cameraBasisMatrix(pos, forward, down),
+		cameraPosition: pos,
+		cameraRotation: mgl64.QuatIdent(),
+		NearPlane:      10.0,
+	}
+	c.SetOrthographic(viewHeight)
+
+	return c
+}
+
+// cameraBasisMatrix builds a world to camera matrix for a camera at pos
+// looking along forward, with down pointing towards the bottom of the screen.
+// down is made perpendicular to forward before it is used.
+func cameraBasisMatrix(pos, forward, down Vector3) Matrix {
+	forward = forward.Normalize()
+	d := Dot(down, forward)
+	down = NewVector3(down.X-forward.X*d, down.Y-forward.Y*d, down.Z-forward.Z*d).Normalize()
+	right := Cross(down, forward)
+
+	m := IdentMatrix()
+	axes := [3]Vector3{right, down, forward}
+	for j, axis := range axes {
+		m.ThisMatrix[0][j] = axis.X
+		m.ThisMatrix[1][j] = axis.Y
+		m.ThisMatrix[2][j] = axis.Z
+		m.ThisMatrix[3][j] = -Dot(axis, pos)
+	}
+	return m
+}
@@ -118,3 +118,129 @@ func TestCamera_AddAngle(t *testing.T) {
 	// Check if matrix updated (smoke test)
 	c.GetMatrix()
 }
+
+func TestNewOrthographicCamera(t *testing.T) {
+	target := NewVector3(10, 20, 30)
+	views := []OrthographicView{OrthoFront, OrthoTop, OrthoSide, OrthoIsometric}
+
+	for _, view := range views {
+		c := NewOrthographicCamera(view, target, 500, 200)
+		if !c.Orthographic || c.OrthoHeight != 200 {
+			t.Errorf("view %d: expected orthographic camera with height 200", view)
+		}
+
+		// The target must sit on the view axis, distance units in front.
+		m := c.GetCameraMatrix()
+		dest := make([]Vector3, 1)
+		m.TransformObj([]Vector3{target}, dest)
+		if math.Abs(dest[0].X) > 1e-9 || math.Abs(dest[0].Y) > 1e-9 || math.Abs(dest[0].Z-500) > 1e-9 {
+			t.Errorf("view %d: target in camera space expected (0, 0, 500), got %v", view, dest[0])
+		}
+	}
+}
+
+func TestNewOrthographicCamera_TopView(t *testing.T) {
+	c := NewOrthographicCamera(OrthoTop, NewVector3(0, 0, 0), 100, 50)
+
+	// Looking down, world +X stays to the right and the front (-Z) of the
+	// model is at the bottom of the screen.
+	m := c.GetCameraMatrix()
+	dest := make([]Vector3, 2)
+	m.TransformObj([]Vector3{NewVector3(1, 0, 0), NewVector3(0, 0, -1)}, dest)
+	if math.Abs(dest[0].X-1) > 1e-9 {
+		t.Errorf("world +X expected to map to camera +X, got %v", dest[0])
+	}
+	if math.Abs(dest[1].Y-1) > 1e-9 {
+		t.Errorf("world -Z expected to map to camera +Y (screen down), got %v", dest[1])
+	}
+}
+
+func TestCamera_SetOrthographic(t *testing.T) {
+	c := NewCamera(0, 0, 0, 0, 0, 0)
+	c.SetOrthographic(120)
+
+	p := c.Projection(240, 120)
+	if !p.Orthographic {
+		t.Fatal("expected an orthographic projection")
+	}
+	if p.FocalX != 1 || p.FocalY != 1 {
+		t.Errorf("expected 1 pixel per unit, got %f/%f", p.FocalX, p.FocalY)
+	}
+
+	c.SetPerspective()
+	if c.Projection(240, 120).Orthographic {
+		t.Error("SetPerspective did not switch back to a perspective projection")
+	}
+}
+
+func TestCamera_ProjectUnproject(t *testing.T) {
+	fov := NewCamera(0, 0, 0, 0, 0, 0)
+	fov.SetCameraPosition(50, -80, -300)
+	fov.LookAt(NewVector3(0, 0, 0), NewVector3(0, -1, 0))
+	fov.SetFieldOfViewDegrees(60)
+	fov.SetPrincipalPoint(12, -7)
+
+	cameras := map[string]*Camera{
+		"legacy":       NewCamera(0, 0, -300, 0.05, 0.1, 0),
+		"fov":          fov,
+		"orthographic": NewOrthographicCamera(OrthoIsometric, NewVector3(10, 0, 0), 500, 200),
+	}
+	const width, height = 320, 200
+
+	for name, c := range cameras {
+		proj := c.Projection(width, height)
+		camM := c.GetCameraMatrix()
+		for _, world := range []Vector3{NewVector3(0, 0, 0), NewVector3(40, -30, 20), NewVector3(-60, 10, -40)} {
+			x, y, depth, visible := c.Project(world, width, height)
+			if !visible {
+				t.Errorf("%s: %v not visible at (%v, %v) depth %v", name, world, x, y, depth)
+			}
+
+			// Project agrees with the projection used by renders.
+			p := camM.TransformPoint(world)
+			if math.Abs(x-float64(proj.ToScreenX(p.X, p.Z))) > 1e-3 || math.Abs(y-float64(proj.ToScreenY(p.Y, p.Z))) > 1e-3 || depth != p.Z {
+				t.Errorf("%s: Project(%v) = (%v, %v, %v), want (%v, %v, %v)", name, world, x, y, depth, proj.ToScreenX(p.X, p.Z), proj.ToScreenY(p.Y, p.Z), p.Z)
+			}
+
+			// The ray back through that point passes through the world point.
+			origin, dir := c.Unproject(x, y, width, height)
+			if math.Abs(GetLength2(dir)-1) > 1e-9 {
+				t.Errorf("%s: ray direction %v is not a unit vector", name, dir)
+			}
+			toPoint := Subtract(world, origin)
+			along := Dot(toPoint, dir)
+			if off := GetLength2(Subtract(toPoint, NewVector3(dir.X*along, dir.Y*along, dir.Z*along))); along <= 0 || off > 1e-6 {
+				t.Errorf("%s: ray (%v, %v) misses %v by %v", name, origin, dir, world, off)
+			}
+		}
+	}
+}
+
+func TestCamera_ProjectVisibility(t *testing.T) {
+	c := NewCamera(0, 0, -300, 0, 0, 0)
+	c.FarPlane = 1000
+
+	for _, tc := range []struct {
+		world   Vector3
+		visible bool
+	}{
+		{NewVector3(0, 0, 0), true},
+		{NewVector3(0, 0, -400), false},    // behind the camera
+		{NewVector3(0, 0, -295), false},    // in front of the near plane
+		{NewVector3(0, 0, 800), false},     // beyond the far plane
+		{NewVector3(1000, 0, 0), false},    // off the right of the screen
+		{NewVector3(0, -1000, 100), false}, // above the screen
+	} {
+		if _, _, _, visible := c.Project(tc.world, 200, 150); visible != tc.visible {
+			t.Errorf("Project(%v) visible = %v, want %v", tc.world, visible, tc.visible)
+		}
+	}
+
+	// Orthographic rays all run along the view axis from the camera plane.
+	o := NewOrthographicCamera(OrthoFront, NewVector3(0, 0, 0), 500, 200)
+	origin, dir := o.Unproject(0, 0, 200, 200)
+	_, _, depth, _ := o.Project(origin, 200, 200)
+	if math.Abs(depth) > 1e-9 || math.Abs(dir.X) > 1e-9 || math.Abs(dir.Y) > 1e-9 || math.Abs(dir.Z-1) > 1e-9 {
+		t.Errorf("orthographic ray (%v, %v); want to start at depth 0 and run along +Z", origin, dir)
+	}
+}
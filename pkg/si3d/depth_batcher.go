@@ -0,0 +1,206 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// strokeDepthBias is the relative depth tolerance used when testing outline
+// pixels. Together with a slope-scaled term it stops a polygon's edges from
+// being hidden by its own fill or by the fill of a neighbour sharing the edge.
+const strokeDepthBias = 1e-4
+
+// DepthBatcher is a PolygonBatcher that rasterises polygons itself with a
+// scanline fill and resolves visibility per pixel with a float32 Z-buffer.
+// Polygons added through AddDepthPolygon are depth tested, so intersecting
+// entities and models without a BSP tree render correctly regardless of
+// draw order. Polygons added through the plain PolygonBatcher methods are
+// drawn on top in submission order without depth testing.
+//
+// Opaque fills write to the Z-buffer; translucent fills and outlines are
+// depth tested but do not.
+type DepthBatcher struct {
+	DefaultBatcher
+	zbuf []float32
+}
+
+func NewDepthBatcher(initialCap int) *DepthBatcher {
+	return &DepthBatcher{
+		DefaultBatcher: *NewDefaultBatcher(initialCap),
+	}
+}
+
+// AddDepthPolygon adds a polygon with a depth value per vertex. The polygon
+// does not need to be clipped to the screen.
+func (b *DepthBatcher) AddDepthPolygon(xp, yp, zp []float32, fillClr, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	if len(xp) < 3 {
+		return
+	}
+
+	cmd := polygonCommand{
+		xp:        xp,
+		yp:        yp,
+		zp:        zp,
+		fillClr:   fillClr,
+		strokeClr: strokeClr,
+		strokeW:   strokeWidth,
+		hasFill:   true,
+		hasStroke: hasStroke,
+	}
+	b.commands = append(b.commands, cmd)
+}
+
+// AddShadedPolygon adds a depth-tested polygon whose fill colour is
+// interpolated between per-vertex colours (Gouraud shading).
+func (b *DepthBatcher) AddShadedPolygon(xp, yp, zp []float32, colors []color.RGBA, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	if len(xp) < 3 {
+		return
+	}
+
+	cmd := polygonCommand{
+		xp:        xp,
+		yp:        yp,
+		zp:        zp,
+		colors:    colors,
+		strokeClr: strokeClr,
+		strokeW:   strokeWidth,
+		hasFill:   true,
+		hasStroke: hasStroke,
+	}
+	b.commands = append(b.commands, cmd)
+}
+
+// AddTexturedPolygon adds a depth-tested polygon filled with a
+// perspective-correct mapping of tex, scaled by the light interpolated
+// between its vertices.
+func (b *DepthBatcher) AddTexturedPolygon(xp, yp, zp []float32, uvs []UV, invZ []float32, light []color.RGBA, fog []float32, fogClr color.RGBA, tex *Texture, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	if len(xp) < 3 {
+		return
+	}
+
+	cmd := polygonCommand{
+		xp:        xp,
+		yp:        yp,
+		zp:        zp,
+		colors:    light,
+		tex:       tex,
+		uvs:       uvs,
+		invZ:      invZ,
+		fog:       fog,
+		fogClr:    fogClr,
+		strokeClr: strokeClr,
+		strokeW:   strokeWidth,
+		hasFill:   true,
+		hasStroke: hasStroke,
+	}
+	b.commands = append(b.commands, cmd)
+}
+
+// Draw rasterises the batch into the target image and clears it.
+func (b *DepthBatcher) Draw(target *image.RGBA) {
+	if len(b.commands) == 0 {
+		return
+	}
+
+	width := target.Bounds().Dx()
+	height := target.Bounds().Dy()
+	b.clearDepth(width * height)
+
+	for _, cmd := range b.commands {
+		if len(cmd.xp) == 0 {
+			continue
+		}
+		if cmd.hasFill {
+			b.crossings = fillPolygon(target, cmd, b.zbuf, b.crossings)
+		}
+		if cmd.hasStroke {
+			b.strokePolygon(target, cmd, width, height)
+		}
+	}
+
+	// Reset the commands slice for the next frame, but keep the allocated memory.
+	b.commands = b.commands[:0]
+}
+
+func (b *DepthBatcher) clearDepth(size int) {
+	if cap(b.zbuf) < size {
+		b.zbuf = make([]float32, size)
+	}
+	b.zbuf = b.zbuf[:size]
+
+	inf := float32(math.Inf(1))
+	for i := range b.zbuf {
+		b.zbuf[i] = inf
+	}
+}
+
+// strokePolygon draws the closed outline of the polygon with a DDA line
+// walker, depth testing each pixel against the Z-buffer with a small bias.
+func (b *DepthBatcher) strokePolygon(target *image.RGBA, cmd polygonCommand, width, height int) {
+	brush := max(1, int(cmd.strokeW+0.5))
+	offset := (brush - 1) / 2
+	n := len(cmd.xp)
+
+	// Outline pixels are sampled up to a brush width away from the exact
+	// edge, so allow for how fast depth changes across the polygon.
+	var slopeBias float32
+	if cmd.zp != nil {
+		slopeBias = depthSlope(cmd) * (float32(brush)/2 + 1)
+	}
+
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		x0, y0, x1, y1 := cmd.xp[i], cmd.yp[i], cmd.xp[j], cmd.yp[j]
+		var z0, z1 float32
+		if cmd.zp != nil {
+			z0, z1 = cmd.zp[i], cmd.zp[j]
+		}
+
+		steps := int(math.Ceil(float64(max(abs32(x1-x0), abs32(y1-y0)))))
+		if steps == 0 {
+			steps = 1
+		}
+
+		// The end point is skipped because the next edge starts there.
+		for s := 0; s < steps; s++ {
+			t := float32(s) / float32(steps)
+			x := int(math.Floor(float64(x0 + t*(x1-x0))))
+			y := int(math.Floor(float64(y0 + t*(y1-y0))))
+			z := z0 + t*(z1-z0)
+
+			for by := y - offset; by < y-offset+brush; by++ {
+				for bx := x - offset; bx < x-offset+brush; bx++ {
+					if bx < 0 || by < 0 || bx >= width || by >= height {
+						continue
+					}
+					if cmd.zp != nil {
+						zb := b.zbuf[by*width+bx]
+						if z > zb+slopeBias+strokeDepthBias*abs32(zb) {
+							continue
+						}
+					}
+					blendPixel(target, bx, by, cmd.strokeClr)
+				}
+			}
+		}
+	}
+}
+
+// depthSlope returns the sum of the absolute screen-space depth gradients of
+// the polygon's plane, found with Newell's method. Polygons seen edge-on have
+// no usable plane and return 0.
+func depthSlope(cmd polygonCommand) float32 {
+	var nx, ny, nz float32
+	n := len(cmd.xp)
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		nx += (cmd.yp[i] - cmd.yp[j]) * (cmd.zp[i] + cmd.zp[j])
+		ny += (cmd.zp[i] - cmd.zp[j]) * (cmd.xp[i] + cmd.xp[j])
+		nz += (cmd.xp[i] - cmd.xp[j]) * (cmd.yp[i] + cmd.yp[j])
+	}
+	if abs32(nz) < 1e-6 {
+		return 0
+	}
+	return (abs32(nx) + abs32(ny)) / abs32(nz)
+}
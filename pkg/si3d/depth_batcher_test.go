@@ -0,0 +1,175 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func square(x0, y0, x1, y1 float32) ([]float32, []float32) {
+	return []float32{x0, x1, x1, x0}, []float32{y0, y0, y1, y1}
+}
+
+func TestNewDepthBatcher(t *testing.T) {
+	b := NewDepthBatcher(50)
+	if b == nil {
+		t.Fatal("NewDepthBatcher returned nil")
+	}
+	if cap(b.commands) != 50 {
+		t.Errorf("NewDepthBatcher initial capacity expected 50, got %d", cap(b.commands))
+	}
+
+	var _ DepthPolygonBatcher = b
+	var _ ShadedPolygonBatcher = b
+}
+
+func TestDepthBatcher_NearestWins(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	for _, nearFirst := range []bool{true, false} {
+		b := NewDepthBatcher(10)
+		target := image.NewRGBA(image.Rect(0, 0, 20, 20))
+
+		xp, yp := square(2, 2, 18, 18)
+		near := []float32{1, 1, 1, 1}
+		far := []float32{2, 2, 2, 2}
+		if nearFirst {
+			b.AddDepthPolygon(xp, yp, near, red, color.RGBA{}, 0, false)
+			b.AddDepthPolygon(xp, yp, far, blue, color.RGBA{}, 0, false)
+		} else {
+			b.AddDepthPolygon(xp, yp, far, blue, color.RGBA{}, 0, false)
+			b.AddDepthPolygon(xp, yp, near, red, color.RGBA{}, 0, false)
+		}
+		b.Draw(target)
+
+		if got := target.RGBAAt(10, 10); got != red {
+			t.Errorf("nearFirst=%v: expected the nearer polygon to win, got %v", nearFirst, got)
+		}
+		if len(b.commands) != 0 {
+			t.Error("Draw did not reset the command list")
+		}
+	}
+}
+
+func TestDepthBatcher_Intersecting(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	b := NewDepthBatcher(10)
+	target := image.NewRGBA(image.Rect(0, 0, 20, 20))
+
+	// Two polygons whose depths cross in the middle of the screen.
+	xp, yp := square(0, 0, 20, 20)
+	b.AddDepthPolygon(xp, yp, []float32{1, 3, 3, 1}, red, color.RGBA{}, 0, false)
+	b.AddDepthPolygon(xp, yp, []float32{3, 1, 1, 3}, blue, color.RGBA{}, 0, false)
+	b.Draw(target)
+
+	if got := target.RGBAAt(2, 10); got != red {
+		t.Errorf("left side expected red, got %v", got)
+	}
+	if got := target.RGBAAt(17, 10); got != blue {
+		t.Errorf("right side expected blue, got %v", got)
+	}
+}
+
+func TestDepthBatcher_OutlineNotHiddenByOwnFill(t *testing.T) {
+	fill := color.RGBA{A: 255}
+	stroke := color.RGBA{G: 255, A: 255}
+	b := NewDepthBatcher(10)
+	target := image.NewRGBA(image.Rect(0, 0, 20, 20))
+
+	// A sloped polygon, so depth varies across each pixel.
+	xp, yp := square(2.3, 2.3, 17.7, 17.7)
+	b.AddDepthPolygon(xp, yp, []float32{1, 5, 9, 5}, fill, stroke, 1, true)
+	b.Draw(target)
+
+	if got := target.RGBAAt(10, 2); got != stroke {
+		t.Errorf("expected the top edge to be stroked, got %v", got)
+	}
+	if got := target.RGBAAt(10, 10); got != fill {
+		t.Errorf("expected the interior to be filled, got %v", got)
+	}
+}
+
+func TestDepthBatcher_TranslucentDoesNotWriteDepth(t *testing.T) {
+	glass := color.RGBA{R: 0, G: 0, B: 100, A: 100}
+	red := color.RGBA{R: 255, A: 255}
+	b := NewDepthBatcher(10)
+	target := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	xp, yp := square(0, 0, 10, 10)
+	b.AddDepthPolygon(xp, yp, []float32{1, 1, 1, 1}, glass, color.RGBA{}, 0, false)
+	b.AddDepthPolygon(xp, yp, []float32{2, 2, 2, 2}, red, color.RGBA{}, 0, false)
+	b.Draw(target)
+
+	if got := target.RGBAAt(5, 5); got != red {
+		t.Errorf("expected the opaque polygon behind the translucent one to be drawn, got %v", got)
+	}
+}
+
+func TestDepthBatcher_PlainPolygons(t *testing.T) {
+	green := color.RGBA{G: 255, A: 255}
+	b := NewDepthBatcher(10)
+	target := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	// Polygons off the screen must be clipped, not wrap around.
+	xp, yp := square(-5, -5, 5, 5)
+	b.AddPolygon(xp, yp, green)
+	b.Draw(target)
+
+	if got := target.RGBAAt(2, 2); got != green {
+		t.Errorf("expected plain polygon to be drawn, got %v", got)
+	}
+	if got := target.RGBAAt(7, 7); got != (color.RGBA{}) {
+		t.Errorf("expected pixel outside the polygon to be untouched, got %v", got)
+	}
+}
+
+func TestDepthBatcher_ShadedPolygon(t *testing.T) {
+	b := NewDepthBatcher(10)
+	target := image.NewRGBA(image.Rect(0, 0, 20, 20))
+
+	black := color.RGBA{A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	// A shaded square in front of a flat one drawn afterwards.
+	xp, yp := square(0, 0, 20, 20)
+	b.AddShadedPolygon(xp, yp, []float32{1, 1, 1, 1}, []color.RGBA{black, green, green, black}, color.RGBA{}, 0, false)
+	b.AddDepthPolygon(xp, yp, []float32{2, 2, 2, 2}, blue, color.RGBA{}, 0, false)
+	b.Draw(target)
+
+	left, right := target.RGBAAt(0, 10), target.RGBAAt(19, 10)
+	if left.B != 0 || right.B != 0 {
+		t.Errorf("far polygon drew over shaded polygon: %v, %v", left, right)
+	}
+	if left.G >= right.G {
+		t.Errorf("shaded polygon not interpolated: left %v, right %v", left, right)
+	}
+}
+
+func TestWorld_DepthBatcherIntersectingEntities(t *testing.T) {
+	w := NewWorld3d()
+	w.SetPolygonBatcher(NewDepthBatcher(100))
+
+	cam := NewCamera(0, 0, -300, 0, 0, 0)
+	w.AddCamera(cam, 0, 0, -300)
+
+	// A long red bar passing through a green block: the middle of the bar is
+	// inside the block, so the block must cover it even though the bar's
+	// centre is nearer the camera.
+	red := NewRectangle(200, 20, 20, color.RGBA{R: 255, A: 255})
+	green := NewRectangle(60, 60, 60, color.RGBA{G: 255, A: 255})
+	w.AddObject(&Entity{Model: red, X: 0, Y: 0, Z: -5})
+	w.AddObject(&Entity{Model: green, X: 0, Y: 0, Z: 0})
+
+	img := w.Render(200, 200, color.RGBA{A: 255})
+	centre := img.RGBAAt(100, 100)
+	if centre.G <= centre.R {
+		t.Errorf("expected the block to hide the bar at the centre, got %v", centre)
+	}
+	side := img.RGBAAt(100+50, 100)
+	if side.R <= side.G {
+		t.Errorf("expected the bar to be visible beside the block, got %v", side)
+	}
+}
@@ -3,6 +3,9 @@ package si3d
 import (
 	"image"
 	"image/color"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/fogleman/gg"
 )
@@ -21,6 +24,24 @@ type polygonCommand struct {
 	strokeW   float32
 	hasStroke bool
 	hasFill   bool
+	// zp holds per-vertex depth for polygons added through
+	// DepthPolygonBatcher. It is nil for plain 2D polygons.
+	zp []float32
+	// colors holds per-vertex fill colours for polygons added through
+	// ShadedPolygonBatcher. It is nil for flat filled polygons. Textured
+	// polygons use it for the light falling on each vertex.
+	colors []color.RGBA
+	// tex, uvs and invZ describe the texture of polygons added through
+	// TexturedPolygonBatcher. tex is nil for untextured polygons. fog, if
+	// not nil, holds each vertex's fog visibility and fogClr the fog colour.
+	tex    *Texture
+	uvs    []UV
+	invZ   []float32
+	fog    []float32
+	fogClr color.RGBA
+	// unclipped marks a polygon a vector batcher has yet to clip to the
+	// screen; see vectorBatcher.
+	unclipped bool
 }
 
 // PolygonBatcher is the interface for polygon batch renderers.
@@ -32,18 +53,249 @@ type PolygonBatcher interface {
 	ClipPolygon(subjectPolygon []Point, screenWidth, screenHeight float32) []Point
 }
 
+// DepthPolygonBatcher is implemented by batchers that resolve visibility per
+// pixel. The paint paths hand them unclipped screen polygons together with a
+// depth value per vertex (see Projection.Depth) instead of calling
+// ClipPolygon, so they must clip to the screen themselves.
+type DepthPolygonBatcher interface {
+	PolygonBatcher
+	AddDepthPolygon(xp, yp, zp []float32, fillClr, strokeClr color.RGBA, strokeWidth float32, hasStroke bool)
+}
+
+// ShadedPolygonBatcher is implemented by batchers that can interpolate
+// per-vertex colours across a polygon, as used by Gouraud shading. Like
+// DepthPolygonBatcher they are handed polygons that are not clipped to the
+// screen. zp is nil unless the batcher is also a DepthPolygonBatcher.
+type ShadedPolygonBatcher interface {
+	PolygonBatcher
+	AddShadedPolygon(xp, yp, zp []float32, colors []color.RGBA, strokeClr color.RGBA, strokeWidth float32, hasStroke bool)
+}
+
+// TexturedPolygonBatcher is implemented by batchers that can map a texture
+// across a polygon. Like ShadedPolygonBatcher they are handed polygons that
+// are not clipped to the screen, and zp is nil unless the batcher is also a
+// DepthPolygonBatcher. uvs holds each vertex's texture coordinate and invZ
+// its perspective weight, 1/z of its camera-space depth or 1 for
+// orthographic projections, so that the coordinates can be interpolated
+// perspective-correctly. light holds the colour a white surface is lit to
+// at each vertex, which scales the texture. fog is nil without fog, and
+// otherwise holds how much of the lit texture survives the fog at each
+// vertex, the rest being fogClr.
+type TexturedPolygonBatcher interface {
+	PolygonBatcher
+	AddTexturedPolygon(xp, yp, zp []float32, uvs []UV, invZ []float32, light []color.RGBA, fog []float32, fogClr color.RGBA, tex *Texture, strokeClr color.RGBA, strokeWidth float32, hasStroke bool)
+}
+
+// addPolygon projects a frustum-clipped camera-space polygon and adds it to
+// the batcher. Depth-aware batchers receive per-vertex depth; all others get
+// a polygon clipped to the screen.
+func addPolygon(
+	batcher PolygonBatcher,
+	points []Vector3,
+	proj *Projection,
+	screenWidth, screenHeight float32,
+	fillClr, strokeClr color.RGBA,
+	strokeWidth float32,
+	hasStroke bool,
+	ctx *RenderContext,
+) {
+	if ctx.translucent != nil && fillClr.A != 255 {
+		ctx.translucent.add(points, proj, func(points []Vector3, batcher PolygonBatcher, ctx *RenderContext) {
+			addPolygon(batcher, points, proj, screenWidth, screenHeight, fillClr, strokeClr, strokeWidth, hasStroke, ctx)
+		})
+		return
+	}
+
+	if depthBatcher, ok := batcher.(DepthPolygonBatcher); ok {
+		xp := make([]float32, len(points))
+		yp := make([]float32, len(points))
+		zp := make([]float32, len(points))
+		for i, point := range points {
+			xp[i] = proj.ToScreenX(point.X, point.Z)
+			yp[i] = proj.ToScreenY(point.Y, point.Z)
+			zp[i] = proj.Depth(point.Z)
+		}
+		depthBatcher.AddDepthPolygon(xp, yp, zp, fillClr, strokeClr, strokeWidth, hasStroke)
+		return
+	}
+
+	ctx.BufferPoints = ctx.BufferPoints[:0]
+	for _, point := range points {
+		// At this stage, point[2] (z) is guaranteed to be >= the near plane,
+		// so perspective division is safe.
+		z := float32(point.Z)
+		ctx.BufferPoints = append(ctx.BufferPoints, Point{
+			X: proj.ToScreenX(point.X, float64(z)),
+			Y: proj.ToScreenY(point.Y, float64(z)),
+		})
+	}
+
+	clippedPoints := batcher.ClipPolygon(ctx.BufferPoints, screenWidth, screenHeight)
+	if len(clippedPoints) < 3 {
+		return
+	}
+
+	ctx.BufferFloatX = ctx.BufferFloatX[:0]
+	ctx.BufferFloatY = ctx.BufferFloatY[:0]
+	for _, p := range clippedPoints {
+		ctx.BufferFloatX = append(ctx.BufferFloatX, p.X)
+		ctx.BufferFloatY = append(ctx.BufferFloatY, p.Y)
+	}
+
+	// Copy the data to new slices so the batcher can store them
+	finalScreenPointsX := make([]float32, len(ctx.BufferFloatX))
+	copy(finalScreenPointsX, ctx.BufferFloatX)
+	finalScreenPointsY := make([]float32, len(ctx.BufferFloatY))
+	copy(finalScreenPointsY, ctx.BufferFloatY)
+
+	if hasStroke {
+		batcher.AddPolygonAndOutline(finalScreenPointsX, finalScreenPointsY, fillClr, strokeClr, strokeWidth)
+	} else {
+		batcher.AddPolygon(finalScreenPointsX, finalScreenPointsY, fillClr)
+	}
+}
+
+// addShadedPolygon projects a frustum-clipped camera-space polygon with a
+// colour per vertex and adds it to the batcher. Batchers that cannot
+// interpolate colours get a flat polygon filled with the average colour.
+func addShadedPolygon(
+	batcher PolygonBatcher,
+	points []Vector3,
+	colors []color.RGBA,
+	proj *Projection,
+	screenWidth, screenHeight float32,
+	strokeClr color.RGBA,
+	strokeWidth float32,
+	hasStroke bool,
+	ctx *RenderContext,
+) {
+	if ctx.translucent != nil && anyTranslucent(colors) {
+		colors := slices.Clone(colors)
+		ctx.translucent.add(points, proj, func(points []Vector3, batcher PolygonBatcher, ctx *RenderContext) {
+			addShadedPolygon(batcher, points, colors, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
+		})
+		return
+	}
+
+	shadedBatcher, ok := batcher.(ShadedPolygonBatcher)
+	if !ok {
+		addPolygon(batcher, points, proj, screenWidth, screenHeight, averageColor(colors), strokeClr, strokeWidth, hasStroke, ctx)
+		return
+	}
+
+	xp := make([]float32, len(points))
+	yp := make([]float32, len(points))
+	for i, point := range points {
+		xp[i] = proj.ToScreenX(point.X, point.Z)
+		yp[i] = proj.ToScreenY(point.Y, point.Z)
+	}
+
+	var zp []float32
+	if _, ok := batcher.(DepthPolygonBatcher); ok {
+		zp = make([]float32, len(points))
+		for i, point := range points {
+			zp[i] = proj.Depth(point.Z)
+		}
+	}
+
+	finalColors := make([]color.RGBA, len(colors))
+	copy(finalColors, colors)
+
+	shadedBatcher.AddShadedPolygon(xp, yp, zp, finalColors, strokeClr, strokeWidth, hasStroke)
+}
+
+// averageColor returns the mean of a list of colours.
+func averageColor(colors []color.RGBA) color.RGBA {
+	if len(colors) == 0 {
+		return color.RGBA{}
+	}
+
+	var r, g, b, a int
+	for _, c := range colors {
+		r += int(c.R)
+		g += int(c.G)
+		b += int(c.B)
+		a += int(c.A)
+	}
+	n := len(colors)
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)}
+}
+
+// unpremultiply converts a premultiplied colour to straight alpha. A colour
+// with a channel above its alpha is not validly premultiplied and is taken
+// to be straight already, as the default BSP outline {100, 100, 100, 25}, a
+// faint grey, is meant; unpremultiplying it would clamp it to white.
+func unpremultiply(c color.RGBA) color.RGBA {
+	if c.A == 0 || c.A == 255 || c.R > c.A || c.G > c.A || c.B > c.A {
+		return c
+	}
+	return color.RGBA{
+		R: uint8(int(c.R) * 255 / int(c.A)),
+		G: uint8(int(c.G) * 255 / int(c.A)),
+		B: uint8(int(c.B) * 255 / int(c.A)),
+		A: c.A,
+	}
+}
+
+// flatFill returns the command's fill colour, averaging per-vertex colours
+// for vector batchers, which cannot interpolate them. Textured polygons are
+// filled with the texture's average colour.
+func (cmd *polygonCommand) flatFill() color.RGBA {
+	if cmd.tex != nil {
+		c := modulate(cmd.tex.average, averageColor(cmd.colors))
+		if cmd.fog != nil {
+			var sum float32
+			for _, f := range cmd.fog {
+				sum += f
+			}
+			c = fogBlend(c, cmd.fogClr, float64(sum/float32(len(cmd.fog))))
+		}
+		return c
+	}
+	if cmd.colors != nil {
+		return averageColor(cmd.colors)
+	}
+	return cmd.fillClr
+}
+
+// formatCoord formats a screen coordinate for vector output with the
+// shortest exact representation.
+func formatCoord(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+// formatRGB formats a straight colour as the three 0..1 components used by
+// PDF and PostScript.
+func formatRGB(c color.RGBA) string {
+	return formatUnit(float64(c.R)/255) + " " + formatUnit(float64(c.G)/255) + " " + formatUnit(float64(c.B)/255)
+}
+
+// formatUnit formats a value in 0..1 to four decimal places, without
+// trailing zeros.
+func formatUnit(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
 // DefaultBatcher is the software-rasteriser implementation of PolygonBatcher.
 type DefaultBatcher struct {
-	commands []polygonCommand
-	clipBufA []Point
-	clipBufB []Point
+	commands  []polygonCommand
+	clipBufA  []Point
+	clipBufB  []Point
+	crossings []scanCrossing
+	// workers is the number of goroutines Draw rasterises with; see
+	// SetParallelism.
+	workers int
+	bands   []*bandRasterizer
 }
 
 func NewDefaultBatcher(initialCap int) *DefaultBatcher {
 	return &DefaultBatcher{
-		commands: make([]polygonCommand, 0, initialCap),
-		clipBufA: make([]Point, 0, 20),
-		clipBufB: make([]Point, 0, 20),
+		commands:  make([]polygonCommand, 0, initialCap),
+		clipBufA:  make([]Point, 0, 20),
+		clipBufB:  make([]Point, 0, 20),
+		crossings: make([]scanCrossing, 0, 20),
 	}
 }
 
@@ -95,11 +347,62 @@ func (b *DefaultBatcher) AddPolygonAndOutline(xp, yp []float32, fillClr, strokeC
 	b.commands = append(b.commands, cmd)
 }
 
+// AddShadedPolygon adds a polygon whose fill colour is interpolated between
+// per-vertex colours. The fill is rasterised without anti-aliasing and
+// clipped to the target image; zp is ignored.
+func (b *DefaultBatcher) AddShadedPolygon(xp, yp, zp []float32, colors []color.RGBA, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	if len(xp) < 3 {
+		return
+	}
+
+	cmd := polygonCommand{
+		xp:        xp,
+		yp:        yp,
+		colors:    colors,
+		strokeClr: strokeClr,
+		strokeW:   strokeWidth,
+		hasFill:   true,
+		hasStroke: hasStroke,
+	}
+	b.commands = append(b.commands, cmd)
+}
+
+// AddTexturedPolygon adds a polygon filled with a perspective-correct
+// mapping of tex, scaled by the light interpolated between its vertices. The
+// fill is rasterised without anti-aliasing and clipped to the target image;
+// zp is ignored.
+func (b *DefaultBatcher) AddTexturedPolygon(xp, yp, zp []float32, uvs []UV, invZ []float32, light []color.RGBA, fog []float32, fogClr color.RGBA, tex *Texture, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	if len(xp) < 3 {
+		return
+	}
+
+	cmd := polygonCommand{
+		xp:        xp,
+		yp:        yp,
+		colors:    light,
+		tex:       tex,
+		uvs:       uvs,
+		invZ:      invZ,
+		fog:       fog,
+		fogClr:    fogClr,
+		strokeClr: strokeClr,
+		strokeW:   strokeWidth,
+		hasFill:   true,
+		hasStroke: hasStroke,
+	}
+	b.commands = append(b.commands, cmd)
+}
+
 // Draw sends the entire batch of polygons to be drawn on the target image.
 func (b *DefaultBatcher) Draw(target *image.RGBA) {
 	if len(b.commands) == 0 {
 		return
 	}
+	if b.workers > 1 && target.Bounds().Min == (image.Point{}) {
+		b.drawParallel(target)
+		b.commands = b.commands[:0]
+		return
+	}
 
 	dc := gg.NewContextForRGBA(target)
 
@@ -108,6 +411,16 @@ func (b *DefaultBatcher) Draw(target *image.RGBA) {
 			continue
 		}
 
+		if cmd.colors != nil {
+			// gg cannot interpolate colours or map textures, so
+			// shaded and textured fills are rasterised directly into
+			// the target.
+			b.crossings = fillPolygon(target, cmd, nil, b.crossings)
+			if !cmd.hasStroke {
+				continue
+			}
+		}
+
 		dc.NewSubPath()
 		dc.MoveTo(float64(cmd.xp[0]), float64(cmd.yp[0]))
 		for i := 1; i < len(cmd.xp); i++ {
@@ -115,7 +428,7 @@ func (b *DefaultBatcher) Draw(target *image.RGBA) {
 		}
 		dc.ClosePath()
 
-		if cmd.hasFill {
+		if cmd.hasFill && cmd.colors == nil {
 			dc.SetColor(cmd.fillClr)
 			if cmd.hasStroke {
 				dc.FillPreserve()
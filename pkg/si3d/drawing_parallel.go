@@ -0,0 +1,235 @@
+package si3d
+
+import (
+	"image"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/freetype/raster"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// minBandHeight is the height, in rows, of the smallest band a parallel
+	// Draw splits the target into.
+	minBandHeight = 16
+	// bandsPerWorker is the average number of bands per worker. Workers pick
+	// up bands as they finish, so busy parts of the screen are shared out.
+	bandsPerWorker = 4
+)
+
+// SetParallelism makes Draw rasterise with up to workers goroutines. The
+// target is split into horizontal bands, each polygon is handed to every
+// band its bounds touch, and the bands are rasterised concurrently, each
+// clipped to its own rows. A band draws its polygons in submission order
+// with exactly the coverage gg computes for the whole image, so the result
+// is pixel-identical to a serial Draw. workers <= 1, the default, draws
+// serially. Targets whose bounds do not start at the origin are always
+// drawn serially.
+//
+// This only parallelises rasterisation; World.SetParallelism also paints
+// large models concurrently.
+func (b *DefaultBatcher) SetParallelism(workers int) {
+	b.workers = workers
+}
+
+// bandRasterizer is the scratch state of one parallel worker.
+type bandRasterizer struct {
+	r          *raster.Rasterizer
+	fillPath   raster.Path
+	strokePath raster.Path
+	crossings  []scanCrossing
+}
+
+// drawParallel draws the batch into target with b.workers goroutines. It
+// does not clear the batch.
+func (b *DefaultBatcher) drawParallel(target *image.RGBA) {
+	height := target.Bounds().Dy()
+	bandHeight := max(minBandHeight, (height+b.workers*bandsPerWorker-1)/(b.workers*bandsPerWorker))
+	numBands := (height + bandHeight - 1) / bandHeight
+
+	bins := make([][]int32, numBands)
+	for i, cmd := range b.commands {
+		if len(cmd.xp) == 0 {
+			continue
+		}
+		first, last, ok := commandBands(cmd, bandHeight, numBands)
+		for band := first; ok && band <= last; band++ {
+			bins[band] = append(bins[band], int32(i))
+		}
+	}
+
+	for len(b.bands) < b.workers {
+		b.bands = append(b.bands, &bandRasterizer{})
+	}
+
+	var next atomic.Int32
+	var wg sync.WaitGroup
+	for _, br := range b.bands[:min(b.workers, numBands)] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				band := int(next.Add(1)) - 1
+				if band >= numBands {
+					return
+				}
+				rowMin := band * bandHeight
+				rowMax := min(height, rowMin+bandHeight)
+				br.draw(target, b.commands, bins[band], rowMin, rowMax)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// commandBands returns the range of bands a command may paint, widening its
+// bounds by commandMargin. Polygons with non-finite coordinates are given
+// every band.
+func commandBands(cmd polygonCommand, bandHeight, numBands int) (first, last int, ok bool) {
+	minY, maxY := commandYRange(cmd)
+	if math.IsNaN(minY) || math.IsNaN(maxY) || math.IsInf(minY, 0) || math.IsInf(maxY, 0) {
+		return 0, numBands - 1, true
+	}
+
+	margin := commandMargin(cmd)
+	top := math.Floor((minY - margin) / float64(bandHeight))
+	bottom := math.Floor((maxY + margin) / float64(bandHeight))
+	if bottom < 0 || top > float64(numBands-1) {
+		return 0, 0, false
+	}
+	return int(math.Max(top, 0)), int(math.Min(bottom, float64(numBands-1))), true
+}
+
+func commandYRange(cmd polygonCommand) (minY, maxY float64) {
+	minY, maxY = float64(cmd.yp[0]), float64(cmd.yp[0])
+	for _, y := range cmd.yp[1:] {
+		minY = math.Min(minY, float64(y))
+		maxY = math.Max(maxY, float64(y))
+	}
+	return minY, maxY
+}
+
+// commandMargin returns how far, in pixels, a command may paint beyond its
+// vertices: half the stroke width for outlines, plus room for
+// anti-aliasing and the flattening of round joins and caps.
+func commandMargin(cmd polygonCommand) float64 {
+	margin := 2.0
+	if cmd.hasStroke {
+		margin += float64(cmd.strokeW) / 2
+	}
+	return margin
+}
+
+// draw rasterises the given commands into the rows [rowMin, rowMax) of
+// target. It builds the same paths as DefaultBatcher's serial gg calls and
+// rasterises them in the same coordinates, only painting the band, so
+// coverage matches the serial path exactly.
+func (br *bandRasterizer) draw(target *image.RGBA, commands []polygonCommand, indices []int32, rowMin, rowMax int) {
+	size := target.Bounds().Size()
+	if br.r == nil {
+		br.r = raster.NewRasterizer(size.X, size.Y)
+	}
+
+	// freetype rasterises rows independently, so the rasteriser can stop at
+	// the bottom of the band. Its bounds also pick how finely round joins
+	// and caps are flattened, so they are only reduced where that stays the
+	// same.
+	height := rowMax
+	if splitScale(size.X, height) != splitScale(size.X, size.Y) {
+		height = size.Y
+	}
+	r := br.r
+	r.SetBounds(size.X, height)
+	r.UseNonZeroWinding = true
+
+	band := target.SubImage(image.Rect(0, rowMin, size.X, rowMax)).(*image.RGBA)
+	painter := raster.NewRGBAPainter(band)
+
+	for _, i := range indices {
+		cmd := commands[i]
+
+		if cmd.colors != nil {
+			br.crossings = fillPolygonRows(target, cmd, nil, br.crossings, rowMin, rowMax)
+			if !cmd.hasStroke {
+				continue
+			}
+		}
+
+		if cmd.hasFill && cmd.colors == nil {
+			br.buildFillPath(cmd)
+			painter.SetColor(cmd.fillClr)
+			r.Clear()
+			r.AddPath(br.fillPath)
+			r.Rasterize(painter)
+		}
+
+		if cmd.hasStroke {
+			br.buildStrokePath(cmd)
+			painter.SetColor(cmd.strokeClr)
+			r.Clear()
+			r.AddStroke(br.strokePath, fixed.Int26_6(float64(cmd.strokeW)*64), raster.RoundCapper, raster.RoundJoiner)
+			r.Rasterize(painter)
+		}
+	}
+}
+
+// splitScale returns the curve flattening class freetype's Rasterizer
+// chooses for the given bounds.
+func splitScale(width, height int) int {
+	switch {
+	case width > 120 || height > 120:
+		return 2
+	case width > 24 || height > 24:
+		return 1
+	}
+	return 0
+}
+
+// fixedPoint converts a screen point to 26.6 fixed point, truncating as gg
+// does.
+func fixedPoint(x, y float32) fixed.Point26_6 {
+	return fixed.Point26_6{X: fixed.Int26_6(float64(x) * 64), Y: fixed.Int26_6(float64(y) * 64)}
+}
+
+// buildFillPath builds the path gg fills for a command: the polygon, closed,
+// with its start point added once more when the fill implicitly closes it.
+func (br *bandRasterizer) buildFillPath(cmd polygonCommand) {
+	start := fixedPoint(cmd.xp[0], cmd.yp[0])
+	br.fillPath.Clear()
+	br.fillPath.Start(start)
+	for i := 1; i < len(cmd.xp); i++ {
+		br.fillPath.Add1(fixedPoint(cmd.xp[i], cmd.yp[i]))
+	}
+	br.fillPath.Add1(start)
+	br.fillPath.Add1(start)
+}
+
+// buildStrokePath builds the path gg strokes for a command: the closed
+// polygon, dropping points within 8/64 of a pixel (in Manhattan distance) of
+// the point before them.
+func (br *bandRasterizer) buildStrokePath(cmd polygonCommand) {
+	start := fixedPoint(cmd.xp[0], cmd.yp[0])
+	br.strokePath.Clear()
+	br.strokePath.Start(start)
+
+	prev := start
+	for i := 1; i <= len(cmd.xp); i++ {
+		p := start
+		if i < len(cmd.xp) {
+			p = fixedPoint(cmd.xp[i], cmd.yp[i])
+		}
+		dx, dy := p.X-prev.X, p.Y-prev.Y
+		if dx < 0 {
+			dx = -dx
+		}
+		if dy < 0 {
+			dy = -dy
+		}
+		if dx+dy > 8 {
+			br.strokePath.Add1(p)
+		}
+		prev = p
+	}
+}
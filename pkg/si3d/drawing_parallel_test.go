@@ -0,0 +1,251 @@
+package si3d
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	imgdraw "image/draw"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+// addRandomPolygons adds n random polygons of every kind DefaultBatcher
+// draws, some reaching past the edges of a width x height target.
+func addRandomPolygons(b *DefaultBatcher, rng *rand.Rand, n, width, height int) {
+	randColor := func() color.RGBA {
+		a := uint8(rng.Intn(2) * 255)
+		if a == 0 {
+			a = uint8(rng.Intn(255))
+		}
+		// Premultiplied, so no channel exceeds alpha.
+		return color.RGBA{R: uint8(rng.Intn(int(a) + 1)), G: uint8(rng.Intn(int(a) + 1)), B: uint8(rng.Intn(int(a) + 1)), A: a}
+	}
+
+	for i := 0; i < n; i++ {
+		cx := rng.Float32()*float32(width+40) - 20
+		cy := rng.Float32()*float32(height+40) - 20
+		size := rng.Float32() * 40
+		verts := 3 + rng.Intn(4)
+		xp := make([]float32, verts)
+		yp := make([]float32, verts)
+		for v := range xp {
+			xp[v] = cx + (rng.Float32()-0.5)*size
+			yp[v] = cy + (rng.Float32()-0.5)*size
+		}
+		strokeW := rng.Float32() * 6
+
+		switch i % 4 {
+		case 0:
+			b.AddPolygon(xp, yp, randColor())
+		case 1:
+			b.AddPolygonOutline(xp, yp, strokeW, randColor())
+		case 2:
+			b.AddPolygonAndOutline(xp, yp, randColor(), randColor(), strokeW)
+		case 3:
+			colors := make([]color.RGBA, verts)
+			for v := range colors {
+				colors[v] = randColor()
+			}
+			b.AddShadedPolygon(xp, yp, nil, colors, randColor(), strokeW, rng.Intn(2) == 0)
+		}
+	}
+}
+
+func TestDefaultBatcher_ParallelMatchesSerial(t *testing.T) {
+	const width, height = 173, 131
+
+	render := func(workers int) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		imgdraw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{R: 20, G: 40, B: 60, A: 255}}, image.Point{}, imgdraw.Src)
+
+		b := NewDefaultBatcher(100)
+		b.SetParallelism(workers)
+		addRandomPolygons(b, rand.New(rand.NewSource(7)), 2000, width, height)
+		b.Draw(img)
+		if len(b.commands) != 0 {
+			t.Errorf("workers=%d: Draw did not clear the batch", workers)
+		}
+		return img
+	}
+
+	want := render(1)
+	for _, workers := range []int{2, 3, 8, 64} {
+		if got := render(workers); !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("workers=%d: parallel render differs from serial render", workers)
+			compareImages(t, got, want)
+		}
+	}
+}
+
+// TestDefaultBatcher_ParallelMatchesGG pins the band rasteriser, which
+// rebuilds gg's fill and stroke paths itself, to gg's own output. Wide
+// strokes around sharp corners exercise the round joins and caps gg
+// flattens into curves, and tiny polygons the points it drops.
+func TestDefaultBatcher_ParallelMatchesGG(t *testing.T) {
+	const width, height = 160, 120
+	type poly struct {
+		xp, yp         []float32
+		fill, stroke   color.RGBA
+		strokeW        float32
+		hasFill, hasSt bool
+	}
+	polys := []poly{
+		// A thin spike, whose joins reach far beyond its corners.
+		{[]float32{10, 150, 12}, []float32{20, 60, 24}, color.RGBA{}, color.RGBA{R: 200, A: 255}, 18, false, true},
+		// A filled star crossing band boundaries with a translucent stroke.
+		{[]float32{80, 92, 130, 98, 110, 80, 50, 62, 30, 68}, []float32{5, 40, 42, 62, 100, 78, 100, 62, 42, 40},
+			color.RGBA{G: 90, B: 120, A: 255}, color.RGBA{R: 40, G: 40, A: 64}, 7.5, true, true},
+		// Sub-pixel polygons at fractional positions.
+		{[]float32{20.2, 20.7, 20.4}, []float32{90.1, 90.3, 90.9}, color.RGBA{B: 255, A: 255}, color.RGBA{A: 255}, 1, true, true},
+		{[]float32{40.5, 40.55, 40.6, 40.5}, []float32{95.5, 95.5, 95.6, 95.6}, color.RGBA{}, color.RGBA{R: 255, G: 255, A: 255}, 3, false, true},
+		// A large stroked polygon reaching off every edge.
+		{[]float32{-30, 190, 170, -10}, []float32{-20, -5, 140, 130}, color.RGBA{}, color.RGBA{R: 10, G: 100, B: 10, A: 100}, 12, false, true},
+	}
+
+	bg := &image.Uniform{color.RGBA{R: 20, G: 40, B: 60, A: 255}}
+	want := image.NewRGBA(image.Rect(0, 0, width, height))
+	imgdraw.Draw(want, want.Bounds(), bg, image.Point{}, imgdraw.Src)
+	dc := gg.NewContextForRGBA(want)
+	for _, p := range polys {
+		dc.NewSubPath()
+		dc.MoveTo(float64(p.xp[0]), float64(p.yp[0]))
+		for i := 1; i < len(p.xp); i++ {
+			dc.LineTo(float64(p.xp[i]), float64(p.yp[i]))
+		}
+		dc.ClosePath()
+		if p.hasFill {
+			dc.SetColor(p.fill)
+			dc.FillPreserve()
+		}
+		dc.SetColor(p.stroke)
+		dc.SetLineWidth(float64(p.strokeW))
+		dc.Stroke()
+	}
+
+	for _, workers := range []int{2, 5} {
+		got := image.NewRGBA(image.Rect(0, 0, width, height))
+		imgdraw.Draw(got, got.Bounds(), bg, image.Point{}, imgdraw.Src)
+		b := NewDefaultBatcher(10)
+		b.SetParallelism(workers)
+		for _, p := range polys {
+			if p.hasFill {
+				b.AddPolygonAndOutline(p.xp, p.yp, p.fill, p.stroke, p.strokeW)
+			} else {
+				b.AddPolygonOutline(p.xp, p.yp, p.strokeW, p.stroke)
+			}
+		}
+		b.Draw(got)
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("workers=%d: band rasteriser differs from gg", workers)
+			compareImages(t, got, want)
+		}
+	}
+}
+
+func TestWorld_SetParallelism(t *testing.T) {
+	want := buildMountainsScene()
+
+	world, bgColor := buildMountainsWorld()
+	world.SetParallelism(4)
+	got := world.Render(goldenMountainsWidth, goldenMountainsHeight, bgColor)
+	if !compareImages(t, got, want) {
+		t.Error("parallel mountains render differs from serial render")
+	}
+
+	// The batch is reused across frames.
+	got = world.Render(goldenMountainsWidth, goldenMountainsHeight, bgColor)
+	if !compareImages(t, got, want) {
+		t.Error("second parallel mountains render differs from serial render")
+	}
+}
+
+// newDenseWorld returns a world of models with enough faces to be painted
+// concurrently: a Gouraud sphere with a translucent stripe, a textured
+// sphere and a flat-shaded terrain, some of each off screen.
+func newDenseWorld() *World {
+	shaded := NewUVSphere(90, 64, 64, color.RGBA{R: 200, G: 60, B: 60, A: 255}, color.RGBA{G: 60, B: 90, A: 128}, 16)
+	shaded.SetShadingMode(ShadingGouraud)
+	textured := NewUVSphere(70, 64, 64, color.RGBA{R: 255, G: 255, B: 255, A: 255}, color.RGBA{R: 255, G: 255, B: 255, A: 255}, 0)
+	textured.SetTexture(newTopBottomTexture())
+	terrain := NewSubdividedPlaneHeightMapPerlin(600, 600, color.RGBA{R: 153, G: 196, B: 210, A: 255}, 48, 40, 40, 42)
+
+	w := NewWorld3d()
+	w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+	w.AddObjectDrawFirst(&Entity{Model: terrain, Y: 120})
+	w.AddObject(&Entity{Model: shaded, X: -60})
+	w.AddObject(&Entity{Model: textured, X: 110, Y: -40})
+	return w
+}
+
+func TestWorld_SetParallelismDenseModels(t *testing.T) {
+	bg := color.RGBA{A: 255}
+	for e := range newDenseWorld().Entities() {
+		if n := len(e.Model.faceIndices); n < minParallelFaces {
+			t.Fatalf("model has %d faces, want at least %d", n, minParallelFaces)
+		}
+	}
+
+	serial := newDenseWorld()
+	parallel := newDenseWorld()
+	parallel.SetParallelism(4)
+
+	want := serial.Render(200, 150, bg)
+	got := parallel.Render(200, 150, bg)
+	if !compareImages(t, got, want) {
+		t.Error("parallel render differs from serial render")
+	}
+
+	serial.SetPolygonBatcher(NewDepthBatcher(100))
+	parallel.SetPolygonBatcher(NewDepthBatcher(100))
+	want = serial.Render(200, 150, bg)
+	got = parallel.Render(200, 150, bg)
+	if !compareImages(t, got, want) {
+		t.Error("parallel DepthBatcher render differs from serial render")
+	}
+
+	var wantSVG, gotSVG bytes.Buffer
+	if err := serial.RenderToSVG(&wantSVG, 200, 150, bg); err != nil {
+		t.Fatal(err)
+	}
+	if err := parallel.RenderToSVG(&gotSVG, 200, 150, bg); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotSVG.Bytes(), wantSVG.Bytes()) {
+		t.Error("parallel SVG differs from serial SVG")
+	}
+}
+
+// BenchmarkRenderTerrain renders a filled terrain of about 20,000 faces
+// serially and with a goroutine per CPU.
+func BenchmarkRenderTerrain(b *testing.B) {
+	for _, bm := range []struct {
+		name    string
+		workers int
+	}{
+		{"serial", 1},
+		{"parallel", runtime.NumCPU()},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			terrain := NewSubdividedPlaneHeightMapPerlin(
+				10000, 10000,
+				color.RGBA{R: 153, G: 196, B: 210, A: 255},
+				100, 800, 800, 42,
+			)
+			cam := NewCamera(0, 0, 0, 0, 0, 0)
+			cam.SetCameraPosition(500, -200, 0)
+			cam.LookAt(NewVector3(0, -100, 0), NewVector3(0, -1, 0))
+			world := NewWorld3d()
+			world.AddCamera(cam, 500, -200, 0)
+			world.AddObjectDrawFirst(&Entity{Model: terrain})
+			bg := color.RGBA{R: 10, G: 10, B: 30, A: 255}
+			world.SetParallelism(bm.workers)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				world.Render(1280, 720, bg)
+			}
+		})
+	}
+}
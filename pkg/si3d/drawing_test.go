@@ -1,6 +1,7 @@
 package si3d
 
 import (
+	"image"
 	"image/color"
 	"testing"
 )
@@ -95,3 +96,29 @@ func TestPolygonBatcher_AddPolygonAndOutline(t *testing.T) {
 		t.Error("AddPolygonAndOutline stroke color mismatch")
 	}
 }
+
+func TestPolygonBatcher_AddShadedPolygon(t *testing.T) {
+	pb := NewDefaultBatcher(10)
+	var _ ShadedPolygonBatcher = pb
+
+	// A horizontal ramp from black on the left to red on the right.
+	black := color.RGBA{A: 255}
+	red := color.RGBA{R: 255, A: 255}
+	xp := []float32{0, 100, 100, 0}
+	yp := []float32{0, 0, 10, 10}
+	pb.AddShadedPolygon(xp, yp, nil, []color.RGBA{black, red, red, black}, color.RGBA{}, 0, false)
+
+	target := image.NewRGBA(image.Rect(0, 0, 100, 10))
+	pb.Draw(target)
+
+	left, mid, right := target.RGBAAt(0, 5), target.RGBAAt(50, 5), target.RGBAAt(99, 5)
+	if left.R > 5 || right.R < 250 {
+		t.Errorf("ramp ends = %v, %v; want black and red", left, right)
+	}
+	if mid.R < 120 || mid.R > 135 {
+		t.Errorf("ramp middle = %v; want half red", mid)
+	}
+	if len(pb.commands) != 0 {
+		t.Errorf("Draw left %d commands", len(pb.commands))
+	}
+}
@@ -0,0 +1,174 @@
+package si3d
+
+import "sync/atomic"
+
+// lastEntityID is the most recently assigned entity ID.
+var lastEntityID atomic.Uint64
+
+// Entity places a Model in a World. Its model's points are transformed by
+// the model's Transform, then by the entity's own Transform, then moved to
+// X, Y, Z, and finally by the world matrix of its parent, if it has one.
+// Several entities may share a model while each carries its own position,
+// rotation and scale.
+//
+// Children added with AddChild are drawn along with their parent and follow
+// it as it moves, so only the root of a hierarchy is added to the World.
+// An entity without a Model draws nothing itself but still places its
+// children, which makes it a pivot or group node.
+type Entity struct {
+	Model   *Model
+	X, Y, Z float64
+	// Transform is the entity's local position, rotation and scale. nil is
+	// the identity, as it is for entities built as struct literals.
+	Transform *Transform
+	// Name is an optional label for finding the entity with
+	// World.FindByName. Names need not be unique.
+	Name string
+
+	id       uint64
+	hidden   bool
+	parent   *Entity
+	children []*Entity
+}
+
+// NewEntity returns an entity holding model at the origin with an identity
+// Transform.
+func NewEntity(model *Model) *Entity {
+	e := &Entity{Model: model, Transform: NewTransform()}
+	e.assignID()
+	return e
+}
+
+// ID returns the entity's ID, unique within the process and fixed for the
+// entity's lifetime. Entities built as struct literals are given one when
+// they are first added to a World or another entity; until then it is 0.
+func (e *Entity) ID() uint64 {
+	return e.id
+}
+
+func (e *Entity) assignID() {
+	if e.id == 0 {
+		e.id = lastEntityID.Add(1)
+	}
+}
+
+// SetVisible shows or hides the entity. A hidden entity is not drawn, and
+// neither are its children, but it stays in the World and keeps its ID.
+func (e *Entity) SetVisible(visible bool) {
+	e.hidden = !visible
+}
+
+// Visible reports whether the entity is drawn, ignoring its ancestors.
+func (e *Entity) Visible() bool {
+	return !e.hidden
+}
+
+// AddChild attaches child to e, detaching it from any previous parent. The
+// child's transform is then relative to e. Adding e itself or one of its
+// ancestors is ignored, as it would make a cycle.
+func (e *Entity) AddChild(child *Entity) {
+	if child == nil {
+		return
+	}
+	for a := e; a != nil; a = a.parent {
+		if a == child {
+			return
+		}
+	}
+	if child.parent != nil {
+		child.parent.RemoveChild(child)
+	}
+	child.assignID()
+	child.parent = e
+	e.children = append(e.children, child)
+}
+
+// RemoveChild detaches child from e, leaving it without a parent.
+func (e *Entity) RemoveChild(child *Entity) {
+	for i, c := range e.children {
+		if c == child {
+			e.children = append(e.children[:i], e.children[i+1:]...)
+			child.parent = nil
+			return
+		}
+	}
+}
+
+// Parent returns the entity e is attached to, or nil for a root entity.
+func (e *Entity) Parent() *Entity {
+	return e.parent
+}
+
+// Children returns the entities attached to e.
+func (e *Entity) Children() []*Entity {
+	return e.children
+}
+
+// LocalMatrix returns the matrix taking e's model space to its parent's
+// space: its Transform followed by the move to X, Y, Z.
+func (e *Entity) LocalMatrix() Matrix {
+	m := TransMatrix(e.X, e.Y, e.Z)
+	if e.Transform != nil {
+		m = m.MultiplyBy(e.Transform.GetMatrix())
+	}
+	return m
+}
+
+// WorldMatrix returns the matrix taking e's model space to world space, its
+// local matrix composed with those of its ancestors.
+func (e *Entity) WorldMatrix() Matrix {
+	m := e.LocalMatrix()
+	for p := e.parent; p != nil; p = p.parent {
+		m = p.LocalMatrix().MultiplyBy(m)
+	}
+	return m
+}
+
+// WorldPosition returns the world space position of e's origin.
+func (e *Entity) WorldPosition() Vector3 {
+	m := e.WorldMatrix()
+	return NewVector3(m.ThisMatrix[3][0], m.ThisMatrix[3][1], m.ThisMatrix[3][2])
+}
+
+// appendDrawable appends e, if it has a model, and then its descendants,
+// depth first, to dst. Hidden entities are left out with their descendants.
+func appendDrawable(dst []*Entity, e *Entity) []*Entity {
+	if e.hidden {
+		return dst
+	}
+	if e.Model != nil {
+		dst = append(dst, e)
+	}
+	for _, c := range e.children {
+		dst = appendDrawable(dst, c)
+	}
+	return dst
+}
+
+// find returns the first of e and its descendants, depth first, for which
+// match is true.
+func (e *Entity) find(match func(*Entity) bool) *Entity {
+	if match(e) {
+		return e
+	}
+	for _, c := range e.children {
+		if found := c.find(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// drawableEntities flattens entities and their descendants into the ones
+// with models to paint. Entities attached to a parent since they were added
+// to the world are skipped, as the parent draws them.
+func drawableEntities(entities []*Entity) []*Entity {
+	var out []*Entity
+	for _, e := range entities {
+		if e.parent != nil {
+			continue
+		}
+		out = appendDrawable(out, e)
+	}
+	return out
+}
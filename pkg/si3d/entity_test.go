@@ -0,0 +1,237 @@
+package si3d
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestEntity_WorldMatrix(t *testing.T) {
+	body := NewEntity(nil)
+	body.X = 100
+	body.Transform.Scale = NewVector3(2, 2, 2)
+
+	wheel := &Entity{X: 10, Y: 5}
+	body.AddChild(wheel)
+
+	// The wheel's offset is scaled by its parent before the parent moves.
+	if got, want := wheel.WorldPosition(), NewVector3(120, 10, 0); got != want {
+		t.Errorf("wheel at %v, want %v", got, want)
+	}
+
+	// Turning the parent half a turn about Z swings the wheel to the other side.
+	body.Transform.Scale = NewVector3(1, 1, 1)
+	body.Transform.Rotate(NewVector3(0, 0, 1), math.Pi)
+	got := wheel.WorldPosition()
+	if math.Abs(got.X-90) > 1e-9 || math.Abs(got.Y+5) > 1e-9 || math.Abs(got.Z) > 1e-9 {
+		t.Errorf("wheel at %v after turning, want (90, -5, 0)", got)
+	}
+
+	// Grandchildren compose through every ancestor.
+	hub := &Entity{Z: 3}
+	wheel.AddChild(hub)
+	got = hub.WorldPosition()
+	if math.Abs(got.X-90) > 1e-9 || math.Abs(got.Y+5) > 1e-9 || math.Abs(got.Z-3) > 1e-9 {
+		t.Errorf("hub at %v, want (90, -5, 3)", got)
+	}
+}
+
+func TestEntity_AddChild(t *testing.T) {
+	a, b, c := NewEntity(nil), NewEntity(nil), NewEntity(nil)
+	a.AddChild(b)
+	b.AddChild(c)
+
+	if b.Parent() != a || c.Parent() != b || len(a.Children()) != 1 {
+		t.Fatal("AddChild did not link parent and child")
+	}
+
+	// Cycles are refused.
+	c.AddChild(a)
+	b.AddChild(b)
+	if a.Parent() != nil || b.Parent() != a || len(c.Children()) != 0 {
+		t.Error("AddChild made a cycle")
+	}
+
+	// Re-parenting detaches the child from its old parent.
+	a.AddChild(c)
+	if c.Parent() != a || len(b.Children()) != 0 || len(a.Children()) != 2 {
+		t.Error("AddChild did not move the child to its new parent")
+	}
+
+	a.RemoveChild(c)
+	if c.Parent() != nil || len(a.Children()) != 1 {
+		t.Error("RemoveChild did not detach the child")
+	}
+}
+
+// newTestWorld returns a world with the standard test camera and the given
+// entities added with AddObject.
+func newTestWorld(entities ...*Entity) *World {
+	w := NewWorld3d()
+	w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+	for _, e := range entities {
+		w.AddObject(e)
+	}
+	return w
+}
+
+// renderWorld renders w at the standard test size over black.
+func renderWorld(w *World, opts ...RenderOptions) *image.RGBA {
+	return w.Render(200, 150, color.RGBA{A: 255}, opts...)
+}
+
+// renderEntities renders entities added with AddObject from the standard
+// test camera.
+func renderEntities(entities ...*Entity) *image.RGBA {
+	return renderWorld(newTestWorld(entities...))
+}
+
+// differingPixels counts the pixels that differ between two images of the
+// same size.
+func differingPixels(a, b *image.RGBA) int {
+	n := 0
+	for i := 0; i < len(a.Pix); i += 4 {
+		if !bytes.Equal(a.Pix[i:i+4], b.Pix[i:i+4]) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestEntity_SharedModelOrientations(t *testing.T) {
+	cube := NewCube()
+
+	turned := NewEntity(cube)
+	turned.Transform.Rotate(NewVector3(0, 1, 0), 0.5)
+	turned.Transform.Scale = NewVector3(1.5, 1, 1)
+	plain := NewEntity(cube)
+
+	gotTurned := renderEntities(turned)
+	gotPlain := renderEntities(plain)
+	if differingPixels(gotTurned, gotPlain) == 0 {
+		t.Fatal("entities sharing a model render the same despite different transforms")
+	}
+	if cube.Transform.Rotation != NewTransform().Rotation {
+		t.Error("rendering an entity changed its model's Transform")
+	}
+
+	// The entity's transform matches baking the same one into a copy of the
+	// model, up to rounding in the order the matrices are multiplied.
+	baked := cube.Clone()
+	baked.Transform.Rotate(NewVector3(0, 1, 0), 0.5)
+	baked.Transform.Scale = NewVector3(1.5, 1, 1)
+	want := renderEntities(&Entity{Model: baked})
+	if n := differingPixels(gotTurned, want); n > 20 {
+		t.Errorf("%d pixels differ from a render with the transform on the model", n)
+	}
+}
+
+func TestEntity_ChildrenFollowParent(t *testing.T) {
+	body := NewRectangle(120, 40, 40, color.RGBA{R: 200, A: 255})
+	wheel := NewCube()
+
+	// A pivot without a model carries the body and its wheels.
+	car := &Entity{X: -40, Y: 10}
+	car.AddChild(&Entity{Model: body})
+	car.AddChild(&Entity{Model: wheel, X: -50, Y: 30})
+	car.AddChild(&Entity{Model: wheel, X: 50, Y: 30})
+
+	want := renderEntities(
+		&Entity{Model: body, X: -40, Y: 10},
+		&Entity{Model: wheel, X: -90, Y: 40},
+		&Entity{Model: wheel, X: 10, Y: 40},
+	)
+	if got := renderEntities(car); !compareImages(t, got, want) {
+		t.Error("hierarchy renders differently from the same entities placed directly")
+	}
+
+	// Moving the parent moves everything attached to it.
+	car.X = 20
+	want = renderEntities(
+		&Entity{Model: body, X: 20, Y: 10},
+		&Entity{Model: wheel, X: -30, Y: 40},
+		&Entity{Model: wheel, X: 70, Y: 40},
+	)
+	if got := renderEntities(car); !compareImages(t, got, want) {
+		t.Error("children did not follow their parent")
+	}
+}
+
+func TestWorld_WriteGLBHierarchy(t *testing.T) {
+	cube := NewCube()
+	car := NewEntity(cube)
+	car.X = 10
+	car.Transform.Scale = NewVector3(2, 2, 2)
+	car.AddChild(&Entity{Model: cube, Y: 5})
+
+	w := NewWorld3d()
+	w.AddObject(car)
+
+	var buf bytes.Buffer
+	if err := w.WriteGLB(&buf); err != nil {
+		t.Fatalf("WriteGLB: %v", err)
+	}
+	jsonData, _, err := readGLB(buf.Bytes())
+	if err != nil {
+		t.Fatalf("readGLB: %v", err)
+	}
+	doc := decodeGLTF(t, jsonData)
+
+	root := doc.Nodes[doc.Scenes[0].Nodes[0]]
+	if len(root.Children) != 1 {
+		t.Fatalf("root has %d children, want 1", len(root.Children))
+	}
+	carNode := doc.Nodes[root.Children[0]]
+	if carNode.Mesh != nil || carNode.Translation[0] != 10 || carNode.Scale[0] != 2 || len(carNode.Children) != 2 {
+		t.Fatalf("car node = %+v", carNode)
+	}
+	model, wheel := doc.Nodes[carNode.Children[0]], doc.Nodes[carNode.Children[1]]
+	if model.Mesh == nil || model.Translation != nil || wheel.Mesh == nil || wheel.Translation[1] != 5 {
+		t.Errorf("car children = %+v, %+v", model, wheel)
+	}
+}
+
+func TestEntity_SetVisible(t *testing.T) {
+	cube := NewCube()
+	shown := &Entity{Model: cube, X: -60}
+	hidden := &Entity{Model: cube, X: 60}
+	hidden.AddChild(&Entity{Model: cube, Y: 60})
+
+	want := renderEntities(&Entity{Model: cube, X: -60})
+
+	hidden.SetVisible(false)
+	if hidden.Visible() {
+		t.Error("SetVisible(false) left the entity visible")
+	}
+	if got := renderEntities(shown, hidden); !compareImages(t, got, want) {
+		t.Error("hidden entity or its child was drawn")
+	}
+
+	hidden.SetVisible(true)
+	if got := renderEntities(shown, hidden); differingPixels(got, want) == 0 {
+		t.Error("entity shown again is not drawn")
+	}
+}
+
+func TestEntity_ScaledShading(t *testing.T) {
+	// A cube three times the size, three times as far away, covers the same
+	// pixels and must be lit the same.
+	plain := renderEntities(&Entity{Model: NewCube()}).RGBAAt(100, 75)
+
+	big := NewEntity(NewCube())
+	big.Transform.Scale = NewVector3(3, 3, 3)
+	big.Z = 600
+	if got := renderEntities(big).RGBAAt(100, 75); got != plain {
+		t.Errorf("scaled cube is %v, want %v", got, plain)
+	}
+
+	// Stretching along the view axis leaves the front face's normal alone.
+	deep := NewEntity(NewCube())
+	deep.Transform.Scale = NewVector3(1, 1, 3)
+	deep.Z = 80
+	if got := renderEntities(deep).RGBAAt(100, 75); got != plain {
+		t.Errorf("stretched cube is %v, want %v", got, plain)
+	}
+}
@@ -0,0 +1,86 @@
+package si3d
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// EPSBatcher is a PolygonBatcher that records polygons as Encapsulated
+// PostScript, one point per pixel. Like SVGBatcher it clips polygons to the
+// bounding box and fills Gouraud shaded and textured polygons with their
+// average colour. PostScript has no transparency, so translucent fills are drawn
+// opaque at their unpremultiplied colour and fully transparent ones are
+// skipped. Strokes less than half opaque are skipped too: drawn opaque, the
+// faint default outlines, at an alpha of 25, would become solid lines.
+type EPSBatcher struct {
+	vectorBatcher
+}
+
+// epsMinStrokeAlpha is the least alpha a stroke is drawn with in EPS output.
+const epsMinStrokeAlpha = 128
+
+func NewEPSBatcher(initialCap int) *EPSBatcher {
+	return &EPSBatcher{vectorBatcher: newVectorBatcher(initialCap)}
+}
+
+// writeEPSPolygon writes cmd as a PostScript path, filled and stroked.
+func writeEPSPolygon(buf *bytes.Buffer, cmd polygonCommand) {
+	if len(cmd.xp) == 0 {
+		return
+	}
+
+	fill := unpremultiply(cmd.flatFill())
+	stroke := unpremultiply(cmd.strokeClr)
+	hasFill := cmd.hasFill && fill.A > 0
+	hasStroke := cmd.hasStroke && stroke.A >= epsMinStrokeAlpha
+	if !hasFill && !hasStroke {
+		return
+	}
+
+	fmt.Fprintf(buf, "%s %s M", formatCoord(cmd.xp[0]), formatCoord(cmd.yp[0]))
+	for i := 1; i < len(cmd.xp); i++ {
+		fmt.Fprintf(buf, " %s %s L", formatCoord(cmd.xp[i]), formatCoord(cmd.yp[i]))
+	}
+	buf.WriteString(" closepath\n")
+
+	switch {
+	case hasFill && hasStroke:
+		fmt.Fprintf(buf, "gsave %s C fill grestore %s C %s setlinewidth stroke\n",
+			formatRGB(fill), formatRGB(stroke), formatCoord(cmd.strokeW))
+	case hasFill:
+		fmt.Fprintf(buf, "%s C fill\n", formatRGB(fill))
+	default:
+		fmt.Fprintf(buf, "%s C %s setlinewidth stroke\n", formatRGB(stroke), formatCoord(cmd.strokeW))
+	}
+}
+
+// WriteEPS writes an EPS document of the given size holding every polygon
+// drawn since the last call, over a background of bg, and then clears the
+// document.
+func (b *EPSBatcher) WriteEPS(w io.Writer, width, height int, bg color.Color) error {
+	var doc bytes.Buffer
+	doc.WriteString("%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(&doc, "%%%%BoundingBox: 0 0 %d %d\n", width, height)
+	doc.WriteString("%%Creator: si3d\n%%LanguageLevel: 2\n%%Pages: 1\n%%EndComments\n")
+	doc.WriteString("/M /moveto load def /L /lineto load def /C /setrgbcolor load def\n")
+	// Flip the page so that y grows downwards, as on screen, and stroke
+	// with round joins and caps as gg does.
+	fmt.Fprintf(&doc, "gsave\n0 %d translate 1 -1 scale\n1 setlinejoin 1 setlinecap\n", height)
+	if bg != nil {
+		if c := unpremultiply(color.RGBAModel.Convert(bg).(color.RGBA)); c.A > 0 {
+			fmt.Fprintf(&doc, "%s C 0 0 %d %d rectfill\n", formatRGB(c), width, height)
+		}
+	}
+
+	for _, cmd := range b.takeDocument(width, height) {
+		writeEPSPolygon(&doc, cmd)
+	}
+	if _, err := w.Write(doc.Bytes()); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "grestore\nshowpage\n%%EOF\n")
+	return err
+}
@@ -0,0 +1,108 @@
+package si3d
+
+import (
+	"bytes"
+	"image/color"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEPSBatcher_Polygons(t *testing.T) {
+	b := NewEPSBatcher(10)
+	var _ ShadedPolygonBatcher = b
+
+	xp, yp := square(1, 2, 10.5, 20)
+	b.AddPolygon(xp, yp, color.RGBA{R: 255, A: 255})
+	b.AddPolygonOutline(xp, yp, 2, color.RGBA{G: 64, A: 128})
+	b.AddPolygonAndOutline(xp, yp, color.RGBA{B: 255, A: 255}, color.RGBA{A: 255}, 0.5)
+	b.AddPolygon(xp, yp, color.RGBA{})
+	b.AddPolygonOutline(xp, yp, 1, color.RGBA{A: 25})
+	b.AddPolygonAndOutline(xp, yp, color.RGBA{R: 255, G: 255, A: 255}, color.RGBA{A: 25}, 1)
+	b.Draw(nil)
+
+	var buf bytes.Buffer
+	if err := b.WriteEPS(&buf, 32, 24, color.RGBA{G: 255, A: 255}); err != nil {
+		t.Fatalf("WriteEPS: %v", err)
+	}
+	out := buf.String()
+
+	want := []string{
+		"%!PS-Adobe-3.0 EPSF-3.0\n%%BoundingBox: 0 0 32 24\n",
+		"0 24 translate 1 -1 scale\n",
+		"0 1 0 C 0 0 32 24 rectfill\n",
+		"1 2 M 10.5 2 L 10.5 20 L 1 20 L closepath\n1 0 0 C fill\n",
+		// Translucent colours are drawn opaque.
+		"closepath\n0 0.498 0 C 2 setlinewidth stroke\n",
+		"closepath\ngsave 0 0 1 C fill grestore 0 0 0 C 0.5 setlinewidth stroke\n",
+		// Faint strokes are skipped rather than drawn solid.
+		"closepath\n1 1 0 C fill\n",
+		"grestore\nshowpage\n%%EOF\n",
+	}
+	last := -1
+	for _, w := range want {
+		i := strings.Index(out, w)
+		if i < 0 {
+			t.Errorf("EPS lacks %q\n%s", w, out)
+			continue
+		}
+		if i < last {
+			t.Errorf("%q is out of draw order", w)
+		}
+		last = i
+	}
+	if n := strings.Count(out, "closepath"); n != 4 {
+		t.Errorf("EPS has %d paths, want 4 (transparent polygons and faint outlines are skipped)", n)
+	}
+
+	// WriteEPS starts the next document afresh.
+	buf.Reset()
+	if err := b.WriteEPS(&buf, 32, 24, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "closepath") || strings.Contains(buf.String(), "rectfill") {
+		t.Errorf("second document is not empty:\n%s", buf.String())
+	}
+}
+
+func TestWorld_RenderToEPS(t *testing.T) {
+	w := NewWorld3d()
+	counter := &countingBatcher{DefaultBatcher: *NewDefaultBatcher(100)}
+	w.SetPolygonBatcher(counter)
+
+	cam := NewCamera(0, 0, -300, 0, 0, 0)
+	w.AddCamera(cam, 0, 0, -300)
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(0, 1, 0), 0.5)
+	w.AddObject(&Entity{Model: cube})
+
+	var buf bytes.Buffer
+	if err := w.RenderToEPS(&buf, 200, 150, color.RGBA{A: 255}); err != nil {
+		t.Fatalf("RenderToEPS: %v", err)
+	}
+	if w.batcher != counter || counter.count != 0 {
+		t.Error("RenderToEPS did not leave the world's batcher alone")
+	}
+
+	w.Render(200, 150, color.RGBA{A: 255})
+	if paths := strings.Count(buf.String(), "closepath"); paths == 0 || paths != counter.count {
+		t.Errorf("EPS has %d paths; the raster render drew %d", paths, counter.count)
+	}
+
+	// The faint default outlines are left out rather than drawn as solid
+	// black lines.
+	if strings.Contains(buf.String(), "stroke") {
+		t.Error("EPS strokes the default outlines")
+	}
+}
+
+func TestWorld_RenderToEPSClipsShaded(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newEdgeWorld().RenderToEPS(&buf, 200, 150, color.RGBA{A: 255}); err != nil {
+		t.Fatal(err)
+	}
+	point := regexp.MustCompile(`(-?[\d.]+) (-?[\d.]+) [ML]\b`)
+	if n := pointsOutside(t, buf.String(), point, 200, 150); n > 0 {
+		t.Errorf("%d EPS points lie outside the bounding box", n)
+	}
+}
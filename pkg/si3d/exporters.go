@@ -2,8 +2,10 @@ package si3d
 
 import (
 	"bufio"
+	"encoding/binary"
 	"fmt"
 	"image/color"
+	"io"
 	"os"
 )
 
@@ -111,49 +113,50 @@ func (o *Model) SaveDXF(fileName string) error {
 }
 
 func (o *Model) SavePLYWithFaceColors(fileName string) error {
+	return o.savePLY(fileName, false)
+}
+
+// SaveBinaryPLYWithFaceColors is SavePLYWithFaceColors writing the
+// binary_little_endian format.
+func (o *Model) SaveBinaryPLYWithFaceColors(fileName string) error {
+	return o.savePLY(fileName, true)
+}
+
+func (o *Model) savePLY(fileName string, binary bool) error {
 	file, err := os.Create(fileName)
 	if err != nil {
 		return fmt.Errorf("could not create PLY file %s: %w", fileName, err)
 	}
 	defer file.Close()
 
-	writer := bufio.NewWriter(file)
+	return o.WritePLYWithFaceColors(file, binary)
+}
 
-	// Helper struct to associate face indices with their color.
-	type coloredFace struct {
-		indices []int
-		color   color.RGBA
-	}
+// WritePLYWithFaceColors writes the model to w as a PLY file with one colour
+// per face, in the binary_little_endian format or as ASCII. Both formats
+// share the same header layout.
+func (o *Model) WritePLYWithFaceColors(w io.Writer, binary bool) error {
+	writer := bufio.NewWriter(w)
 
 	// Collect all face indices and their corresponding colors
 	allFaces := make([]coloredFace, 0)
-	if o.canPaintWithoutBSP {
-		for i, faceIdxs := range o.faceIndices {
-			if i < len(o.faces.faces) {
-				faceColor := o.faces.faces[i].Col
-				allFaces = append(allFaces, coloredFace{indices: faceIdxs, color: faceColor})
-			}
-		}
-	} else if o.root != nil {
-		var collectFromBSP func(node *BspNode)
-		collectFromBSP = func(node *BspNode) {
-			if node == nil {
-				return
-			}
-			nodeColor := color.RGBA{R: node.colRed, G: node.colGreen, B: node.colBlue, A: node.colAlpha}
-			allFaces = append(allFaces, coloredFace{indices: node.facePointIndices, color: nodeColor})
-			collectFromBSP(node.Left)
-			collectFromBSP(node.Right)
+	for _, face := range o.collectColoredFaces() {
+		if len(face.indices) >= 3 {
+			allFaces = append(allFaces, face)
 		}
-		collectFromBSP(o.root)
 	}
 
 	numVertices := len(o.faceMesh.Points)
 
+	format := "ascii"
+	if binary {
+		format = "binary_little_endian"
+	}
+
 	// Write the modified PLY file header
 	// Note that the color properties are now part of the "face" element.
 	_, _ = fmt.Fprintln(writer, "ply")
-	_, _ = fmt.Fprintln(writer, "format ascii 1.0")
+	_, _ = fmt.Fprintf(writer, "format %s 1.0\n", format)
 	_, _ = fmt.Fprintf(writer, "comment Generated by si3d with face colors\n")
 	_, _ = fmt.Fprintf(writer, "element vertex %d\n", numVertices)
 	_, _ = fmt.Fprintln(writer, "property float x")
@@ -166,6 +169,23 @@ func (o *Model) SavePLYWithFaceColors(fileName string) error {
 	_, _ = fmt.Fprintln(writer, "property uchar blue")  // <-- Moved to face element
 	_, _ = fmt.Fprintln(writer, "end_header")
 
+	if binary {
+		for _, vertex := range o.faceMesh.Points {
+			_ = binaryWrite(writer, [3]float32{float32(vertex.X), float32(vertex.Y), float32(vertex.Z)})
+		}
+		for _, face := range allFaces {
+			if len(face.indices) > 255 {
+				return fmt.Errorf("face with %d vertices does not fit a uchar count", len(face.indices))
+			}
+			_ = writer.WriteByte(uint8(len(face.indices)))
+			for _, vIndex := range face.indices {
+				_ = binaryWrite(writer, int32(vIndex))
+			}
+			_, _ = writer.Write([]byte{face.color.R, face.color.G, face.color.B})
+		}
+		return writer.Flush()
+	}
+
 	// --- 3. Write vertex data (coordinates ONLY) ---
 	for _, vertex := range o.faceMesh.Points {
 		_, _ = fmt.Fprintf(writer, "%f %f %f\n", vertex.X, vertex.Y, vertex.Z)
@@ -173,13 +193,8 @@ func (o *Model) SavePLYWithFaceColors(fileName string) error {
 
 	// --- 4. Write face data (indices AND color) ---
 	for _, face := range allFaces {
-		numFaceVerts := len(face.indices)
-		if numFaceVerts < 3 {
-			continue
-		}
-
 		// Start line with number of vertices for this face.
-		_, _ = fmt.Fprintf(writer, "%d", numFaceVerts)
+		_, _ = fmt.Fprintf(writer, "%d", len(face.indices))
 
 		// Append the vertex indices.
 		for _, vIndex := range face.indices {
@@ -193,3 +208,133 @@ func (o *Model) SavePLYWithFaceColors(fileName string) error {
 
 	return writer.Flush()
 }
+
+// coloredFace associates a polygon's point indices with its colour and
+// untransformed face normal.
+type coloredFace struct {
+	indices []int
+	color   color.RGBA
+	normal  Vector3
+}
+
+// collectColoredFaces returns every polygon of the model, taken from the face
+// list or by walking the BSP tree.
+func (o *Model) collectColoredFaces() []coloredFace {
+	allFaces := make([]coloredFace, 0)
+	if o.canPaintWithoutBSP {
+		for i, faceIdxs := range o.faceIndices {
+			if i < len(o.faces.faces) {
+				faceColor := o.faces.faces[i].Col
+				normal := o.normalMesh.Points[o.normalIndices[i]]
+				allFaces = append(allFaces, coloredFace{indices: faceIdxs, color: faceColor, normal: normal})
+			}
+		}
+	} else if o.root != nil {
+		var collectFromBSP func(node *BspNode)
+		collectFromBSP = func(node *BspNode) {
+			if node == nil {
+				return
+			}
+			nodeColor := color.RGBA{R: node.colRed, G: node.colGreen, B: node.colBlue, A: node.colAlpha}
+			normal := o.normalMesh.Points[node.normalIndex]
+			allFaces = append(allFaces, coloredFace{indices: node.facePointIndices, color: nodeColor, normal: normal})
+			collectFromBSP(node.Left)
+			collectFromBSP(node.Right)
+		}
+		collectFromBSP(o.root)
+	}
+	return allFaces
+}
+
+// outward returns the face's outward normal. The engine's face normals point
+// into a solid, whereas exchange formats expect them to point out.
+func (f coloredFace) outward() Vector3 {
+	return NewVector3(-f.normal.X, -f.normal.Y, -f.normal.Z)
+}
+
+// outwardTriangles splits a face into a triangle fan of point indices, each
+// wound counter-clockwise around the face's outward normal.
+func (o *Model) outwardTriangles(face coloredFace) [][3]int {
+	outward := face.outward()
+	triangles := make([][3]int, 0, max(len(face.indices)-2, 0))
+	for i := 1; i+1 < len(face.indices); i++ {
+		tri := [3]int{face.indices[0], face.indices[i], face.indices[i+1]}
+		a := o.faceMesh.Points[tri[0]]
+		b := o.faceMesh.Points[tri[1]]
+		c := o.faceMesh.Points[tri[2]]
+		if Dot(Cross(Subtract(b, a), Subtract(c, a)), outward) < 0 {
+			tri[1], tri[2] = tri[2], tri[1]
+		}
+		triangles = append(triangles, tri)
+	}
+	return triangles
+}
+
+// SaveSTL writes the model to w as an STL file, in the little-endian binary
+// format or as ASCII. Polygons are split into triangle fans, wound
+// counter-clockwise around outward facing normals as STL expects. Binary
+// files carry each face colour in the VisCAM/SolidView attribute format.
+func (o *Model) SaveSTL(w io.Writer, binary bool) error {
+	type triangle struct {
+		normal Vector3
+		points [3]Vector3
+		color  color.RGBA
+	}
+
+	var triangles []triangle
+	for _, face := range o.collectColoredFaces() {
+		outward := face.outward()
+		for _, tri := range o.outwardTriangles(face) {
+			points := [3]Vector3{o.faceMesh.Points[tri[0]], o.faceMesh.Points[tri[1]], o.faceMesh.Points[tri[2]]}
+			triangles = append(triangles, triangle{normal: outward, points: points, color: face.color})
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+
+	if binary {
+		header := make([]byte, 80)
+		copy(header, "Generated by si3d")
+		_, _ = writer.Write(header)
+		_ = binaryWrite(writer, uint32(len(triangles)))
+
+		for _, tri := range triangles {
+			values := [12]float32{
+				float32(tri.normal.X), float32(tri.normal.Y), float32(tri.normal.Z),
+			}
+			for i, p := range tri.points {
+				values[3+i*3] = float32(p.X)
+				values[4+i*3] = float32(p.Y)
+				values[5+i*3] = float32(p.Z)
+			}
+			_ = binaryWrite(writer, values)
+			_ = binaryWrite(writer, stlColorAttribute(tri.color))
+		}
+		return writer.Flush()
+	}
+
+	_, _ = fmt.Fprintln(writer, "solid si3d")
+	for _, tri := range triangles {
+		_, _ = fmt.Fprintf(writer, "  facet normal %e %e %e\n", tri.normal.X, tri.normal.Y, tri.normal.Z)
+		_, _ = fmt.Fprintln(writer, "    outer loop")
+		for _, p := range tri.points {
+			_, _ = fmt.Fprintf(writer, "      vertex %e %e %e\n", p.X, p.Y, p.Z)
+		}
+		_, _ = fmt.Fprintln(writer, "    endloop")
+		_, _ = fmt.Fprintln(writer, "  endfacet")
+	}
+	_, _ = fmt.Fprintln(writer, "endsolid si3d")
+
+	return writer.Flush()
+}
+
+// binaryWrite writes fixed-size data in little-endian byte order.
+func binaryWrite(w io.Writer, data any) error {
+	return binary.Write(w, binary.LittleEndian, data)
+}
+
+// stlColorAttribute packs a colour into the 15-bit VisCAM/SolidView format,
+// with bit 15 marking the colour as valid.
+func stlColorAttribute(c color.RGBA) uint16 {
+	return 1<<15 | uint16(c.R>>3)<<10 | uint16(c.G>>3)<<5 | uint16(c.B>>3)
+}
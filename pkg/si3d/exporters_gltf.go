@@ -0,0 +1,344 @@
+package si3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gltfBuilder accumulates a glTF document and its binary buffer.
+type gltfBuilder struct {
+	doc       gltfDocument
+	bin       bytes.Buffer
+	meshes    map[*Model]int
+	materials map[gltfMaterialKey]int
+}
+
+type gltfMaterialKey struct {
+	color       color.RGBA
+	doubleSided bool
+}
+
+func newGLTFBuilder() *gltfBuilder {
+	return &gltfBuilder{
+		doc: gltfDocument{
+			Asset: gltfAsset{Version: "2.0", Generator: "si3d"},
+		},
+		meshes:    make(map[*Model]int),
+		materials: make(map[gltfMaterialKey]int),
+	}
+}
+
+// addBufferView appends data to the binary buffer, aligned to four bytes,
+// and returns the index of its buffer view.
+func (b *gltfBuilder) addBufferView(data []byte, target int) int {
+	for b.bin.Len()%4 != 0 {
+		b.bin.WriteByte(0)
+	}
+	b.doc.BufferViews = append(b.doc.BufferViews, gltfBufferView{
+		ByteOffset: b.bin.Len(),
+		ByteLength: len(data),
+		Target:     target,
+	})
+	b.bin.Write(data)
+	return len(b.doc.BufferViews) - 1
+}
+
+func (b *gltfBuilder) addAccessor(a gltfAccessor) int {
+	b.doc.Accessors = append(b.doc.Accessors, a)
+	return len(b.doc.Accessors) - 1
+}
+
+// addMaterial returns the index of a matte material of the given colour,
+// sharing materials between faces of the same colour.
+func (b *gltfBuilder) addMaterial(key gltfMaterialKey) int {
+	if idx, ok := b.materials[key]; ok {
+		return idx
+	}
+
+	// Face colours are premultiplied sRGB; glTF factors are straight linear.
+	c := unpremultiply(key.color)
+	r, g, bl := c.R, c.G, c.B
+
+	metallic, roughness := 0.0, 1.0
+	mat := gltfMaterial{
+		Name: fmt.Sprintf("color_%02x%02x%02x%02x", r, g, bl, c.A),
+		PBRMetallicRoughness: &gltfPBR{
+			BaseColorFactor: []float64{srgbToLinear(r), srgbToLinear(g), srgbToLinear(bl), float64(c.A) / 255},
+			MetallicFactor:  &metallic,
+			RoughnessFactor: &roughness,
+		},
+		DoubleSided: key.doubleSided,
+	}
+	if c.A < 255 {
+		mat.AlphaMode = "BLEND"
+	}
+
+	b.doc.Materials = append(b.doc.Materials, mat)
+	idx := len(b.doc.Materials) - 1
+	b.materials[key] = idx
+	return idx
+}
+
+// addMesh writes a model's untransformed geometry as a mesh, returning its
+// index, or -1 if the model has no faces. All primitives share one position
+// accessor; each colour becomes its own primitive and material. No normals
+// are written, so viewers shade the triangles flat, as si3d does by default.
+func (b *gltfBuilder) addMesh(o *Model) int {
+	if idx, ok := b.meshes[o]; ok {
+		return idx
+	}
+
+	var colors []color.RGBA
+	trianglesByColor := make(map[color.RGBA][]uint32)
+	for _, face := range o.collectColoredFaces() {
+		for _, tri := range o.outwardTriangles(face) {
+			if _, ok := trianglesByColor[face.color]; !ok {
+				colors = append(colors, face.color)
+			}
+			trianglesByColor[face.color] = append(trianglesByColor[face.color], uint32(tri[0]), uint32(tri[1]), uint32(tri[2]))
+		}
+	}
+	if len(colors) == 0 {
+		b.meshes[o] = -1
+		return -1
+	}
+
+	points := o.faceMesh.Points
+	positions := make([]float32, 0, len(points)*3)
+	minPos := []float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	maxPos := []float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range points {
+		for i, v := range [3]float32{float32(p.X), float32(p.Y), float32(p.Z)} {
+			positions = append(positions, v)
+			minPos[i] = math.Min(minPos[i], float64(v))
+			maxPos[i] = math.Max(maxPos[i], float64(v))
+		}
+	}
+
+	var data bytes.Buffer
+	_ = binary.Write(&data, binary.LittleEndian, positions)
+	posView := b.addBufferView(data.Bytes(), gltfTargetArrayBuffer)
+	posAccessor := b.addAccessor(gltfAccessor{
+		BufferView:    &posView,
+		ComponentType: gltfComponentFloat,
+		Count:         len(points),
+		Type:          "VEC3",
+		Min:           minPos,
+		Max:           maxPos,
+	})
+
+	mesh := gltfMesh{}
+	for _, c := range colors {
+		indices := trianglesByColor[c]
+		data.Reset()
+		_ = binary.Write(&data, binary.LittleEndian, indices)
+		view := b.addBufferView(data.Bytes(), gltfTargetElementArrayBuffer)
+		accessor := b.addAccessor(gltfAccessor{
+			BufferView:    &view,
+			ComponentType: gltfComponentUnsignedInt,
+			Count:         len(indices),
+			Type:          "SCALAR",
+		})
+		material := b.addMaterial(gltfMaterialKey{color: c, doubleSided: o.drawAllFaces})
+		mode := gltfModeTriangles
+		mesh.Primitives = append(mesh.Primitives, gltfPrimitive{
+			Attributes: map[string]int{"POSITION": posAccessor},
+			Indices:    &accessor,
+			Material:   &material,
+			Mode:       &mode,
+		})
+	}
+
+	b.doc.Meshes = append(b.doc.Meshes, mesh)
+	idx := len(b.doc.Meshes) - 1
+	b.meshes[o] = idx
+	return idx
+}
+
+// addModelNode adds a node placing the model at (x, y, z) with its
+// Transform applied, and returns the node's index.
+func (b *gltfBuilder) addModelNode(o *Model, x, y, z float64) int {
+	node := gltfNode{}
+	if mesh := b.addMesh(o); mesh >= 0 {
+		node.Mesh = &mesh
+	}
+
+	node.setTransform(o.Transform, x, y, z)
+	b.doc.Nodes = append(b.doc.Nodes, node)
+	return len(b.doc.Nodes) - 1
+}
+
+// addEntityNode adds a node for an entity and its children, and returns its
+// index. An entity without a Transform or children is a single node like
+// addModelNode's; otherwise the entity's node carries its own transform,
+// with its model and its children's nodes below it.
+func (b *gltfBuilder) addEntityNode(e *Entity) int {
+	if e.Transform == nil && len(e.children) == 0 && e.Model != nil {
+		return b.addModelNode(e.Model, e.X, e.Y, e.Z)
+	}
+
+	t := e.Transform
+	if t == nil {
+		t = NewTransform()
+	}
+	node := gltfNode{}
+	node.setTransform(t, e.X, e.Y, e.Z)
+	if e.Model != nil {
+		node.Children = append(node.Children, b.addModelNode(e.Model, 0, 0, 0))
+	}
+	for _, c := range e.children {
+		node.Children = append(node.Children, b.addEntityNode(c))
+	}
+
+	b.doc.Nodes = append(b.doc.Nodes, node)
+	return len(b.doc.Nodes) - 1
+}
+
+// setTransform sets the node's TRS properties to t, moved by (x, y, z),
+// leaving out the ones that are the identity.
+func (node *gltfNode) setTransform(t *Transform, x, y, z float64) {
+	pos := NewVector3(x+t.Position.X, y+t.Position.Y, z+t.Position.Z)
+	if pos != NewVector3(0, 0, 0) {
+		node.Translation = []float64{pos.X, pos.Y, pos.Z}
+	}
+	if q := t.Rotation.Normalize(); q.W != 1 {
+		node.Rotation = []float64{q.V[0], q.V[1], q.V[2], q.W}
+	}
+	if t.Scale != NewVector3(1, 1, 1) {
+		node.Scale = []float64{t.Scale.X, t.Scale.Y, t.Scale.Z}
+	}
+}
+
+// finish adds the root node holding the axis conversion and the scene.
+func (b *gltfBuilder) finish(children []int) {
+	b.doc.Nodes = append(b.doc.Nodes, gltfNode{
+		Name:     "si3d",
+		Children: children,
+		Rotation: gltfAxisRotation,
+	})
+	scene := 0
+	b.doc.Scene = &scene
+	b.doc.Scenes = []gltfScene{{Nodes: []int{len(b.doc.Nodes) - 1}}}
+}
+
+// writeGLTF writes the document as JSON referencing the buffer by binURI,
+// and the buffer itself to bin.
+func (b *gltfBuilder) writeGLTF(w, bin io.Writer, binURI string) error {
+	if b.bin.Len() > 0 {
+		b.doc.Buffers = []gltfBuffer{{URI: binURI, ByteLength: b.bin.Len()}}
+	}
+	data, err := json.MarshalIndent(&b.doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding glTF JSON: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err = bin.Write(b.bin.Bytes())
+	return err
+}
+
+func (b *gltfBuilder) writeGLB(w io.Writer) error {
+	if b.bin.Len() > 0 {
+		b.doc.Buffers = []gltfBuffer{{ByteLength: b.bin.Len()}}
+	}
+	return writeGLB(w, &b.doc, b.bin.Bytes())
+}
+
+// saveGLTF writes a .gltf file and its .bin buffer alongside it.
+func (b *gltfBuilder) saveGLTF(fileName string) error {
+	binName := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".bin"
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("could not create glTF file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	binFile, err := os.Create(binName)
+	if err != nil {
+		return fmt.Errorf("could not create glTF buffer %s: %w", binName, err)
+	}
+	defer binFile.Close()
+
+	return b.writeGLTF(file, binFile, filepath.Base(binName))
+}
+
+func (b *gltfBuilder) saveGLB(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("could not create GLB file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	return b.writeGLB(file)
+}
+
+func (o *Model) gltfBuilder() *gltfBuilder {
+	b := newGLTFBuilder()
+	b.finish([]int{b.addModelNode(o, 0, 0, 0)})
+	return b
+}
+
+// WriteGLTF writes the model as a glTF 2.0 asset: the JSON to w and the
+// binary buffer, referenced from the JSON as binURI, to bin. The model is a
+// single node carrying its Transform. Each face colour becomes a material,
+// and a root node turns the engine's -Y up world into glTF's +Y up.
+func (o *Model) WriteGLTF(w, bin io.Writer, binURI string) error {
+	return o.gltfBuilder().writeGLTF(w, bin, binURI)
+}
+
+// WriteGLB writes the model as a single binary glTF (.glb) file, laid out as
+// for WriteGLTF.
+func (o *Model) WriteGLB(w io.Writer) error {
+	return o.gltfBuilder().writeGLB(w)
+}
+
+// SaveGLTF writes the model to fileName and its buffer to a .bin file of the
+// same name next to it.
+func (o *Model) SaveGLTF(fileName string) error {
+	return o.gltfBuilder().saveGLTF(fileName)
+}
+
+func (o *Model) SaveGLB(fileName string) error {
+	return o.gltfBuilder().saveGLB(fileName)
+}
+
+func (w *World) gltfBuilder() *gltfBuilder {
+	b := newGLTFBuilder()
+	var nodes []int
+	for e := range w.Entities() {
+		nodes = append(nodes, b.addEntityNode(e))
+	}
+	b.finish(nodes)
+	return b
+}
+
+// WriteGLTF writes every entity of the world as a glTF 2.0 asset, as
+// Model.WriteGLTF does for one model. Each entity is a node translated to
+// its X, Y, Z position with its model's Transform applied; entities sharing
+// a model share its mesh. Entities with their own Transform or children
+// keep their hierarchy as nested nodes.
+func (w *World) WriteGLTF(jsonW, bin io.Writer, binURI string) error {
+	return w.gltfBuilder().writeGLTF(jsonW, bin, binURI)
+}
+
+func (w *World) WriteGLB(out io.Writer) error {
+	return w.gltfBuilder().writeGLB(out)
+}
+
+func (w *World) SaveGLTF(fileName string) error {
+	return w.gltfBuilder().saveGLTF(fileName)
+}
+
+func (w *World) SaveGLB(fileName string) error {
+	return w.gltfBuilder().saveGLB(fileName)
+}
@@ -0,0 +1,218 @@
+package si3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func decodeGLTF(t *testing.T, data []byte) gltfDocument {
+	t.Helper()
+	var doc gltfDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid glTF JSON: %v", err)
+	}
+	return doc
+}
+
+func TestModel_WriteGLTF(t *testing.T) {
+	cube := NewCube()
+	cube.Transform.Position = NewVector3(1, 2, 3)
+	cube.Transform.Rotate(NewVector3(0, 1, 0), math.Pi/2)
+	cube.Transform.Scale = NewVector3(2, 2, 2)
+
+	var jsonBuf, bin bytes.Buffer
+	if err := cube.WriteGLTF(&jsonBuf, &bin, "cube.bin"); err != nil {
+		t.Fatalf("WriteGLTF: %v", err)
+	}
+	doc := decodeGLTF(t, jsonBuf.Bytes())
+
+	if doc.Asset.Version != "2.0" {
+		t.Errorf("asset version = %q", doc.Asset.Version)
+	}
+	if len(doc.Buffers) != 1 || doc.Buffers[0].URI != "cube.bin" || doc.Buffers[0].ByteLength != bin.Len() {
+		t.Fatalf("buffers = %+v; want one cube.bin of %d bytes", doc.Buffers, bin.Len())
+	}
+
+	// The scene root turns -Y up into +Y up and holds the model's node.
+	root := doc.Nodes[doc.Scenes[*doc.Scene].Nodes[0]]
+	if len(root.Rotation) != 4 || root.Rotation[0] != 1 || len(root.Children) != 1 {
+		t.Fatalf("root node = %+v", root)
+	}
+	node := doc.Nodes[root.Children[0]]
+	if node.Mesh == nil {
+		t.Fatal("model node has no mesh")
+	}
+	if len(node.Translation) != 3 || node.Translation[0] != 1 || node.Translation[1] != 2 || node.Translation[2] != 3 {
+		t.Errorf("translation = %v; want [1 2 3]", node.Translation)
+	}
+	if len(node.Rotation) != 4 || math.Abs(node.Rotation[1]-math.Sin(math.Pi/4)) > 1e-9 {
+		t.Errorf("rotation = %v; want a quarter turn about Y", node.Rotation)
+	}
+	if len(node.Scale) != 3 || node.Scale[0] != 2 {
+		t.Errorf("scale = %v; want [2 2 2]", node.Scale)
+	}
+
+	// Six differently coloured quads become six primitives of two
+	// triangles each, sharing one position accessor.
+	mesh := doc.Meshes[*node.Mesh]
+	if len(mesh.Primitives) != 6 || len(doc.Materials) != 6 {
+		t.Fatalf("primitives = %d, materials = %d; want 6 and 6", len(mesh.Primitives), len(doc.Materials))
+	}
+	pos := mesh.Primitives[0].Attributes["POSITION"]
+	for _, p := range mesh.Primitives {
+		if p.Attributes["POSITION"] != pos {
+			t.Error("primitives do not share the position accessor")
+		}
+		if n := doc.Accessors[*p.Indices].Count; n != 6 {
+			t.Errorf("index count = %d; want 6", n)
+		}
+	}
+	if a := doc.Accessors[pos]; a.Count != 8 || a.Min[0] != -40 || a.Max[0] != 40 {
+		t.Errorf("position accessor = %+v; want 8 points spanning -40..40", a)
+	}
+}
+
+func TestModel_WriteGLTF_Winding(t *testing.T) {
+	cube := NewCube()
+	var jsonBuf, bin bytes.Buffer
+	if err := cube.WriteGLTF(&jsonBuf, &bin, "cube.bin"); err != nil {
+		t.Fatalf("WriteGLTF: %v", err)
+	}
+	doc := decodeGLTF(t, jsonBuf.Bytes())
+	data := bin.Bytes()
+
+	readVec := func(accessor, i int) Vector3 {
+		a := doc.Accessors[accessor]
+		off := doc.BufferViews[*a.BufferView].ByteOffset + a.ByteOffset + i*12
+		f := func(o int) float64 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(data[off+o:])))
+		}
+		return NewVector3(f(0), f(4), f(8))
+	}
+
+	// Every triangle is wound counter-clockwise seen from outside the
+	// cube, which is centred on the origin.
+	for _, p := range doc.Meshes[0].Primitives {
+		a := doc.Accessors[*p.Indices]
+		off := doc.BufferViews[*a.BufferView].ByteOffset
+		for i := 0; i < a.Count; i += 3 {
+			var pts [3]Vector3
+			for k := range pts {
+				idx := int(binary.LittleEndian.Uint32(data[off+(i+k)*4:]))
+				pts[k] = readVec(p.Attributes["POSITION"], idx)
+			}
+			n := Cross(Subtract(pts[1], pts[0]), Subtract(pts[2], pts[0]))
+			if Dot(n, pts[0]) <= 0 {
+				t.Errorf("triangle %v is wound clockwise from outside", pts)
+			}
+		}
+	}
+}
+
+func TestModel_WriteGLTF_Materials(t *testing.T) {
+	m := NewModel()
+	for _, c := range []color.RGBA{{R: 255, A: 255}, {R: 128, G: 64, A: 128}, {R: 255, A: 255}} {
+		f := NewFace(nil, c, Vector3{})
+		f.AddPoint(0, 0, 0)
+		f.AddPoint(1, 0, 0)
+		f.AddPoint(0, 1, 0)
+		f.Finished(FACE_NORMAL)
+		m.faces.AddFace(f)
+	}
+	m.Compile()
+
+	var jsonBuf, bin bytes.Buffer
+	if err := m.WriteGLTF(&jsonBuf, &bin, "m.bin"); err != nil {
+		t.Fatalf("WriteGLTF: %v", err)
+	}
+	doc := decodeGLTF(t, jsonBuf.Bytes())
+
+	if len(doc.Materials) != 2 {
+		t.Fatalf("materials = %d; want the two distinct colours", len(doc.Materials))
+	}
+	red := doc.Materials[0]
+	if f := red.PBRMetallicRoughness.BaseColorFactor; f[0] != 1 || f[1] != 0 || f[3] != 1 || red.AlphaMode != "" {
+		t.Errorf("red material = %+v", red)
+	}
+	// The translucent colour is unpremultiplied and blended.
+	half := doc.Materials[1]
+	if f := half.PBRMetallicRoughness.BaseColorFactor; math.Abs(f[0]-1) > 1e-9 || math.Abs(f[1]-srgbToLinear(127)) > 1e-9 || half.AlphaMode != "BLEND" {
+		t.Errorf("translucent material = %+v", half)
+	}
+}
+
+func TestWorld_WriteGLB(t *testing.T) {
+	cube := NewCube()
+	w := NewWorld3d()
+	w.AddObject(&Entity{Model: cube, X: 10})
+	w.AddObjectDrawFirst(&Entity{Model: cube, Y: -5})
+	w.AddObjectDrawLast(&Entity{Model: NewModel()})
+
+	var buf bytes.Buffer
+	if err := w.WriteGLB(&buf); err != nil {
+		t.Fatalf("WriteGLB: %v", err)
+	}
+	data := buf.Bytes()
+
+	if binary.LittleEndian.Uint32(data) != glbMagic || binary.LittleEndian.Uint32(data[4:]) != 2 {
+		t.Fatal("bad GLB header")
+	}
+	if n := int(binary.LittleEndian.Uint32(data[8:])); n != len(data) {
+		t.Fatalf("GLB length = %d; file is %d bytes", n, len(data))
+	}
+	jsonLen := int(binary.LittleEndian.Uint32(data[12:]))
+	if jsonLen%4 != 0 || binary.LittleEndian.Uint32(data[16:]) != glbChunkJSON {
+		t.Fatal("bad JSON chunk")
+	}
+	doc := decodeGLTF(t, data[20:20+jsonLen])
+	binChunk := data[20+jsonLen:]
+	if binary.LittleEndian.Uint32(binChunk[4:]) != glbChunkBIN {
+		t.Fatal("missing BIN chunk")
+	}
+	if len(doc.Buffers) != 1 || doc.Buffers[0].URI != "" || doc.Buffers[0].ByteLength > int(binary.LittleEndian.Uint32(binChunk)) {
+		t.Errorf("buffers = %+v", doc.Buffers)
+	}
+
+	root := doc.Nodes[doc.Scenes[0].Nodes[0]]
+	if len(root.Children) != 3 {
+		t.Fatalf("root has %d children; want one per entity", len(root.Children))
+	}
+	first, second, empty := doc.Nodes[root.Children[0]], doc.Nodes[root.Children[1]], doc.Nodes[root.Children[2]]
+	if first.Translation[1] != -5 || second.Translation[0] != 10 {
+		t.Errorf("entity translations = %v, %v", first.Translation, second.Translation)
+	}
+	// Both cube entities share one mesh; the empty model has none.
+	if len(doc.Meshes) != 1 || *first.Mesh != 0 || *second.Mesh != 0 || empty.Mesh != nil {
+		t.Errorf("meshes = %d, node meshes = %v %v %v", len(doc.Meshes), first.Mesh, second.Mesh, empty.Mesh)
+	}
+}
+
+func TestModel_SaveGLTF(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "cube.gltf")
+	if err := NewCube().SaveGLTF(fileName); err != nil {
+		t.Fatalf("SaveGLTF: %v", err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := decodeGLTF(t, data)
+	if doc.Buffers[0].URI != "cube.bin" {
+		t.Errorf("buffer URI = %q; want cube.bin", doc.Buffers[0].URI)
+	}
+	info, err := os.Stat(filepath.Join(dir, "cube.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(info.Size()) != doc.Buffers[0].ByteLength {
+		t.Errorf("cube.bin is %d bytes; buffer declares %d", info.Size(), doc.Buffers[0].ByteLength)
+	}
+}
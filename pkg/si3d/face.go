@@ -3,12 +3,16 @@ package si3d
 import "image/color"
 
 type Face struct {
-	Points    []Vector3
+	Points []Vector3
+	// UVs holds a texture coordinate per point for faces drawn with their
+	// model's texture, or nil for faces drawn in Col.
+	UVs       []UV
 	Col       color.RGBA
 	normal    Vector3
 	hasNormal bool
 	plane     *Plane
 	vecPnts   []Vector3
+	vecUVs    []UV
 	meRev     bool
 	Cnum      int
 }
@@ -42,6 +46,14 @@ func (f *Face) Copy() *Face {
 	}
 	copy(newFace.Points, f.Points)
 	copy(newFace.vecPnts, f.vecPnts)
+	if f.UVs != nil {
+		newFace.UVs = make([]UV, len(f.UVs))
+		copy(newFace.UVs, f.UVs)
+	}
+	if f.vecUVs != nil {
+		newFace.vecUVs = make([]UV, len(f.vecUVs))
+		copy(newFace.vecUVs, f.vecUVs)
+	}
 	return newFace
 }
 
@@ -68,6 +80,13 @@ func (f *Face) AddPoint(x, y, z float64) {
 	f.vecPnts = append(f.vecPnts, pnts)
 }
 
+// AddPointUV adds a point with the texture coordinate (u, v). The UVs are
+// kept by Finished only if every point was added with one.
+func (f *Face) AddPointUV(x, y, z, u, v float64) {
+	f.AddPoint(x, y, z)
+	f.vecUVs = append(f.vecUVs, UV{U: u, V: v})
+}
+
 func (f *Face) GetNormal() Vector3 {
 	if !f.hasNormal {
 		f.createNormal()
@@ -87,7 +106,11 @@ func (f *Face) Finished(reverse int) {
 	f.Cnum = len(f.vecPnts)
 	f.Points = make([]Vector3, f.Cnum)
 	copy(f.Points, f.vecPnts)
+	if len(f.vecUVs) == f.Cnum {
+		f.UVs = f.vecUVs
+	}
 	f.vecPnts = nil
+	f.vecUVs = nil
 }
 
 func (f *Face) GetPlane() *Plane {
@@ -17,6 +17,7 @@ func (fm *FaceMesh) AddFace(f *Face) (*Face, []int) { // Return indices
 		newPoints[i], indices[i] = fm.AddPoint(p)
 	}
 	newface := NewFace(newPoints, f.Col, f.GetNormal())
+	newface.UVs = f.UVs
 
 	// fm.faces = append(fm.faces, newface)
 
@@ -0,0 +1,98 @@
+package si3d
+
+import (
+	"image/color"
+	"math"
+)
+
+// FogMode selects how fog thickens with distance from the camera.
+type FogMode int
+
+const (
+	// FogNone disables fog.
+	FogNone FogMode = iota
+	// FogLinear fades surfaces evenly from Start to End.
+	FogLinear
+	// FogExponential fades surfaces exponentially beyond Start at a rate set
+	// by Density.
+	FogExponential
+)
+
+// Fog fades surfaces towards a colour with their camera-space depth, the Z
+// of their points in front of the camera, so that distant geometry recedes
+// into the background. Flat shaded polygons are fogged at the same point
+// they are lit at, and Gouraud shaded and textured ones per vertex.
+type Fog struct {
+	Mode FogMode
+	// Color is the alpha-premultiplied colour surfaces fade to, usually the
+	// background colour of the render.
+	Color color.RGBA
+	// Start is the depth where the fog begins. End is the depth where linear
+	// fog hides surfaces completely.
+	Start, End float64
+	// Density is the rate exponential fog thickens at: a surface d units
+	// beyond Start keeps exp(-Density*d) of its own colour.
+	Density float64
+}
+
+// SetFog sets the fog the world is rendered with. The zero Fog, or any with
+// Mode FogNone, turns fog off.
+func (w *World) SetFog(fog Fog) {
+	w.fog = fog
+}
+
+func (w *World) GetFog() Fog {
+	return w.fog
+}
+
+// active returns the fog to render with, or nil if it is turned off.
+func (f *Fog) active() *Fog {
+	if f.Mode == FogNone {
+		return nil
+	}
+	return f
+}
+
+// visibility returns how much of a surface's own colour survives the fog at
+// depth z, from 1 in front of the fog to 0 where it is hidden. A nil fog
+// hides nothing.
+func (f *Fog) visibility(z float64) float64 {
+	if f == nil {
+		return 1
+	}
+	switch f.Mode {
+	case FogLinear:
+		if f.End <= f.Start {
+			if z < f.Start {
+				return 1
+			}
+			return 0
+		}
+		return min(max((f.End-z)/(f.End-f.Start), 0), 1)
+	case FogExponential:
+		return math.Exp(-f.Density * max(z-f.Start, 0))
+	}
+	return 1
+}
+
+// apply fades c, lit at depth z, into the fog.
+func (f *Fog) apply(c color.RGBA, z float64) color.RGBA {
+	if f == nil {
+		return c
+	}
+	return fogBlend(c, f.Color, f.visibility(z))
+}
+
+// fogBlend mixes an alpha-premultiplied colour with visibility v of its own
+// colour and the rest of fogClr, keeping its alpha so translucent surfaces
+// stay as translucent.
+func fogBlend(c, fogClr color.RGBA, v float64) color.RGBA {
+	if v >= 1 {
+		return c
+	}
+	a := float64(c.A) / 255
+	mix := func(own, fog uint8) uint8 {
+		return uint8(math.Round(float64(own)*v + float64(fog)*a*(1-v)))
+	}
+	return color.RGBA{R: mix(c.R, fogClr.R), G: mix(c.G, fogClr.G), B: mix(c.B, fogClr.B), A: c.A}
+}
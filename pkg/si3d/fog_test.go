@@ -0,0 +1,128 @@
+package si3d
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestFog_Visibility(t *testing.T) {
+	linear := &Fog{Mode: FogLinear, Start: 100, End: 300}
+	for _, tc := range []struct {
+		z, want float64
+	}{{50, 1}, {100, 1}, {200, 0.5}, {300, 0}, {400, 0}} {
+		if got := linear.visibility(tc.z); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("linear visibility at %v = %v, want %v", tc.z, got, tc.want)
+		}
+	}
+
+	exp := &Fog{Mode: FogExponential, Start: 100, Density: 0.01}
+	if got := exp.visibility(50); got != 1 {
+		t.Errorf("exponential visibility before Start = %v, want 1", got)
+	}
+	if got, want := exp.visibility(200), math.Exp(-1); math.Abs(got-want) > 1e-9 {
+		t.Errorf("exponential visibility at 200 = %v, want %v", got, want)
+	}
+
+	var none *Fog
+	if got := none.visibility(1e6); got != 1 {
+		t.Errorf("nil fog visibility = %v, want 1", got)
+	}
+	if (&Fog{}).active() != nil {
+		t.Error("zero Fog is active")
+	}
+}
+
+func TestFogBlend(t *testing.T) {
+	fogClr := color.RGBA{B: 200, A: 255}
+	c := color.RGBA{R: 200, A: 255}
+	if got, want := fogBlend(c, fogClr, 0.5), (color.RGBA{R: 100, B: 100, A: 255}); got != want {
+		t.Errorf("half fogged = %v, want %v", got, want)
+	}
+	if got := fogBlend(c, fogClr, 1); got != c {
+		t.Errorf("unfogged = %v, want %v", got, c)
+	}
+	// A translucent colour keeps its alpha and stays premultiplied.
+	glass := color.RGBA{G: 100, A: 100}
+	if got, want := fogBlend(glass, fogClr, 0), (color.RGBA{B: 78, A: 100}); got != want {
+		t.Errorf("fully fogged glass = %v, want %v", got, want)
+	}
+}
+
+func TestWorld_Fog(t *testing.T) {
+	fogClr := color.RGBA{B: 255, A: 255}
+
+	// The zero Fog leaves the render unchanged.
+	plain := renderEntities(&Entity{Model: NewCube()})
+	w := newTestWorld(&Entity{Model: NewCube()})
+	w.SetFog(Fog{})
+	if !compareImages(t, renderWorld(w), plain) {
+		t.Error("zero fog changed the render")
+	}
+
+	// Linear fog starting behind the cube leaves it alone.
+	fog := Fog{Mode: FogLinear, Color: fogClr, Start: 400, End: 1000}
+	w.SetFog(fog)
+	if !compareImages(t, renderWorld(w), plain) {
+		t.Error("fog starting behind the cube changed it")
+	}
+
+	// The same cube far away is mostly fog.
+	w = newTestWorld(&Entity{Model: NewCube(), Z: 600})
+	w.SetFog(fog)
+	far := renderWorld(w)
+	if p := far.RGBAAt(100, 75); p.B <= p.R || p.B <= p.G {
+		t.Errorf("distant cube is %v, want it faded to blue", p)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		model func() *Model
+	}{
+		{"BSP", newBSPCube},
+		{"Gouraud", func() *Model {
+			m := NewUVSphere(50, 12, 8, color.RGBA{R: 255, A: 255}, color.RGBA{R: 255, A: 255}, 2)
+			m.SetShadingMode(ShadingGouraud)
+			return m
+		}},
+		{"textured", func() *Model {
+			m := NewCube()
+			m.SetTexture(newTopBottomTexture())
+			return m
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			thick := Fog{Mode: FogExponential, Color: fogClr, Density: 0.005}
+			unfogged := renderEntities(&Entity{Model: tc.model()}).RGBAAt(100, 70)
+			w := newTestWorld(&Entity{Model: tc.model()})
+			w.SetFog(thick)
+			got := renderWorld(w).RGBAAt(100, 70)
+			if got.B <= unfogged.B || got.R >= unfogged.R {
+				t.Errorf("fogged pixel %v, unfogged %v; want faded to blue", got, unfogged)
+			}
+		})
+	}
+}
+
+func TestWorld_FogLinesOnly(t *testing.T) {
+	model := NewCube()
+	model.SetDrawLinesOnly(true)
+	fog := Fog{Mode: FogLinear, Color: color.RGBA{B: 255, A: 255}, Start: 0, End: 400}
+
+	plain := renderEntities(&Entity{Model: model})
+	w := newTestWorld(&Entity{Model: model})
+	w.SetFog(fog)
+	got := renderWorld(w)
+	if differingPixels(got, plain) == 0 {
+		t.Fatal("fog did not change the outlines")
+	}
+	var blue int
+	for i := 0; i < len(got.Pix); i += 4 {
+		if got.Pix[i+2] > plain.Pix[i+2] {
+			blue++
+		}
+	}
+	if blue == 0 {
+		t.Error("outlines did not fade towards the fog colour")
+	}
+}
@@ -0,0 +1,198 @@
+package si3d
+
+// frustumGuardBand is how far, in pixels, the side planes of a Frustum sit
+// outside the visible screen. Polygons are clipped in 3D against this slightly
+// larger volume so that their projected coordinates stay small, and the final
+// exact clip to the screen edges is still done by PolygonBatcher.ClipPolygon.
+const frustumGuardBand = 64.0
+
+// Frustum is the camera-space view volume that polygons are clipped against
+// before they are projected onto the screen.
+type Frustum struct {
+	Near float64
+	// Far is the far clipping distance. A value of 0 disables far clipping.
+	Far float64
+	// Sides holds the left, right, top and bottom planes. Points on the
+	// positive side of a plane are inside the frustum.
+	Sides [4]Plane
+}
+
+// NewFrustum builds the view volume for a projection. For perspective
+// projections the side planes pass through the camera; for orthographic ones
+// they are parallel to the view axis. The principal point can be anywhere on
+// the screen.
+func NewFrustum(near, far float64, proj *Projection) *Frustum {
+	left := proj.CenterX + frustumGuardBand
+	right := proj.Width - proj.CenterX + frustumGuardBand
+	top := proj.CenterY + frustumGuardBand
+	bottom := proj.Height - proj.CenterY + frustumGuardBand
+
+	if proj.Orthographic {
+		return &Frustum{
+			Near: near,
+			Far:  far,
+			Sides: [4]Plane{
+				{A: 1, D: left / proj.FocalX},
+				{A: -1, D: right / proj.FocalX},
+				{B: 1, D: top / proj.FocalY},
+				{B: -1, D: bottom / proj.FocalY},
+			},
+		}
+	}
+
+	return &Frustum{
+		Near: near,
+		Far:  far,
+		Sides: [4]Plane{
+			{A: proj.FocalX, C: left},    // left:   screenX >= -guard
+			{A: -proj.FocalX, C: right},  // right:  screenX <= width + guard
+			{B: proj.FocalY, C: top},     // top:    screenY >= -guard
+			{B: -proj.FocalY, C: bottom}, // bottom: screenY <= height + guard
+		},
+	}
+}
+
+// ClipPolygon clips a camera-space polygon against the near plane, the four
+// side planes and, when enabled, the far plane of the frustum. The two
+// buffers are used as scratch space and must not overlap polygon or each
+// other. The returned slice aliases one of the buffers.
+func (f *Frustum) ClipPolygon(polygon []Vector3, bufA, bufB []Vector3) []Vector3 {
+	clipped := clipPolygonAgainstNearPlane(polygon, f.Near, bufA[:0])
+
+	// Ping-pong between the buffers so a clip never writes over its input.
+	spare, out := bufA, bufB
+	for i := range f.Sides {
+		if len(clipped) < 3 {
+			return clipped[:0]
+		}
+		clipped = clipPolygonAgainstPlane(clipped, &f.Sides[i], out[:0])
+		spare, out = out, spare
+	}
+
+	if f.Far > 0 && len(clipped) >= 3 {
+		farPlane := Plane{C: -1, D: f.Far}
+		clipped = clipPolygonAgainstPlane(clipped, &farPlane, out[:0])
+	}
+
+	return clipped
+}
+
+// clipPolygonAgainstPlane keeps the part of the polygon on the positive side
+// of the plane (Sutherland-Hodgman in 3D).
+func clipPolygonAgainstPlane(polygon []Vector3, p *Plane, buffer []Vector3) []Vector3 {
+	if len(polygon) == 0 {
+		return polygon
+	}
+
+	clippedPolygon := buffer
+	startPoint := polygon[len(polygon)-1]
+	startDist := p.signedDistance(startPoint)
+
+	for _, endPoint := range polygon {
+		endDist := p.signedDistance(endPoint)
+
+		if endDist >= 0 {
+			if startDist < 0 {
+				clippedPolygon = append(clippedPolygon, intersectPlane(startPoint, endPoint, startDist, endDist))
+			}
+			clippedPolygon = append(clippedPolygon, endPoint)
+		} else if startDist >= 0 {
+			clippedPolygon = append(clippedPolygon, intersectPlane(startPoint, endPoint, startDist, endDist))
+		}
+
+		startPoint = endPoint
+		startDist = endDist
+	}
+
+	return clippedPolygon
+}
+
+// intersectPlane interpolates between two points given their signed
+// distances from a plane, which must have opposite signs.
+func intersectPlane(p1, p2 Vector3, d1, d2 float64) Vector3 {
+	t := d1 / (d1 - d2)
+	return NewVector3(
+		p1.X+t*(p2.X-p1.X),
+		p1.Y+t*(p2.Y-p1.Y),
+		p1.Z+t*(p2.Z-p1.Z),
+	)
+}
+
+// vertexAttrs holds the values interpolated across a polygon alongside its
+// camera-space positions, such as Gouraud vertex colours, texture
+// coordinates and fog visibility.
+type vertexAttrs struct {
+	r, g, b, a float64
+	u, v       float64
+	fog        float64
+}
+
+func (v vertexAttrs) lerp(o vertexAttrs, t float64) vertexAttrs {
+	return vertexAttrs{
+		r:   v.r + t*(o.r-v.r),
+		g:   v.g + t*(o.g-v.g),
+		b:   v.b + t*(o.b-v.b),
+		a:   v.a + t*(o.a-v.a),
+		u:   v.u + t*(o.u-v.u),
+		v:   v.v + t*(o.v-v.v),
+		fog: v.fog + t*(o.fog-v.fog),
+	}
+}
+
+// clipPolygonAttrs clips a polygon like ClipPolygon while interpolating the
+// per-vertex attributes. It uses the clip buffers of ctx; the returned slices
+// alias them.
+func (f *Frustum) clipPolygonAttrs(polygon []Vector3, attrs []vertexAttrs, ctx *RenderContext) ([]Vector3, []vertexAttrs) {
+	nearPlane := Plane{C: 1, D: -f.Near}
+	clipped, clippedAttrs := clipPolygonAttrsAgainstPlane(polygon, attrs, &nearPlane, ctx.ClipBufA[:0], ctx.attrBufA[:0])
+
+	spare, out := ctx.ClipBufA, ctx.ClipBufB
+	spareAttrs, outAttrs := ctx.attrBufA, ctx.attrBufB
+	for i := range f.Sides {
+		if len(clipped) < 3 {
+			return clipped[:0], clippedAttrs[:0]
+		}
+		clipped, clippedAttrs = clipPolygonAttrsAgainstPlane(clipped, clippedAttrs, &f.Sides[i], out[:0], outAttrs[:0])
+		spare, out = out, spare
+		spareAttrs, outAttrs = outAttrs, spareAttrs
+	}
+
+	if f.Far > 0 && len(clipped) >= 3 {
+		farPlane := Plane{C: -1, D: f.Far}
+		clipped, clippedAttrs = clipPolygonAttrsAgainstPlane(clipped, clippedAttrs, &farPlane, out[:0], outAttrs[:0])
+	}
+
+	return clipped, clippedAttrs
+}
+
+// clipPolygonAttrsAgainstPlane is clipPolygonAgainstPlane for polygons with
+// per-vertex attributes.
+func clipPolygonAttrsAgainstPlane(polygon []Vector3, attrs []vertexAttrs, p *Plane, buffer []Vector3, attrBuffer []vertexAttrs) ([]Vector3, []vertexAttrs) {
+	if len(polygon) == 0 {
+		return polygon, attrs
+	}
+
+	clippedPolygon, clippedAttrs := buffer, attrBuffer
+	last := len(polygon) - 1
+	startPoint, startAttrs := polygon[last], attrs[last]
+	startDist := p.signedDistance(startPoint)
+
+	for i, endPoint := range polygon {
+		endAttrs := attrs[i]
+		endDist := p.signedDistance(endPoint)
+
+		if (endDist >= 0) != (startDist >= 0) {
+			t := startDist / (startDist - endDist)
+			clippedPolygon = append(clippedPolygon, intersectPlane(startPoint, endPoint, startDist, endDist))
+			clippedAttrs = append(clippedAttrs, startAttrs.lerp(endAttrs, t))
+		}
+		if endDist >= 0 {
+			clippedPolygon = append(clippedPolygon, endPoint)
+			clippedAttrs = append(clippedAttrs, endAttrs)
+		}
+
+		startPoint, startAttrs, startDist = endPoint, endAttrs, endDist
+	}
+
+	return clippedPolygon, clippedAttrs
+}
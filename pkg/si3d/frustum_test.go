@@ -0,0 +1,154 @@
+package si3d
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewFrustum(t *testing.T) {
+	f := NewProjection(200, 100, 0, 0, 0, 0, 10, 500).Frustum
+	if f.Near != 10 || f.Far != 500 {
+		t.Errorf("NewFrustum near/far mismatch: got %f/%f", f.Near, f.Far)
+	}
+
+	// A point straight ahead of the camera must be inside every side plane.
+	ahead := NewVector3(0, 0, 100)
+	for i, p := range f.Sides {
+		if p.signedDistance(ahead) <= 0 {
+			t.Errorf("side plane %d rejects a point on the view axis", i)
+		}
+	}
+}
+
+func TestFrustum_ClipPolygon_Inside(t *testing.T) {
+	f := NewProjection(200, 200, 0, 0, 0, 0, 10, 0).Frustum
+	poly := []Vector3{
+		NewVector3(-5, -5, 50),
+		NewVector3(5, -5, 50),
+		NewVector3(0, 5, 50),
+	}
+
+	clipped := f.ClipPolygon(poly, make([]Vector3, 0, 10), make([]Vector3, 0, 10))
+	if len(clipped) != 3 {
+		t.Fatalf("Expected 3 points, got %d", len(clipped))
+	}
+	for i, p := range clipped {
+		if p.X != poly[i].X || p.Y != poly[i].Y || p.Z != poly[i].Z {
+			t.Errorf("Point mismatch at index %d: expected %v, got %v", i, poly[i], p)
+		}
+	}
+}
+
+func TestFrustum_ClipPolygon_Sides(t *testing.T) {
+	width, height := float32(200), float32(100)
+	f := NewProjection(width, height, 0, 0, 0, 0, 10, 0).Frustum
+
+	// A huge polygon that extends far past every side of the screen.
+	poly := []Vector3{
+		NewVector3(-10000, -10000, 100),
+		NewVector3(10000, -10000, 100),
+		NewVector3(10000, 10000, 100),
+		NewVector3(-10000, 10000, 100),
+	}
+
+	clipped := f.ClipPolygon(poly, make([]Vector3, 0, 10), make([]Vector3, 0, 10))
+	if len(clipped) < 3 {
+		t.Fatalf("Expected a clipped polygon, got %d points", len(clipped))
+	}
+
+	limit := frustumGuardBand + 1e-3
+	for i, p := range clipped {
+		sx := float64(ConvertToScreenX(float64(width), float64(height), p.X, p.Z))
+		sy := float64(ConvertToScreenY(float64(width), float64(height), p.Y, p.Z))
+		if sx < -limit || sx > float64(width)+limit || sy < -limit || sy > float64(height)+limit {
+			t.Errorf("Point %d projects outside the guard band: (%f, %f)", i, sx, sy)
+		}
+	}
+}
+
+func TestFrustum_ClipPolygon_Far(t *testing.T) {
+	f := NewProjection(200, 200, 0, 0, 0, 0, 10, 100).Frustum
+	poly := []Vector3{
+		NewVector3(0, 0, 50),
+		NewVector3(0, 10, 150),
+		NewVector3(10, 0, 150),
+	}
+
+	// Only the first vertex is in range, so a smaller triangle remains.
+	clipped := f.ClipPolygon(poly, make([]Vector3, 0, 10), make([]Vector3, 0, 10))
+	if len(clipped) != 3 {
+		t.Fatalf("Expected 3 points, got %d", len(clipped))
+	}
+	for i, p := range clipped {
+		if p.Z > 100+1e-9 {
+			t.Errorf("Point %d is beyond the far plane: %f", i, p.Z)
+		}
+	}
+}
+
+func TestFrustum_ClipPolygon_Outside(t *testing.T) {
+	f := NewProjection(200, 200, 0, 0, 0, 0, 10, 100).Frustum
+
+	behind := []Vector3{
+		NewVector3(0, 0, 5),
+		NewVector3(10, 0, 5),
+		NewVector3(0, 10, 5),
+	}
+	if clipped := f.ClipPolygon(behind, nil, nil); len(clipped) >= 3 {
+		t.Errorf("Expected polygon behind the camera to be removed, got %d points", len(clipped))
+	}
+
+	beyond := []Vector3{
+		NewVector3(0, 0, 500),
+		NewVector3(10, 0, 500),
+		NewVector3(0, 10, 500),
+	}
+	if clipped := f.ClipPolygon(beyond, nil, nil); len(clipped) >= 3 {
+		t.Errorf("Expected polygon beyond the far plane to be removed, got %d points", len(clipped))
+	}
+
+	offLeft := []Vector3{
+		NewVector3(-1000, 0, 50),
+		NewVector3(-900, 0, 50),
+		NewVector3(-950, 10, 50),
+	}
+	if clipped := f.ClipPolygon(offLeft, nil, nil); len(clipped) >= 3 {
+		t.Errorf("Expected polygon left of the screen to be removed, got %d points", len(clipped))
+	}
+}
+
+func TestIntersectPlane(t *testing.T) {
+	p := Plane{C: -1, D: 100} // z <= 100
+	p1 := NewVector3(0, 0, 50)
+	p2 := NewVector3(10, 20, 150)
+
+	got := intersectPlane(p1, p2, p.signedDistance(p1), p.signedDistance(p2))
+	if math.Abs(got.X-5) > 1e-9 || math.Abs(got.Y-10) > 1e-9 || math.Abs(got.Z-100) > 1e-9 {
+		t.Errorf("intersectPlane expected (5, 10, 100), got %v", got)
+	}
+}
+
+func TestFrustum_ClipPolygonAttrs(t *testing.T) {
+	f := NewProjection(100, 100, 0, 0, 0, 0, 10, 0).Frustum
+	ctx := NewRenderContext()
+
+	// An edge from behind the near plane to in front of it.
+	polygon := []Vector3{
+		NewVector3(0, 0, 0),
+		NewVector3(0, 1, 20),
+		NewVector3(1, 0, 20),
+	}
+	attrs := []vertexAttrs{{r: 0}, {r: 200}, {r: 200}}
+
+	clipped, clippedAttrs := f.clipPolygonAttrs(polygon, attrs, ctx)
+	if len(clipped) != 4 || len(clippedAttrs) != 4 {
+		t.Fatalf("clipPolygonAttrs gave %d points and %d attributes; want 4", len(clipped), len(clippedAttrs))
+	}
+
+	for i, p := range clipped {
+		// Attributes are interpolated in step with Z, which runs 0 to 20.
+		if want := p.Z * 10; math.Abs(clippedAttrs[i].r-want) > 1e-9 {
+			t.Errorf("point %v has attribute %f; want %f", p, clippedAttrs[i].r, want)
+		}
+	}
+}
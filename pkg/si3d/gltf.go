@@ -0,0 +1,217 @@
+package si3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// glTF 2.0 constants.
+const (
+	gltfComponentByte          = 5120
+	gltfComponentUnsignedByte  = 5121
+	gltfComponentShort         = 5122
+	gltfComponentUnsignedShort = 5123
+	gltfComponentUnsignedInt   = 5125
+	gltfComponentFloat         = 5126
+
+	gltfTargetArrayBuffer        = 34962
+	gltfTargetElementArrayBuffer = 34963
+
+	gltfModeTriangles     = 4
+	gltfModeTriangleStrip = 5
+	gltfModeTriangleFan   = 6
+
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\x00"
+)
+
+// gltfDocument is the JSON part of a glTF 2.0 asset, limited to the parts
+// si3d reads and writes.
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       *int             `json:"scene,omitempty"`
+	Scenes      []gltfScene      `json:"scenes,omitempty"`
+	Nodes       []gltfNode       `json:"nodes,omitempty"`
+	Meshes      []gltfMesh       `json:"meshes,omitempty"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors,omitempty"`
+	BufferViews []gltfBufferView `json:"bufferViews,omitempty"`
+	Buffers     []gltfBuffer     `json:"buffers,omitempty"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type gltfScene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes,omitempty"`
+}
+
+type gltfNode struct {
+	Name        string    `json:"name,omitempty"`
+	Children    []int     `json:"children,omitempty"`
+	Mesh        *int      `json:"mesh,omitempty"`
+	Matrix      []float64 `json:"matrix,omitempty"`
+	Translation []float64 `json:"translation,omitempty"`
+	Rotation    []float64 `json:"rotation,omitempty"`
+	Scale       []float64 `json:"scale,omitempty"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name,omitempty"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       *int           `json:"mode,omitempty"`
+}
+
+type gltfMaterial struct {
+	Name                 string   `json:"name,omitempty"`
+	PBRMetallicRoughness *gltfPBR `json:"pbrMetallicRoughness,omitempty"`
+	AlphaMode            string   `json:"alphaMode,omitempty"`
+	DoubleSided          bool     `json:"doubleSided,omitempty"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor []float64 `json:"baseColorFactor,omitempty"`
+	MetallicFactor  *float64  `json:"metallicFactor,omitempty"`
+	RoughnessFactor *float64  `json:"roughnessFactor,omitempty"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int      `json:"bufferView,omitempty"`
+	ByteOffset    int       `json:"byteOffset,omitempty"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized,omitempty"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+	// Sparse is only checked for, as sparse accessors are not supported.
+	Sparse json.RawMessage `json:"sparse,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset,omitempty"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride,omitempty"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri,omitempty"`
+	ByteLength int    `json:"byteLength"`
+}
+
+// gltfAxisRotation turns the engine's -Y up world into glTF's +Y up one: a
+// half turn about X, which keeps the coordinate system right-handed and
+// every triangle's winding intact.
+var gltfAxisRotation = []float64{1, 0, 0, 0}
+
+// writeGLB writes a binary glTF container holding doc and its single buffer.
+func writeGLB(w io.Writer, doc *gltfDocument, bin []byte) error {
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("error encoding glTF JSON: %w", err)
+	}
+	for len(jsonData)%4 != 0 {
+		jsonData = append(jsonData, ' ')
+	}
+	binData := bin
+	for len(binData)%4 != 0 {
+		binData = append(binData, 0)
+	}
+
+	length := 12 + 8 + len(jsonData)
+	if len(binData) > 0 {
+		length += 8 + len(binData)
+	}
+
+	var buf bytes.Buffer
+	_ = binaryWrite(&buf, [3]uint32{glbMagic, 2, uint32(length)})
+	_ = binaryWrite(&buf, [2]uint32{uint32(len(jsonData)), glbChunkJSON})
+	buf.Write(jsonData)
+	if len(binData) > 0 {
+		_ = binaryWrite(&buf, [2]uint32{uint32(len(binData)), glbChunkBIN})
+		buf.Write(binData)
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// readGLB splits a binary glTF container into its JSON and BIN chunks.
+func readGLB(data []byte) (jsonData, bin []byte, err error) {
+	if len(data) < 20 {
+		return nil, nil, fmt.Errorf("GLB too short for its header")
+	}
+	if binary.LittleEndian.Uint32(data) != glbMagic {
+		return nil, nil, fmt.Errorf("not a GLB file: bad magic")
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != 2 {
+		return nil, nil, fmt.Errorf("unsupported GLB version %d", v)
+	}
+	length := int(binary.LittleEndian.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, fmt.Errorf("GLB declares %d bytes but holds %d", length, len(data))
+	}
+
+	for off := 12; off+8 <= length; {
+		chunkLen := int(binary.LittleEndian.Uint32(data[off:]))
+		chunkType := binary.LittleEndian.Uint32(data[off+4:])
+		off += 8
+		if chunkLen > length-off {
+			return nil, nil, fmt.Errorf("GLB chunk overruns the file")
+		}
+		chunk := data[off : off+chunkLen]
+		switch chunkType {
+		case glbChunkJSON:
+			if jsonData == nil {
+				jsonData = chunk
+			}
+		case glbChunkBIN:
+			if bin == nil {
+				bin = chunk
+			}
+		}
+		off += chunkLen
+	}
+
+	if jsonData == nil {
+		return nil, nil, fmt.Errorf("GLB has no JSON chunk")
+	}
+	return jsonData, bin, nil
+}
+
+// srgbToLinear converts an 8-bit sRGB channel to a linear value in 0..1, as
+// glTF colour factors are linear.
+func srgbToLinear(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB is the inverse of srgbToLinear.
+func linearToSRGB(v float64) uint8 {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(v * 255))
+}
@@ -0,0 +1,195 @@
+package si3d
+
+import (
+	"image/color"
+	"math"
+)
+
+// LightKind selects how a Light illuminates the scene.
+type LightKind int
+
+const (
+	// DirectionalLight shines along Direction from infinitely far away.
+	DirectionalLight LightKind = iota
+	// PointLight shines in all directions from Position.
+	PointLight
+	// SpotLight shines from Position along Direction inside a cone.
+	SpotLight
+)
+
+// DefaultAmbientLight is the ambient colour used once lights are added to a
+// World. It matches the ambient term of the built-in camera light.
+var DefaultAmbientLight = color.RGBA{R: 166, G: 166, B: 166, A: 255}
+
+// Light is a light source registered on a World. Position and Direction are
+// in world space.
+type Light struct {
+	Kind      LightKind
+	Color     color.RGBA
+	Intensity float64
+	Position  Vector3
+	// Direction is the direction the light travels in.
+	Direction Vector3
+	// Range is the distance at which point and spot lights fade out
+	// completely. 0 means they do not fall off with distance.
+	Range float64
+	// InnerCone and OuterCone are the half-angles of a spot light, in
+	// radians. The light fades out between the two.
+	InnerCone float64
+	OuterCone float64
+}
+
+func NewDirectionalLight(direction Vector3, clr color.RGBA, intensity float64) *Light {
+	return &Light{
+		Kind:      DirectionalLight,
+		Color:     clr,
+		Intensity: intensity,
+		Direction: direction.Normalize(),
+	}
+}
+
+func NewPointLight(position Vector3, clr color.RGBA, intensity, lightRange float64) *Light {
+	return &Light{
+		Kind:      PointLight,
+		Color:     clr,
+		Intensity: intensity,
+		Position:  position,
+		Range:     lightRange,
+	}
+}
+
+// NewSpotLight creates a spot light. innerCone and outerCone are half-angles
+// in radians.
+func NewSpotLight(position, direction Vector3, innerCone, outerCone float64, clr color.RGBA, intensity, lightRange float64) *Light {
+	return &Light{
+		Kind:      SpotLight,
+		Color:     clr,
+		Intensity: intensity,
+		Position:  position,
+		Direction: direction.Normalize(),
+		Range:     lightRange,
+		InnerCone: innerCone,
+		OuterCone: outerCone,
+	}
+}
+
+// Lighting is the lighting environment of one render, with every light
+// transformed into camera space. A nil *Lighting shades with the built-in
+// camera-attached light.
+type Lighting struct {
+	ambient [3]float64
+	lights  []Light
+}
+
+// NewLighting transforms the lights into the camera space defined by camMatrix.
+// It returns nil when there are no lights, selecting the built-in light.
+func NewLighting(lights []*Light, ambient color.RGBA, camMatrix Matrix) *Lighting {
+	if len(lights) == 0 {
+		return nil
+	}
+
+	l := &Lighting{
+		ambient: [3]float64{float64(ambient.R) / 255, float64(ambient.G) / 255, float64(ambient.B) / 255},
+		lights:  make([]Light, len(lights)),
+	}
+
+	positions := make([]Vector3, len(lights))
+	for i, light := range lights {
+		positions[i] = light.Position
+	}
+	camMatrix.TransformObj(positions, positions)
+
+	for i, light := range lights {
+		camLight := *light
+		camLight.Position = positions[i]
+		camLight.Direction = camMatrix.RotateVector3(light.Direction).Normalize()
+		l.lights[i] = camLight
+	}
+
+	return l
+}
+
+// Shade returns the lit colour of a surface at a camera-space point with the
+// given camera-space face normal. Both paint paths shade through this method,
+// by way of RenderContext.shade.
+func (l *Lighting) Shade(point, normal Vector3, base color.RGBA) color.RGBA {
+	if l == nil {
+		return getColor(point, normal, base, defaultAmbient, defaultShadingMinimum)
+	}
+
+	// Face normals point away from the side that faces the camera, so the
+	// outward surface normal is their negation.
+	outward := NewVector3(-normal.X, -normal.Y, -normal.Z)
+
+	r, g, b := l.ambient[0], l.ambient[1], l.ambient[2]
+	for i := range l.lights {
+		light := &l.lights[i]
+		amount := light.illuminate(point, outward)
+		if amount <= 0 {
+			continue
+		}
+		r += amount * float64(light.Color.R) / 255
+		g += amount * float64(light.Color.G) / 255
+		b += amount * float64(light.Color.B) / 255
+	}
+
+	return color.RGBA{
+		R: uint8(clamp2(int(float64(base.R)*r+0.5), 0, 255)),
+		G: uint8(clamp2(int(float64(base.G)*g+0.5), 0, 255)),
+		B: uint8(clamp2(int(float64(base.B)*b+0.5), 0, 255)),
+		A: base.A,
+	}
+}
+
+// illuminate returns how strongly the light reaches a surface at point with
+// the given outward normal, including intensity, attenuation and cone.
+func (l *Light) illuminate(point, outward Vector3) float64 {
+	var toLight Vector3
+	attenuation := 1.0
+
+	if l.Kind == DirectionalLight {
+		toLight = NewVector3(-l.Direction.X, -l.Direction.Y, -l.Direction.Z)
+	} else {
+		toLight = Subtract(l.Position, point)
+		dist := GetLength2(toLight)
+		if dist == 0 {
+			return l.Intensity
+		}
+		toLight = NewVector3(toLight.X/dist, toLight.Y/dist, toLight.Z/dist)
+
+		if l.Range > 0 {
+			falloff := 1 - dist/l.Range
+			if falloff <= 0 {
+				return 0
+			}
+			attenuation = falloff * falloff
+		}
+
+		if l.Kind == SpotLight {
+			attenuation *= l.coneFactor(toLight)
+		}
+	}
+
+	diffuse := Dot(outward, toLight)
+	if diffuse <= 0 {
+		return 0
+	}
+	return diffuse * attenuation * l.Intensity
+}
+
+// coneFactor fades a spot light smoothly from full strength inside InnerCone
+// to nothing outside OuterCone.
+func (l *Light) coneFactor(toLight Vector3) float64 {
+	cosAngle := -Dot(toLight, l.Direction)
+	cosOuter := math.Cos(l.OuterCone)
+	cosInner := math.Cos(l.InnerCone)
+
+	if cosAngle <= cosOuter {
+		return 0
+	}
+	if cosAngle >= cosInner || cosInner <= cosOuter {
+		return 1
+	}
+	t := (cosAngle - cosOuter) / (cosInner - cosOuter)
+	return t * t * (3 - 2*t)
+}
@@ -0,0 +1,187 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestLighting_NilMatchesBuiltInLight(t *testing.T) {
+	var l *Lighting
+	point := NewVector3(10, -20, 100)
+	normal := NewVector3(0, 0, 1)
+	base := color.RGBA{R: 200, G: 100, B: 50, A: 255}
+
+	got := l.Shade(point, normal, base)
+	want := getColor(point, normal, base, defaultAmbient, defaultShadingMinimum)
+	if got != want {
+		t.Errorf("nil Lighting Shade = %v; want %v", got, want)
+	}
+}
+
+func TestNewLighting_NoLights(t *testing.T) {
+	if l := NewLighting(nil, DefaultAmbientLight, IdentMatrix()); l != nil {
+		t.Errorf("NewLighting with no lights = %v; want nil", l)
+	}
+}
+
+func TestLighting_Directional(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	light := NewDirectionalLight(NewVector3(0, 0, 1), white, 1)
+	l := NewLighting([]*Light{light}, color.RGBA{A: 255}, IdentMatrix())
+
+	base := color.RGBA{R: 200, G: 100, B: 50, A: 128}
+	point := NewVector3(0, 0, 100)
+
+	// A face turned towards the camera is lit head on by a light shining
+	// away from the camera.
+	got := l.Shade(point, NewVector3(0, 0, 1), base)
+	if got != base {
+		t.Errorf("Shade facing light = %v; want %v", got, base)
+	}
+
+	// A face turned away gets only the (black) ambient light.
+	got = l.Shade(point, NewVector3(0, 0, -1), base)
+	want := color.RGBA{A: 128}
+	if got != want {
+		t.Errorf("Shade facing away = %v; want %v", got, want)
+	}
+
+	// At 60 degrees the diffuse term halves.
+	normal := NewVector3(math.Sin(math.Pi/3), 0, math.Cos(math.Pi/3))
+	got = l.Shade(point, normal, base)
+	want = color.RGBA{R: 100, G: 50, B: 25, A: 128}
+	if got != want {
+		t.Errorf("Shade at 60 degrees = %v; want %v", got, want)
+	}
+}
+
+func TestLighting_AmbientAndColor(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	light := NewDirectionalLight(NewVector3(0, 0, 1), red, 0.5)
+	ambient := color.RGBA{R: 51, G: 51, B: 51, A: 255}
+	l := NewLighting([]*Light{light}, ambient, IdentMatrix())
+
+	base := color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	got := l.Shade(NewVector3(0, 0, 100), NewVector3(0, 0, 1), base)
+	want := color.RGBA{R: 140, G: 40, B: 40, A: 255}
+	if got != want {
+		t.Errorf("Shade = %v; want %v", got, want)
+	}
+}
+
+func TestLighting_PointRange(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	light := NewPointLight(NewVector3(0, 0, 0), white, 1, 200)
+	l := NewLighting([]*Light{light}, color.RGBA{A: 255}, IdentMatrix())
+
+	base := color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	normal := NewVector3(0, 0, 1)
+
+	// Half way to the range the light is attenuated to a quarter.
+	got := l.Shade(NewVector3(0, 0, 100), normal, base)
+	want := color.RGBA{R: 50, G: 50, B: 50, A: 255}
+	if got != want {
+		t.Errorf("Shade at half range = %v; want %v", got, want)
+	}
+
+	got = l.Shade(NewVector3(0, 0, 250), normal, base)
+	want = color.RGBA{A: 255}
+	if got != want {
+		t.Errorf("Shade beyond range = %v; want %v", got, want)
+	}
+}
+
+func TestLighting_SpotCone(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	light := NewSpotLight(NewVector3(0, 0, 0), NewVector3(0, 0, 1), 10*math.Pi/180, 20*math.Pi/180, white, 1, 0)
+	l := NewLighting([]*Light{light}, color.RGBA{A: 255}, IdentMatrix())
+
+	base := color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	normal := NewVector3(0, 0, 1)
+
+	inside := l.Shade(NewVector3(0, 0, 100), normal, base)
+	if inside.R != 200 {
+		t.Errorf("Shade inside cone R = %d; want 200", inside.R)
+	}
+
+	// 15 degrees off axis sits between the inner and outer cones.
+	edge := l.Shade(NewVector3(100*math.Tan(15*math.Pi/180), 0, 100), normal, base)
+	if edge.R == 0 || edge.R >= inside.R {
+		t.Errorf("Shade in cone falloff R = %d; want between 0 and %d", edge.R, inside.R)
+	}
+
+	outside := l.Shade(NewVector3(100, 0, 100), normal, base)
+	if outside.R != 0 {
+		t.Errorf("Shade outside cone R = %d; want 0", outside.R)
+	}
+}
+
+func TestNewLighting_CameraSpace(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	light := NewPointLight(NewVector3(0, 0, 100), white, 1, 0)
+	cam := NewCamera(0, 0, -100, 0, 0, 0)
+
+	l := NewLighting([]*Light{light}, DefaultAmbientLight, cam.camMatrixRev)
+	pos := l.lights[0].Position
+	if math.Abs(pos.X) > 1e-9 || math.Abs(pos.Y) > 1e-9 || math.Abs(pos.Z-200) > 1e-9 {
+		t.Errorf("camera-space light position = %v; want (0, 0, 200)", pos)
+	}
+
+	// The world light must not be modified.
+	if light.Position.Z != 100 {
+		t.Errorf("world light position changed to %v", light.Position)
+	}
+}
+
+func TestWorld_Lights(t *testing.T) {
+	w := NewWorld3d()
+	a := NewDirectionalLight(NewVector3(0, 1, 0), color.RGBA{R: 255, A: 255}, 1)
+	b := NewPointLight(NewVector3(0, 0, 0), color.RGBA{G: 255, A: 255}, 1, 0)
+	w.AddLight(a)
+	w.AddLight(b)
+
+	if len(w.Lights()) != 2 {
+		t.Fatalf("World Lights count = %d; want 2", len(w.Lights()))
+	}
+
+	w.RemoveLight(a)
+	if len(w.Lights()) != 1 || w.Lights()[0] != b {
+		t.Errorf("World RemoveLight left %v", w.Lights())
+	}
+
+	w.ClearLights()
+	if len(w.Lights()) != 0 {
+		t.Errorf("World ClearLights left %d lights", len(w.Lights()))
+	}
+}
+
+func TestWorld_PaintObjectsWithLight(t *testing.T) {
+	const size = 200
+
+	render := func(w *World) color.RGBA {
+		w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+		w.AddObject(&Entity{Model: NewCube(), X: 0, Y: 0, Z: 0})
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		w.PaintObjects(img, size, size)
+		return img.RGBAAt(size/2, size/2)
+	}
+
+	w := NewWorld3d()
+	w.SetAmbientLight(color.RGBA{A: 255})
+	w.AddLight(NewDirectionalLight(NewVector3(0, 0, 1), color.RGBA{R: 255, A: 255}, 1))
+	lit := render(w)
+	// The front face of the cube is red.
+	if lit.R != 255 || lit.G != 0 || lit.B != 0 {
+		t.Errorf("cube lit head on = %v; want full red", lit)
+	}
+
+	w = NewWorld3d()
+	w.SetAmbientLight(color.RGBA{A: 255})
+	w.AddLight(NewDirectionalLight(NewVector3(0, 0, -1), color.RGBA{R: 255, A: 255}, 1))
+	unlit := render(w)
+	if unlit.R != 0 || unlit.G != 0 || unlit.B != 0 {
+		t.Errorf("cube lit from behind = %v; want black", unlit)
+	}
+}
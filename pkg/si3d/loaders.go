@@ -116,156 +116,3 @@ func NewObjectFromDXF(reader io.Reader, reverse int) (*Model, error) {
 	// On success, return the fully populated object and a nil error.
 	return obj, nil
 }
-
-func LoadObjectFromPLYFile(fileName string, reverse int, useBsp bool) (*Model, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("could not open PLY file %s: %w", fileName, err)
-	}
-	defer file.Close()
-
-	obj, err := LoadObjectFromPLYReader(file, reverse, useBsp)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing PLY file %s: %w", fileName, err)
-	}
-
-	return obj, nil
-}
-
-func LoadObjectFromPLYReader(reader io.Reader, reverse int, useBsp bool) (*Model, error) {
-	obj := NewModel()
-	scanner := bufio.NewScanner(reader)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024) // 1MB max line capacity
-
-	var vertexCount, faceCount int
-	var hasVertexColor, hasFaceColor bool
-	var currentElement string
-
-	// 1. Parse the header intelligently to detect color format
-headerLoop:
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Fields(line)
-		if len(parts) == 0 {
-			continue
-		}
-
-		switch parts[0] {
-		case "element":
-			if len(parts) == 3 {
-				currentElement = parts[1] // Track if we are defining a 'vertex' or 'face'
-				if parts[1] == "vertex" {
-					vertexCount, _ = strconv.Atoi(parts[2])
-				} else if parts[1] == "face" {
-					faceCount, _ = strconv.Atoi(parts[2])
-				}
-			}
-		case "property":
-			// Check for color properties within the current element definition
-			if len(parts) > 2 && (parts[2] == "red" || parts[2] == "diffuse_red") {
-				if currentElement == "vertex" {
-					hasVertexColor = true
-				} else if currentElement == "face" {
-					hasFaceColor = true
-				}
-			}
-		case "end_header":
-			break headerLoop
-		}
-	}
-
-	// Read all vertices
-	vertices := make([]Vertex, 0, vertexCount)
-	for i := 0; i < vertexCount; i++ {
-		if !scanner.Scan() {
-			return nil, fmt.Errorf("unexpected end of file while reading vertices")
-		}
-		parts := strings.Fields(scanner.Text())
-
-		// Conditional Vertex Parsing
-		if hasVertexColor {
-			if len(parts) < 6 {
-				return nil, fmt.Errorf("invalid vertex-color data on line %d", i)
-			}
-			x, _ := strconv.ParseFloat(parts[0], 64)
-			y, _ := strconv.ParseFloat(parts[1], 64)
-			z, _ := strconv.ParseFloat(parts[2], 64)
-			r, _ := strconv.ParseUint(parts[3], 10, 8)
-			g, _ := strconv.ParseUint(parts[4], 10, 8)
-			b, _ := strconv.ParseUint(parts[5], 10, 8)
-			vertices = append(vertices, Vertex{X: x, Y: y, Z: z, Color: color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}})
-		} else { // No vertex color
-			if len(parts) < 3 {
-				return nil, fmt.Errorf("invalid vertex data on line %d", i)
-			}
-			x, _ := strconv.ParseFloat(parts[0], 64)
-			y, _ := strconv.ParseFloat(parts[1], 64)
-			z, _ := strconv.ParseFloat(parts[2], 64)
-			// Use a default white color if none is provided
-			vertices = append(vertices, Vertex{X: x, Y: y, Z: z, Color: color.RGBA{R: 255, G: 255, B: 255, A: 255}})
-		}
-	}
-
-	// Read face definitions
-	for i := 0; i < faceCount; i++ {
-		if !scanner.Scan() {
-			return nil, fmt.Errorf("unexpected end of file while reading faces")
-		}
-		parts := strings.Fields(scanner.Text())
-		numFaceVerts, _ := strconv.Atoi(parts[0])
-
-		var faceColor color.RGBA
-
-		// Conditional Face Parsing
-		if hasFaceColor {
-			if len(parts) != numFaceVerts+1+3 {
-				return nil, fmt.Errorf("invalid face-color data on line %d", i)
-			}
-			// Color is at the end of the line
-			r, _ := strconv.ParseUint(parts[numFaceVerts+1], 10, 8)
-			g, _ := strconv.ParseUint(parts[numFaceVerts+2], 10, 8)
-			b, _ := strconv.ParseUint(parts[numFaceVerts+3], 10, 8)
-			faceColor = color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}
-		} else if hasVertexColor {
-			// No face color, but vertices have color -> average them
-			var r, g, b uint32
-			for j := 1; j <= numFaceVerts; j++ {
-				idx, _ := strconv.Atoi(parts[j])
-				vert := vertices[idx]
-				r += uint32(vert.Color.R)
-				g += uint32(vert.Color.G)
-				b += uint32(vert.Color.B)
-			}
-			faceColor = color.RGBA{
-				R: uint8(r / uint32(numFaceVerts)),
-				G: uint8(g / uint32(numFaceVerts)),
-				B: uint8(b / uint32(numFaceVerts)),
-				A: 255,
-			}
-		} else {
-			// No color information at all, use a default color
-			faceColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
-		}
-
-		aFace := NewFace(nil, faceColor, Vector3{})
-		for j := 1; j <= numFaceVerts; j++ {
-			idx, _ := strconv.Atoi(parts[j])
-			vert := vertices[idx]
-			aFace.AddPoint(vert.X, vert.Y, vert.Z)
-		}
-
-		aFace.Finished(reverse)
-		obj.faces.AddFace(aFace)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading from PLY source: %w", err)
-	}
-
-	if useBsp {
-		obj.BuildBSP()
-	}
-	obj.Compile()
-	return obj, nil
-}
@@ -0,0 +1,567 @@
+package si3d
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// defaultGLTFColor is the colour of primitives without a material, the
+// white of glTF's default material.
+var defaultGLTFColor = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+// gltfFile is a parsed glTF asset with its buffers loaded.
+type gltfFile struct {
+	doc     gltfDocument
+	buffers [][]byte
+}
+
+// LoadObjectFromGLTFFile loads every mesh in the default scene of a .gltf or
+// .glb file into a single model. External buffers are read relative to the
+// file.
+func LoadObjectFromGLTFFile(fileName string, reverse int, useBsp bool) (*Model, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not open glTF file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	obj, err := LoadObjectFromGLTFReader(file, os.DirFS(filepath.Dir(fileName)), reverse, useBsp)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing glTF file %s: %w", fileName, err)
+	}
+
+	return obj, nil
+}
+
+// LoadEntitiesFromGLTFFile loads a .gltf or .glb file as one entity per mesh
+// node, as LoadEntitiesFromGLTFReader does.
+func LoadEntitiesFromGLTFFile(fileName string, reverse int, useBsp bool) ([]*Entity, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not open glTF file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	entities, err := LoadEntitiesFromGLTFReader(file, os.DirFS(filepath.Dir(fileName)), reverse, useBsp)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing glTF file %s: %w", fileName, err)
+	}
+
+	return entities, nil
+}
+
+// LoadObjectFromGLTFReader reads a glTF 2.0 asset, as JSON or GLB, and merges
+// every mesh in its default scene into a single model. Nodes' translation,
+// rotation, scale and matrix are applied down the hierarchy, and the +Y up
+// glTF world is turned into the engine's -Y up one. Triangle primitives,
+// indexed or not and including strips and fans, are read; other primitive
+// modes are skipped. Each face takes its material's base colour factor.
+//
+// Buffers may be embedded in a GLB, given as base64 data URIs, or be
+// external files read from fsys, which may be nil if there are none. glTF
+// triangles are wound counter-clockwise seen from outside, so pass
+// FACE_REVERSE for outward facing solids.
+func LoadObjectFromGLTFReader(reader io.Reader, fsys fs.FS, reverse int, useBsp bool) (*Model, error) {
+	g, err := readGLTF(reader, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := NewModel()
+	err = g.walk(func(node *gltfNode, world mgl64.Mat4) error {
+		if node.Mesh == nil {
+			return nil
+		}
+		return g.addMesh(obj, *node.Mesh, world, reverse)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if useBsp {
+		obj.BuildBSP()
+	}
+	obj.Compile()
+	return obj, nil
+}
+
+// LoadEntitiesFromGLTFReader reads a glTF asset as LoadObjectFromGLTFReader
+// does, but returns one entity per mesh node, ready to add to a World. Each
+// entity sits at its node's world position, with the rest of the node's
+// world transform applied to its model's points.
+func LoadEntitiesFromGLTFReader(reader io.Reader, fsys fs.FS, reverse int, useBsp bool) ([]*Entity, error) {
+	g, err := readGLTF(reader, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var entities []*Entity
+	err = g.walk(func(node *gltfNode, world mgl64.Mat4) error {
+		if node.Mesh == nil {
+			return nil
+		}
+
+		pos := world.Col(3)
+		local := world
+		local.SetCol(3, mgl64.Vec4{0, 0, 0, 1})
+
+		obj := NewModel()
+		if err := g.addMesh(obj, *node.Mesh, local, reverse); err != nil {
+			return err
+		}
+		if obj.faces.FaceCount() == 0 {
+			return nil
+		}
+		if useBsp {
+			obj.BuildBSP()
+		}
+		obj.Compile()
+
+		entities = append(entities, &Entity{Model: obj, X: pos[0], Y: pos[1], Z: pos[2]})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// readGLTF parses a .gltf or .glb asset and loads its buffers.
+func readGLTF(reader io.Reader, fsys fs.FS) (*gltfFile, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading from glTF source: %w", err)
+	}
+
+	jsonData, glbBin := data, []byte(nil)
+	isGLB := len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic
+	if isGLB {
+		jsonData, glbBin, err = readGLB(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g := &gltfFile{}
+	if err := json.Unmarshal(jsonData, &g.doc); err != nil {
+		return nil, fmt.Errorf("invalid glTF JSON: %w", err)
+	}
+	if !strings.HasPrefix(g.doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("unsupported glTF version %q", g.doc.Asset.Version)
+	}
+
+	g.buffers = make([][]byte, len(g.doc.Buffers))
+	for i, buf := range g.doc.Buffers {
+		var data []byte
+		switch {
+		case buf.URI == "":
+			if !isGLB || i != 0 {
+				return nil, fmt.Errorf("buffer %d has no uri", i)
+			}
+			data = glbBin
+		case strings.HasPrefix(buf.URI, "data:"):
+			data, err = decodeDataURI(buf.URI)
+		default:
+			data, err = readExternalBuffer(fsys, buf.URI)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("buffer %d: %w", i, err)
+		}
+		if buf.ByteLength < 0 {
+			return nil, fmt.Errorf("buffer %d has negative byteLength %d", i, buf.ByteLength)
+		}
+		if len(data) < buf.ByteLength {
+			return nil, fmt.Errorf("buffer %d holds %d bytes but declares %d", i, len(data), buf.ByteLength)
+		}
+		g.buffers[i] = data[:buf.ByteLength]
+	}
+
+	return g, nil
+}
+
+// decodeDataURI decodes a base64 data URI.
+func decodeDataURI(uri string) ([]byte, error) {
+	header, payload, ok := strings.Cut(uri, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, fmt.Errorf("unsupported data URI")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 data URI: %w", err)
+	}
+	return data, nil
+}
+
+func readExternalBuffer(fsys fs.FS, uri string) ([]byte, error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("external buffer %q but no file system to read it from", uri)
+	}
+	name, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid buffer uri %q: %w", uri, err)
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read external buffer: %w", err)
+	}
+	return data, nil
+}
+
+// walk calls fn for every node of the default scene with the node's world
+// matrix, including the conversion to the engine's -Y up world.
+func (g *gltfFile) walk(fn func(node *gltfNode, world mgl64.Mat4) error) error {
+	var roots []int
+	switch {
+	case len(g.doc.Scenes) > 0:
+		scene := 0
+		if g.doc.Scene != nil {
+			scene = *g.doc.Scene
+		}
+		if scene < 0 || scene >= len(g.doc.Scenes) {
+			return fmt.Errorf("scene %d out of range", scene)
+		}
+		roots = g.doc.Scenes[scene].Nodes
+	default:
+		// Without scenes, every node that is nobody's child is a root.
+		isChild := make([]bool, len(g.doc.Nodes))
+		for _, node := range g.doc.Nodes {
+			for _, c := range node.Children {
+				if c >= 0 && c < len(isChild) {
+					isChild[c] = true
+				}
+			}
+		}
+		for i := range g.doc.Nodes {
+			if !isChild[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+
+	// A half turn about X, exactly.
+	axes := mgl64.Scale3D(1, -1, -1)
+	visiting := make([]bool, len(g.doc.Nodes))
+
+	var visit func(idx int, parent mgl64.Mat4) error
+	visit = func(idx int, parent mgl64.Mat4) error {
+		if idx < 0 || idx >= len(g.doc.Nodes) {
+			return fmt.Errorf("node %d out of range", idx)
+		}
+		if visiting[idx] {
+			return fmt.Errorf("node %d is its own ancestor", idx)
+		}
+		visiting[idx] = true
+		defer func() { visiting[idx] = false }()
+
+		node := &g.doc.Nodes[idx]
+		local, err := node.localMatrix()
+		if err != nil {
+			return fmt.Errorf("node %d: %w", idx, err)
+		}
+		world := parent.Mul4(local)
+		if err := fn(node, world); err != nil {
+			return fmt.Errorf("node %d: %w", idx, err)
+		}
+		for _, c := range node.Children {
+			if err := visit(c, world); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, r := range roots {
+		if err := visit(r, axes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localMatrix returns the node's matrix, or its translation, rotation and
+// scale combined as T * R * S.
+func (n *gltfNode) localMatrix() (mgl64.Mat4, error) {
+	if n.Matrix != nil {
+		if len(n.Matrix) != 16 {
+			return mgl64.Mat4{}, fmt.Errorf("matrix has %d values, need 16", len(n.Matrix))
+		}
+		var m mgl64.Mat4
+		copy(m[:], n.Matrix) // both column-major
+		return m, nil
+	}
+
+	m := mgl64.Ident4()
+	if n.Translation != nil {
+		if len(n.Translation) != 3 {
+			return mgl64.Mat4{}, fmt.Errorf("translation has %d values, need 3", len(n.Translation))
+		}
+		m = mgl64.Translate3D(n.Translation[0], n.Translation[1], n.Translation[2])
+	}
+	if n.Rotation != nil {
+		if len(n.Rotation) != 4 {
+			return mgl64.Mat4{}, fmt.Errorf("rotation has %d values, need 4", len(n.Rotation))
+		}
+		q := mgl64.Quat{W: n.Rotation[3], V: mgl64.Vec3{n.Rotation[0], n.Rotation[1], n.Rotation[2]}}
+		m = m.Mul4(q.Normalize().Mat4())
+	}
+	if n.Scale != nil {
+		if len(n.Scale) != 3 {
+			return mgl64.Mat4{}, fmt.Errorf("scale has %d values, need 3", len(n.Scale))
+		}
+		m = m.Mul4(mgl64.Scale3D(n.Scale[0], n.Scale[1], n.Scale[2]))
+	}
+	return m, nil
+}
+
+// addMesh adds the triangles of a mesh, transformed by m, to obj.
+func (g *gltfFile) addMesh(obj *Model, meshIdx int, m mgl64.Mat4, reverse int) error {
+	if meshIdx < 0 || meshIdx >= len(g.doc.Meshes) {
+		return fmt.Errorf("mesh %d out of range", meshIdx)
+	}
+
+	// A mirroring transform turns the winding inside out.
+	mirrored := m.Mat3().Det() < 0
+
+	for pi, prim := range g.doc.Meshes[meshIdx].Primitives {
+		mode := gltfModeTriangles
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+		if mode != gltfModeTriangles && mode != gltfModeTriangleStrip && mode != gltfModeTriangleFan {
+			continue
+		}
+
+		posIdx, ok := prim.Attributes["POSITION"]
+		if !ok {
+			return fmt.Errorf("mesh %d primitive %d has no POSITION", meshIdx, pi)
+		}
+		positions, err := g.readVec3(posIdx)
+		if err != nil {
+			return fmt.Errorf("mesh %d primitive %d positions: %w", meshIdx, pi, err)
+		}
+		for i, p := range positions {
+			v := m.Mul4x1(mgl64.Vec4{p.X, p.Y, p.Z, 1})
+			positions[i] = NewVector3(v[0], v[1], v[2])
+		}
+
+		var indices []int
+		if prim.Indices != nil {
+			indices, err = g.readIndices(*prim.Indices)
+			if err != nil {
+				return fmt.Errorf("mesh %d primitive %d indices: %w", meshIdx, pi, err)
+			}
+			for _, idx := range indices {
+				if idx >= len(positions) {
+					return fmt.Errorf("mesh %d primitive %d: index %d out of range (%d positions)", meshIdx, pi, idx, len(positions))
+				}
+			}
+		} else {
+			indices = make([]int, len(positions))
+			for i := range indices {
+				indices[i] = i
+			}
+		}
+
+		faceColor, err := g.materialColor(prim.Material)
+		if err != nil {
+			return fmt.Errorf("mesh %d primitive %d: %w", meshIdx, pi, err)
+		}
+
+		for _, tri := range gltfTriangles(indices, mode) {
+			if mirrored {
+				tri[1], tri[2] = tri[2], tri[1]
+			}
+			a, b, c := positions[tri[0]], positions[tri[1]], positions[tri[2]]
+			if GetLength2(Cross(Subtract(b, a), Subtract(c, a))) == 0 {
+				continue
+			}
+
+			aFace := NewFace(nil, faceColor, Vector3{})
+			aFace.AddPoint(a.X, a.Y, a.Z)
+			aFace.AddPoint(b.X, b.Y, b.Z)
+			aFace.AddPoint(c.X, c.Y, c.Z)
+			aFace.Finished(reverse)
+			obj.faces.AddFace(aFace)
+		}
+	}
+	return nil
+}
+
+// gltfTriangles lists the triangles of a primitive, wound as glTF defines
+// for each mode.
+func gltfTriangles(indices []int, mode int) [][3]int {
+	var tris [][3]int
+	switch mode {
+	case gltfModeTriangles:
+		for i := 0; i+2 < len(indices); i += 3 {
+			tris = append(tris, [3]int{indices[i], indices[i+1], indices[i+2]})
+		}
+	case gltfModeTriangleStrip:
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				tris = append(tris, [3]int{indices[i], indices[i+1], indices[i+2]})
+			} else {
+				tris = append(tris, [3]int{indices[i], indices[i+2], indices[i+1]})
+			}
+		}
+	case gltfModeTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			tris = append(tris, [3]int{indices[0], indices[i], indices[i+1]})
+		}
+	}
+	return tris
+}
+
+// materialColor returns the premultiplied sRGB colour of a material's base
+// colour factor.
+func (g *gltfFile) materialColor(material *int) (color.RGBA, error) {
+	if material == nil {
+		return defaultGLTFColor, nil
+	}
+	if *material < 0 || *material >= len(g.doc.Materials) {
+		return color.RGBA{}, fmt.Errorf("material %d out of range", *material)
+	}
+
+	pbr := g.doc.Materials[*material].PBRMetallicRoughness
+	if pbr == nil || pbr.BaseColorFactor == nil {
+		return defaultGLTFColor, nil
+	}
+	f := pbr.BaseColorFactor
+	if len(f) != 4 {
+		return color.RGBA{}, fmt.Errorf("material %d base colour has %d values, need 4", *material, len(f))
+	}
+
+	// Only blended materials are translucent; MASK and OPAQUE ones are
+	// drawn solid.
+	alpha := 1.0
+	if g.doc.Materials[*material].AlphaMode == "BLEND" {
+		alpha = math.Max(0, math.Min(1, f[3]))
+	}
+	premul := func(v float64) uint8 {
+		return uint8(math.Round(float64(linearToSRGB(v)) * alpha))
+	}
+	return color.RGBA{R: premul(f[0]), G: premul(f[1]), B: premul(f[2]), A: uint8(math.Round(alpha * 255))}, nil
+}
+
+// gltfMaxZeroAccessorBytes limits the zeros allocated for an accessor
+// without a buffer view.
+const gltfMaxZeroAccessorBytes = 1 << 28
+
+// accessorData returns the accessor, its buffer view's bytes from the
+// accessor's offset on, and the distance between elements.
+func (g *gltfFile) accessorData(idx int, elemSize int) (*gltfAccessor, []byte, int, error) {
+	if idx < 0 || idx >= len(g.doc.Accessors) {
+		return nil, nil, 0, fmt.Errorf("accessor %d out of range", idx)
+	}
+	a := &g.doc.Accessors[idx]
+	if a.Sparse != nil {
+		return nil, nil, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", idx)
+	}
+	if a.Count < 0 {
+		return nil, nil, 0, fmt.Errorf("accessor %d has negative count %d", idx, a.Count)
+	}
+	if a.BufferView == nil {
+		// Accessors without a buffer view are all zeros, allocated here, so
+		// their size is limited rather than trusted.
+		if a.Count > gltfMaxZeroAccessorBytes/elemSize {
+			return nil, nil, 0, fmt.Errorf("accessor %d: %d elements without a buffer view is too many", idx, a.Count)
+		}
+		return a, make([]byte, a.Count*elemSize), elemSize, nil
+	}
+
+	if *a.BufferView < 0 || *a.BufferView >= len(g.doc.BufferViews) {
+		return nil, nil, 0, fmt.Errorf("accessor %d: buffer view %d out of range", idx, *a.BufferView)
+	}
+	view := g.doc.BufferViews[*a.BufferView]
+	if view.Buffer < 0 || view.Buffer >= len(g.buffers) {
+		return nil, nil, 0, fmt.Errorf("accessor %d: buffer %d out of range", idx, view.Buffer)
+	}
+	buf := g.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteOffset+view.ByteLength > len(buf) {
+		return nil, nil, 0, fmt.Errorf("accessor %d: buffer view %d overruns its buffer", idx, *a.BufferView)
+	}
+
+	stride := elemSize
+	if view.ByteStride > 0 {
+		stride = view.ByteStride
+	}
+	data := buf[view.ByteOffset : view.ByteOffset+view.ByteLength]
+	if a.ByteOffset < 0 || a.Count < 0 || (a.Count > 0 && a.ByteOffset+(a.Count-1)*stride+elemSize > len(data)) {
+		return nil, nil, 0, fmt.Errorf("accessor %d overruns its buffer view", idx)
+	}
+	return a, data[a.ByteOffset:], stride, nil
+}
+
+func (g *gltfFile) readVec3(idx int) ([]Vector3, error) {
+	if idx >= 0 && idx < len(g.doc.Accessors) {
+		a := g.doc.Accessors[idx]
+		if a.Type != "VEC3" || a.ComponentType != gltfComponentFloat {
+			return nil, fmt.Errorf("accessor %d is %s of type %d, need float VEC3", idx, a.Type, a.ComponentType)
+		}
+	}
+	a, data, stride, err := g.accessorData(idx, 12)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]Vector3, a.Count)
+	for i := range points {
+		off := i * stride
+		f := func(o int) float64 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(data[off+o:])))
+		}
+		points[i] = NewVector3(f(0), f(4), f(8))
+	}
+	return points, nil
+}
+
+func (g *gltfFile) readIndices(idx int) ([]int, error) {
+	if idx < 0 || idx >= len(g.doc.Accessors) {
+		return nil, fmt.Errorf("accessor %d out of range", idx)
+	}
+	var size int
+	switch g.doc.Accessors[idx].ComponentType {
+	case gltfComponentUnsignedByte:
+		size = 1
+	case gltfComponentUnsignedShort:
+		size = 2
+	case gltfComponentUnsignedInt:
+		size = 4
+	default:
+		return nil, fmt.Errorf("accessor %d has component type %d, need an unsigned integer", idx, g.doc.Accessors[idx].ComponentType)
+	}
+	if g.doc.Accessors[idx].Type != "SCALAR" {
+		return nil, fmt.Errorf("accessor %d is %s, need SCALAR", idx, g.doc.Accessors[idx].Type)
+	}
+
+	a, data, stride, err := g.accessorData(idx, size)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make([]int, a.Count)
+	for i := range indices {
+		b := data[i*stride:]
+		switch size {
+		case 1:
+			indices[i] = int(b[0])
+		case 2:
+			indices[i] = int(binary.LittleEndian.Uint16(b))
+		default:
+			indices[i] = int(binary.LittleEndian.Uint32(b))
+		}
+	}
+	return indices, nil
+}
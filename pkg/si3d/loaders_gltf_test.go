@@ -0,0 +1,232 @@
+package si3d
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadObjectFromGLTFReader_GLBRoundTrip(t *testing.T) {
+	cube := NewCube()
+	var buf bytes.Buffer
+	if err := cube.WriteGLB(&buf); err != nil {
+		t.Fatalf("WriteGLB: %v", err)
+	}
+
+	loaded, err := LoadObjectFromGLTFReader(&buf, nil, FACE_REVERSE, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromGLTFReader: %v", err)
+	}
+
+	if n := loaded.faces.FaceCount(); n != 12 {
+		t.Errorf("face count = %d; want 12", n)
+	}
+	if n := len(loaded.faceMesh.Points); n != 8 {
+		t.Errorf("point count = %d; want 8", n)
+	}
+
+	colors := make(map[[4]uint8]bool)
+	for _, f := range cube.faces.faces {
+		colors[[4]uint8{f.Col.R, f.Col.G, f.Col.B, f.Col.A}] = true
+	}
+	for _, f := range loaded.faces.faces {
+		if !colors[[4]uint8{f.Col.R, f.Col.G, f.Col.B, f.Col.A}] {
+			t.Errorf("colour %v is not one of the cube's", f.Col)
+		}
+		// The axis conversions cancel, so the cube is where it was and its
+		// normals still point inwards.
+		if mid := f.GetMidPoint(); Dot(f.GetNormal(), mid) >= 0 {
+			t.Errorf("face at %v has normal %v pointing out of the cube", mid, f.GetNormal())
+		}
+	}
+}
+
+func TestLoadEntitiesFromGLTFReader_WorldRoundTrip(t *testing.T) {
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(0, 0, 1), math.Pi/2)
+
+	w := NewWorld3d()
+	w.AddObject(&Entity{Model: cube, X: 100, Y: -50, Z: 20})
+	w.AddObject(&Entity{Model: NewCube(), X: -100})
+
+	var jsonBuf, bin bytes.Buffer
+	if err := w.WriteGLTF(&jsonBuf, &bin, "scene%20data.bin"); err != nil {
+		t.Fatalf("WriteGLTF: %v", err)
+	}
+	fsys := fstest.MapFS{"scene data.bin": {Data: bin.Bytes()}}
+
+	entities, err := LoadEntitiesFromGLTFReader(&jsonBuf, fsys, FACE_REVERSE, true)
+	if err != nil {
+		t.Fatalf("LoadEntitiesFromGLTFReader: %v", err)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("entities = %d; want 2", len(entities))
+	}
+
+	want := []Vector3{NewVector3(100, -50, 20), NewVector3(-100, 0, 0)}
+	for i, e := range entities {
+		got := NewVector3(e.X, e.Y, e.Z)
+		if GetLength2(Subtract(got, want[i])) > 1e-9 {
+			t.Errorf("entity %d at %v; want %v", i, got, want[i])
+		}
+		if e.Model.root == nil {
+			t.Errorf("entity %d has no BSP tree", i)
+		}
+	}
+
+	// The first cube's quarter turn about Z is baked into its points: each
+	// reloaded triangle lies on the rotated face of the same colour.
+	m := cube.Transform.GetMatrix()
+	corners := make(map[[4]uint8][]Vector3)
+	for _, f := range cube.faces.faces {
+		key := [4]uint8{f.Col.R, f.Col.G, f.Col.B, f.Col.A}
+		for _, p := range f.Points {
+			corners[key] = append(corners[key], m.RotateVector3(p))
+		}
+	}
+	for _, f := range entities[0].Model.faces.faces {
+		for _, p := range f.Points {
+			found := false
+			for _, c := range corners[[4]uint8{f.Col.R, f.Col.G, f.Col.B, f.Col.A}] {
+				found = found || GetLength2(Subtract(p, c)) < 1e-4
+			}
+			if !found {
+				t.Errorf("point %v of a %v triangle is not a corner of the rotated face", p, f.Col)
+			}
+		}
+	}
+}
+
+// gltfDataURI encodes little-endian values as a base64 data URI.
+func gltfDataURI(values ...any) (string, int) {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), buf.Len()
+}
+
+func TestLoadObjectFromGLTFReader_Hierarchy(t *testing.T) {
+	// Four points of a unit square in the XY plane, then three uint16
+	// indices for one triangle padded to four bytes.
+	uri, length := gltfDataURI(
+		[12]float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0},
+		[4]uint16{0, 1, 2, 0},
+	)
+	src := fmt.Sprintf(`{
+  "asset": {"version": "2.0"},
+  "scene": 0,
+  "scenes": [{"nodes": [0, 3]}],
+  "nodes": [
+    {"translation": [10, 0, 0], "children": [1]},
+    {"rotation": [0, 0, 0.7071067811865476, 0.7071067811865476], "scale": [2, 2, 2], "mesh": 0, "children": [2]},
+    {"matrix": [1,0,0,0, 0,1,0,0, 0,0,1,0, 0,0,5,1], "mesh": 1},
+    {"mesh": 2}
+  ],
+  "meshes": [
+    {"primitives": [{"attributes": {"POSITION": 0}, "indices": 1, "material": 0}]},
+    {"primitives": [{"attributes": {"POSITION": 0}, "mode": 6}]},
+    {"primitives": [{"attributes": {"POSITION": 0}, "mode": 0}, {"attributes": {"POSITION": 0}, "mode": 5, "material": 1}]}
+  ],
+  "materials": [
+    {"pbrMetallicRoughness": {"baseColorFactor": [1, 0, 0, 0.5]}},
+    {"pbrMetallicRoughness": {"baseColorFactor": [0, 0.2158605001138992, 1, 0.5]}, "alphaMode": "BLEND"}
+  ],
+  "accessors": [
+    {"bufferView": 0, "componentType": 5126, "count": 4, "type": "VEC3"},
+    {"bufferView": 1, "componentType": 5123, "count": 3, "type": "SCALAR"}
+  ],
+  "bufferViews": [
+    {"buffer": 0, "byteLength": 48},
+    {"buffer": 0, "byteOffset": 48, "byteLength": 6}
+  ],
+  "buffers": [{"uri": %q, "byteLength": %d}]
+}`, uri, length)
+
+	obj, err := LoadObjectFromGLTFReader(strings.NewReader(src), nil, FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromGLTFReader: %v", err)
+	}
+
+	// One indexed triangle, a two-triangle fan, no points and a
+	// two-triangle strip.
+	if n := obj.faces.FaceCount(); n != 5 {
+		t.Fatalf("face count = %d; want 5", n)
+	}
+
+	// glTF (x, y, z) is engine (x, -y, -z).
+	engine := func(x, y, z float64) Vector3 { return NewVector3(x, -y, -z) }
+	check := func(face int, want ...Vector3) {
+		t.Helper()
+		f := obj.faces.GetFace(face)
+		for i, w := range want {
+			if GetLength2(Subtract(f.Points[i], w)) > 1e-9 {
+				t.Errorf("face %d point %d = %v; want %v", face, i, f.Points[i], w)
+			}
+		}
+	}
+
+	// Node 1: scaled by 2, turned a quarter about Z, then moved 10 along X.
+	check(0, engine(10, 0, 0), engine(10, 2, 0), engine(8, 2, 0))
+	// Node 2 adds 5 along Z in node 1's scaled frame.
+	check(1, engine(10, 0, 10), engine(10, 2, 10), engine(8, 2, 10))
+	// Node 3 is untransformed; strips alternate their winding.
+	check(3, engine(0, 0, 0), engine(1, 0, 0), engine(1, 1, 0))
+	check(4, engine(1, 0, 0), engine(0, 1, 0), engine(1, 1, 0))
+
+	// An opaque material ignores its alpha; a blended one is premultiplied.
+	if c := obj.faces.GetFace(0).Col; c.R != 255 || c.G != 0 || c.A != 255 {
+		t.Errorf("opaque colour = %v", c)
+	}
+	if c := obj.faces.GetFace(1).Col; c != defaultGLTFColor {
+		t.Errorf("colour without material = %v; want white", c)
+	}
+	if c := obj.faces.GetFace(3).Col; c.R != 0 || c.G != 64 || c.B != 128 || c.A != 128 {
+		t.Errorf("blended colour = %v; want {0 64 128 128}", c)
+	}
+}
+
+func TestLoadObjectFromGLTFReader_Errors(t *testing.T) {
+	uri, length := gltfDataURI([9]float32{0, 0, 0, 1, 0, 0, 0, 1, 0}, [3]uint8{0, 1, 3})
+	doc := func(extra string) string {
+		return fmt.Sprintf(`{"asset": {"version": "2.0"},
+  "nodes": [{"mesh": 0}],
+  "meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1}]}],
+  "accessors": [
+    {"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+    {"bufferView": 1, "componentType": 5121, "count": 3, "type": "SCALAR"}
+  ],
+  "bufferViews": [{"buffer": 0, "byteLength": 36}, {"buffer": 0, "byteOffset": 36, "byteLength": 3}]
+  %s}`, extra)
+	}
+
+	tests := map[string]string{
+		"bad json":           "{",
+		"old version":        `{"asset": {"version": "1.0"}}`,
+		"bad glb":            "glTF\x01\x00\x00\x00",
+		"index out of range": doc(fmt.Sprintf(`, "buffers": [{"uri": %q, "byteLength": %d}]`, uri, length)),
+		"short buffer":       doc(fmt.Sprintf(`, "buffers": [{"uri": %q, "byteLength": %d}]`, uri, length+10)),
+		"external buffer":    doc(`, "buffers": [{"uri": "missing.bin", "byteLength": 39}]`),
+		"node cycle":         `{"asset": {"version": "2.0"}, "scenes": [{"nodes": [0]}], "nodes": [{"children": [1]}, {"children": [0]}]}`,
+		"sparse": `{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}],
+  "meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+  "accessors": [{"componentType": 5126, "count": 3, "type": "VEC3", "sparse": {"count": 1}}]}`,
+		"negative count": `{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}],
+  "meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+  "accessors": [{"componentType": 5126, "count": -3, "type": "VEC3"}]}`,
+		"huge count": `{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}],
+  "meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+  "accessors": [{"componentType": 5126, "count": 4000000000000, "type": "VEC3"}]}`,
+		"negative byteLength": doc(fmt.Sprintf(`, "buffers": [{"uri": %q, "byteLength": -1}]`, uri)),
+	}
+	for name, src := range tests {
+		if _, err := LoadObjectFromGLTFReader(strings.NewReader(src), fstest.MapFS{}, FACE_NORMAL, false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
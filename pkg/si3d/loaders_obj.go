@@ -0,0 +1,595 @@
+package si3d
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OBJMaterials maps material names used by an OBJ file's usemtl statements to
+// their diffuse colours.
+type OBJMaterials map[string]color.RGBA
+
+// ModelGroup is a named part of a model file, such as an OBJ object or group.
+type ModelGroup struct {
+	Name  string
+	Model *Model
+}
+
+// defaultOBJColor is used for faces without a material or vertex colours.
+var defaultOBJColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+// objCorner is one corner of an OBJ face, as zero-based indices into the
+// file's vertex, texture coordinate and normal lists. Missing references
+// are -1.
+type objCorner struct {
+	v, vt, vn int
+}
+
+type objFace struct {
+	corners  []objCorner
+	material string
+	group    int
+}
+
+// textured reports whether every corner of the face has a texture
+// coordinate.
+func (f objFace) textured() bool {
+	for _, c := range f.corners {
+		if c.vt < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// objData is the parsed content of an OBJ file.
+type objData struct {
+	vertices     []Vertex
+	vertexColors bool
+	texCoords    [][2]float64
+	normals      []Vector3
+	faces        []objFace
+	groups       []string
+	materialLibs []string
+}
+
+// LoadObjectFromOBJFile loads a Wavefront OBJ file into a single model. Any
+// material libraries named by mtllib are read relative to the OBJ file.
+func LoadObjectFromOBJFile(fileName string, reverse int, useBsp bool) (*Model, error) {
+	data, materials, err := readOBJFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return data.buildModel(materials, reverse, useBsp, -1), nil
+}
+
+// LoadObjectGroupsFromOBJFile is LoadObjectFromOBJFile returning one model per
+// object (o) or group (g) in the file.
+func LoadObjectGroupsFromOBJFile(fileName string, reverse int, useBsp bool) ([]ModelGroup, error) {
+	data, materials, err := readOBJFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return data.buildGroups(materials, reverse, useBsp), nil
+}
+
+// LoadObjectFromOBJReader loads Wavefront OBJ data into a single model.
+// materials supplies the colours for usemtl statements and may be nil;
+// mtllib statements are ignored. reverse and useBsp behave as for
+// LoadObjectFromPLYReader.
+//
+// Faces may use negative (relative) indices and any number of corners;
+// concave polygons are split into triangles. Vertex normals (vn) become the
+// model's vertex normals for Gouraud shading when every face has them and
+// no BSP tree is built. Texture coordinates (vt) become the UVs of faces
+// that have one at every corner, for use with Model.SetTexture.
+func LoadObjectFromOBJReader(reader io.Reader, materials OBJMaterials, reverse int, useBsp bool) (*Model, error) {
+	data, err := parseOBJ(reader)
+	if err != nil {
+		return nil, err
+	}
+	return data.buildModel(materials, reverse, useBsp, -1), nil
+}
+
+// LoadObjectGroupsFromOBJReader is LoadObjectFromOBJReader returning one model
+// per object (o) or group (g), in the order they first appear. Faces before
+// the first o or g statement form a group named "default".
+func LoadObjectGroupsFromOBJReader(reader io.Reader, materials OBJMaterials, reverse int, useBsp bool) ([]ModelGroup, error) {
+	data, err := parseOBJ(reader)
+	if err != nil {
+		return nil, err
+	}
+	return data.buildGroups(materials, reverse, useBsp), nil
+}
+
+// LoadMTLFile reads the diffuse colours from a Wavefront MTL file.
+func LoadMTLFile(fileName string) (OBJMaterials, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not open MTL file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	materials, err := LoadMTLReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing MTL file %s: %w", fileName, err)
+	}
+
+	return materials, nil
+}
+
+// LoadMTLReader reads the diffuse (Kd) colour of every material in Wavefront
+// MTL data. Other material properties are ignored.
+func LoadMTLReader(reader io.Reader) (OBJMaterials, error) {
+	materials := make(OBJMaterials)
+	scanner := bufio.NewScanner(reader)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024) // 1MB max line capacity
+
+	current := ""
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		parts := strings.Fields(stripOBJComment(scanner.Text()))
+		if len(parts) == 0 {
+			continue
+		}
+
+		switch parts[0] {
+		case "newmtl":
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("line %d: newmtl without a name", lineNum)
+			}
+			current = strings.Join(parts[1:], " ")
+			materials[current] = defaultOBJColor
+		case "Kd":
+			if current == "" {
+				return nil, fmt.Errorf("line %d: Kd before newmtl", lineNum)
+			}
+			rgb, err := parseFloats(parts[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid Kd: %w", lineNum, err)
+			}
+			materials[current] = unitRGBToColor(rgb[0], rgb[1], rgb[2])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading from MTL source: %w", err)
+	}
+
+	return materials, nil
+}
+
+// readOBJFile parses an OBJ file and the material libraries it references.
+func readOBJFile(fileName string) (*objData, OBJMaterials, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open OBJ file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	data, err := parseOBJ(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing OBJ file %s: %w", fileName, err)
+	}
+
+	materials := make(OBJMaterials)
+	for _, lib := range data.materialLibs {
+		libMaterials, err := LoadMTLFile(filepath.Join(filepath.Dir(fileName), lib))
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, clr := range libMaterials {
+			materials[name] = clr
+		}
+	}
+
+	return data, materials, nil
+}
+
+// parseOBJ reads the statements of an OBJ file that describe polygon
+// geometry. Unsupported statements such as lines, curves and smoothing
+// groups are ignored.
+func parseOBJ(reader io.Reader) (*objData, error) {
+	data := &objData{}
+	scanner := bufio.NewScanner(reader)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024) // 1MB max line capacity
+
+	material := ""
+	group := -1
+	groupIndex := make(map[string]int)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := stripOBJComment(scanner.Text())
+
+		// A trailing backslash continues the statement on the next line.
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			lineNum++
+			line = line[:len(line)-1] + " " + stripOBJComment(scanner.Text())
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+
+		switch parts[0] {
+		case "v":
+			if len(parts) < 4 {
+				return nil, fmt.Errorf("line %d: vertex needs 3 coordinates", lineNum)
+			}
+			values, err := parseFloats(parts[1:], len(parts)-1)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid vertex: %w", lineNum, err)
+			}
+			vert := Vertex{X: values[0], Y: values[1], Z: values[2], Color: defaultOBJColor}
+			// A common extension appends an RGB colour to the position.
+			if len(values) >= 6 {
+				vert.Color = unitRGBToColor(values[3], values[4], values[5])
+				data.vertexColors = true
+			}
+			data.vertices = append(data.vertices, vert)
+		case "vt":
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("line %d: texture coordinate needs a value", lineNum)
+			}
+			values, err := parseFloats(parts[1:], len(parts)-1)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid texture coordinate: %w", lineNum, err)
+			}
+			var uv [2]float64
+			copy(uv[:], values)
+			data.texCoords = append(data.texCoords, uv)
+		case "vn":
+			values, err := parseFloats(parts[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid normal: %w", lineNum, err)
+			}
+			data.normals = append(data.normals, NewVector3(values[0], values[1], values[2]).Normalize())
+		case "f":
+			if len(parts) < 4 {
+				return nil, fmt.Errorf("line %d: face needs at least 3 vertices", lineNum)
+			}
+			if group < 0 {
+				group = data.addGroup("default", groupIndex)
+			}
+			face := objFace{corners: make([]objCorner, 0, len(parts)-1), material: material, group: group}
+			for _, ref := range parts[1:] {
+				corner, err := data.parseCorner(ref)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				face.corners = append(face.corners, corner)
+			}
+			data.faces = append(data.faces, face)
+		case "o", "g":
+			name := "default"
+			if len(parts) > 1 {
+				name = strings.Join(parts[1:], " ")
+			}
+			group = data.addGroup(name, groupIndex)
+		case "usemtl":
+			material = ""
+			if len(parts) > 1 {
+				material = strings.Join(parts[1:], " ")
+			}
+		case "mtllib":
+			data.materialLibs = append(data.materialLibs, parts[1:]...)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading from OBJ source: %w", err)
+	}
+
+	return data, nil
+}
+
+func (d *objData) addGroup(name string, groupIndex map[string]int) int {
+	if i, ok := groupIndex[name]; ok {
+		return i
+	}
+	d.groups = append(d.groups, name)
+	groupIndex[name] = len(d.groups) - 1
+	return len(d.groups) - 1
+}
+
+// parseCorner parses a face corner of the form v, v/vt, v//vn or v/vt/vn.
+func (d *objData) parseCorner(ref string) (objCorner, error) {
+	corner := objCorner{v: -1, vt: -1, vn: -1}
+	fields := strings.Split(ref, "/")
+	if len(fields) > 3 {
+		return corner, fmt.Errorf("invalid face vertex %q", ref)
+	}
+
+	var err error
+	if corner.v, err = resolveOBJIndex(fields[0], len(d.vertices)); err != nil {
+		return corner, fmt.Errorf("face vertex %q: %w", ref, err)
+	}
+	if len(fields) > 1 && fields[1] != "" {
+		if corner.vt, err = resolveOBJIndex(fields[1], len(d.texCoords)); err != nil {
+			return corner, fmt.Errorf("face texture coordinate %q: %w", ref, err)
+		}
+	}
+	if len(fields) > 2 && fields[2] != "" {
+		if corner.vn, err = resolveOBJIndex(fields[2], len(d.normals)); err != nil {
+			return corner, fmt.Errorf("face normal %q: %w", ref, err)
+		}
+	}
+
+	return corner, nil
+}
+
+// resolveOBJIndex converts a one-based OBJ index, or a negative index
+// counting back from the last element read so far, to a zero-based index.
+func resolveOBJIndex(s string, count int) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index %q", s)
+	}
+
+	switch {
+	case i > 0 && i <= count:
+		return i - 1, nil
+	case i < 0 && -i <= count:
+		return count + i, nil
+	}
+	return 0, fmt.Errorf("index %d out of range (%d defined)", i, count)
+}
+
+// buildModel builds a model from the faces of one group, or of every group
+// when group is negative.
+func (d *objData) buildModel(materials OBJMaterials, reverse int, useBsp bool, group int) *Model {
+	obj := NewModel()
+	var vertexNormals [][]Vector3
+	hasNormals := !useBsp
+
+	for _, f := range d.faces {
+		if group >= 0 && f.group != group {
+			continue
+		}
+
+		faceColor := d.faceColor(f, materials)
+		textured := f.textured()
+		for _, tri := range triangulateOBJFace(d, f.corners) {
+			aFace := NewFace(nil, faceColor, Vector3{})
+			for _, c := range tri {
+				vert := d.vertices[c.v]
+				if textured {
+					// OBJ's V runs up the image, UV's down.
+					uv := d.texCoords[c.vt]
+					aFace.AddPointUV(vert.X, vert.Y, vert.Z, uv[0], 1-uv[1])
+				} else {
+					aFace.AddPoint(vert.X, vert.Y, vert.Z)
+				}
+			}
+			aFace.Finished(reverse)
+			obj.faces.AddFace(aFace)
+
+			if !hasNormals {
+				continue
+			}
+			normals := make([]Vector3, len(tri))
+			for i, c := range tri {
+				if c.vn < 0 {
+					hasNormals = false
+					break
+				}
+				normals[i] = d.normals[c.vn]
+			}
+			vertexNormals = append(vertexNormals, normals)
+		}
+	}
+
+	if useBsp {
+		obj.BuildBSP()
+	}
+	obj.Compile()
+
+	if hasNormals && len(vertexNormals) > 0 {
+		obj.setFaceVertexNormals(vertexNormals)
+	}
+	return obj
+}
+
+func (d *objData) buildGroups(materials OBJMaterials, reverse int, useBsp bool) []ModelGroup {
+	groups := make([]ModelGroup, 0, len(d.groups))
+	for i, name := range d.groups {
+		used := false
+		for _, f := range d.faces {
+			if f.group == i {
+				used = true
+				break
+			}
+		}
+		if !used {
+			continue
+		}
+		groups = append(groups, ModelGroup{Name: name, Model: d.buildModel(materials, reverse, useBsp, i)})
+	}
+	return groups
+}
+
+// faceColor returns the material colour of a face, falling back to the
+// average of its vertex colours and then to a default grey.
+func (d *objData) faceColor(f objFace, materials OBJMaterials) color.RGBA {
+	if clr, ok := materials[f.material]; ok && f.material != "" {
+		return clr
+	}
+	if !d.vertexColors {
+		return defaultOBJColor
+	}
+
+	var r, g, b uint32
+	for _, c := range f.corners {
+		clr := d.vertices[c.v].Color
+		r += uint32(clr.R)
+		g += uint32(clr.G)
+		b += uint32(clr.B)
+	}
+	n := uint32(len(f.corners))
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255}
+}
+
+// triangulateOBJFace returns a face unchanged if it is a planar convex
+// polygon, and otherwise splits it into triangles by ear clipping.
+func triangulateOBJFace(d *objData, corners []objCorner) [][]objCorner {
+	points := make([]Vector3, len(corners))
+	for i, c := range corners {
+		v := d.vertices[c.v]
+		points[i] = NewVector3(v.X, v.Y, v.Z)
+	}
+
+	tris := triangulatePolygon(points)
+	if tris == nil {
+		return [][]objCorner{corners}
+	}
+
+	result := make([][]objCorner, len(tris))
+	for i, tri := range tris {
+		result[i] = []objCorner{corners[tri[0]], corners[tri[1]], corners[tri[2]]}
+	}
+	return result
+}
+
+// triangulatePolygon splits a polygon that is concave or not planar into
+// triangles by ear clipping, returning the corner indices of each triangle.
+// It returns nil for triangles and for planar convex polygons, which can be
+// drawn as they are.
+func triangulatePolygon(points []Vector3) [][3]int {
+	n := len(points)
+	if n <= 3 {
+		return nil
+	}
+
+	// Newell's method gives a robust normal for any simple polygon.
+	var normal Vector3
+	for i := 0; i < n; i++ {
+		p, q := points[i], points[(i+1)%n]
+		normal.X += (p.Y - q.Y) * (p.Z + q.Z)
+		normal.Y += (p.Z - q.Z) * (p.X + q.X)
+		normal.Z += (p.X - q.X) * (p.Y + q.Y)
+	}
+	length := GetLength2(normal)
+	if length < 1e-12 {
+		return nil
+	}
+	normal = NewVector3(normal.X/length, normal.Y/length, normal.Z/length)
+
+	// turn is positive where the polygon turns the same way as its normal.
+	turn := func(a, b, c Vector3) float64 {
+		return Dot(Cross(Subtract(b, a), Subtract(c, b)), normal)
+	}
+
+	planar, convex := true, true
+	tolerance := 1e-6 * length
+	for i := 0; i < n; i++ {
+		if math.Abs(Dot(Subtract(points[i], points[0]), normal)) > 1e-6*math.Sqrt(length) {
+			planar = false
+		}
+		if turn(points[i], points[(i+1)%n], points[(i+2)%n]) < -tolerance {
+			convex = false
+		}
+	}
+	if planar && convex {
+		return nil
+	}
+
+	remaining := make([]int, n)
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	tris := make([][3]int, 0, n-2)
+	for len(remaining) > 3 {
+		m := len(remaining)
+		clipped := false
+		for i := 0; i < m; i++ {
+			ia, ib, ic := remaining[(i+m-1)%m], remaining[i], remaining[(i+1)%m]
+			a, b, c := points[ia], points[ib], points[ic]
+			if turn(a, b, c) <= 0 {
+				continue
+			}
+
+			ear := true
+			for _, j := range remaining {
+				if j == ia || j == ib || j == ic {
+					continue
+				}
+				if pointInTriangle(points[j], a, b, c, normal) {
+					ear = false
+					break
+				}
+			}
+			if !ear {
+				continue
+			}
+
+			tris = append(tris, [3]int{ia, ib, ic})
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			clipped = true
+			break
+		}
+
+		// Degenerate input: fall back to a fan over what is left.
+		if !clipped {
+			for i := 1; i+1 < len(remaining); i++ {
+				tris = append(tris, [3]int{remaining[0], remaining[i], remaining[i+1]})
+			}
+			return tris
+		}
+	}
+
+	return append(tris, [3]int{remaining[0], remaining[1], remaining[2]})
+}
+
+// pointInTriangle reports whether p, projected along normal, lies inside or
+// on the edge of triangle abc.
+func pointInTriangle(p, a, b, c, normal Vector3) bool {
+	d1 := Dot(Cross(Subtract(b, a), Subtract(p, a)), normal)
+	d2 := Dot(Cross(Subtract(c, b), Subtract(p, b)), normal)
+	d3 := Dot(Cross(Subtract(a, c), Subtract(p, c)), normal)
+	return d1 >= 0 && d2 >= 0 && d3 >= 0
+}
+
+func stripOBJComment(line string) string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// parseFloats parses at least min floats from fields.
+func parseFloats(fields []string, min int) ([]float64, error) {
+	if len(fields) < min {
+		return nil, fmt.Errorf("expected %d values, got %d", min, len(fields))
+	}
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse float value '%s': %w", f, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// unitRGBToColor converts colour components in the range 0 to 1.
+func unitRGBToColor(r, g, b float64) color.RGBA {
+	conv := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return color.RGBA{R: conv(r), G: conv(g), B: conv(b), A: 255}
+}
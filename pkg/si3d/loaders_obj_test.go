@@ -0,0 +1,264 @@
+package si3d
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const objQuadsWithMaterials = `# two quads
+mtllib scene.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+v 1 0 1
+vt 0.5 0.5
+usemtl red
+f 1 2 3 4
+usemtl blue
+f -6/1 -5/1 -1/1 -2/1
+`
+
+const mtlRedBlue = `newmtl red
+Kd 1 0 0
+newmtl blue
+Ka 0 0 0
+Kd 0 0 1.0
+`
+
+func TestLoadMTLReader(t *testing.T) {
+	materials, err := LoadMTLReader(strings.NewReader(mtlRedBlue))
+	if err != nil {
+		t.Fatalf("LoadMTLReader: %v", err)
+	}
+	if got := materials["red"]; got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("red = %v", got)
+	}
+	if got := materials["blue"]; got != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("blue = %v", got)
+	}
+
+	if _, err := LoadMTLReader(strings.NewReader("Kd 1 1 1\n")); err == nil {
+		t.Error("LoadMTLReader accepted Kd before newmtl")
+	}
+}
+
+func TestLoadObjectFromOBJReader(t *testing.T) {
+	materials := OBJMaterials{
+		"red":  {R: 255, A: 255},
+		"blue": {B: 255, A: 255},
+	}
+	obj, err := LoadObjectFromOBJReader(strings.NewReader(objQuadsWithMaterials), materials, FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromOBJReader: %v", err)
+	}
+
+	if n := obj.faces.FaceCount(); n != 2 {
+		t.Fatalf("face count = %d; want 2", n)
+	}
+	if len(obj.faceMesh.Points) != 6 {
+		t.Errorf("point count = %d; want 6", len(obj.faceMesh.Points))
+	}
+
+	first, second := obj.faces.GetFace(0), obj.faces.GetFace(1)
+	if first.Col != materials["red"] || second.Col != materials["blue"] {
+		t.Errorf("face colours = %v, %v; want red, blue", first.Col, second.Col)
+	}
+
+	// The negative indices of the second face refer to vertices 1, 2, 6, 5.
+	want := []Vector3{NewVector3(0, 0, 0), NewVector3(1, 0, 0), NewVector3(1, 0, 1), NewVector3(0, 0, 1)}
+	for i, p := range second.Points {
+		if p != want[i] {
+			t.Errorf("second face point %d = %v; want %v", i, p, want[i])
+		}
+	}
+}
+
+func TestLoadObjectFromOBJReader_NoMaterials(t *testing.T) {
+	obj, err := LoadObjectFromOBJReader(strings.NewReader(objQuadsWithMaterials), nil, FACE_NORMAL, true)
+	if err != nil {
+		t.Fatalf("LoadObjectFromOBJReader: %v", err)
+	}
+	if obj.root == nil {
+		t.Error("useBsp did not build a BSP tree")
+	}
+}
+
+func TestLoadObjectFromOBJReader_ConcaveNGon(t *testing.T) {
+	// An L shape: six corners, one of them reflex.
+	src := `v 0 0 0
+v 2 0 0
+v 2 1 0
+v 1 1 0
+v 1 2 0
+v 0 2 0
+f 1 2 3 4 5 6
+`
+	obj, err := LoadObjectFromOBJReader(strings.NewReader(src), nil, FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromOBJReader: %v", err)
+	}
+
+	if n := obj.faces.FaceCount(); n != 4 {
+		t.Fatalf("face count = %d; want 4 triangles", n)
+	}
+
+	// The triangles must cover the L's area of 3 without overlapping.
+	area := 0.0
+	for _, f := range obj.faces.faces {
+		area += GetLength2(Cross(Subtract(f.Points[1], f.Points[0]), Subtract(f.Points[2], f.Points[0]))) / 2
+	}
+	if area < 3-1e-9 || area > 3+1e-9 {
+		t.Errorf("triangulated area = %f; want 3", area)
+	}
+}
+
+func TestLoadObjectFromOBJReader_ConvexNGon(t *testing.T) {
+	src := `v 0 0 0
+v 2 0 0
+v 3 1 0
+v 2 2 0
+v 0 2 0
+f 1 2 3 4 5
+`
+	obj, err := LoadObjectFromOBJReader(strings.NewReader(src), nil, FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromOBJReader: %v", err)
+	}
+	if n := obj.faces.FaceCount(); n != 1 || len(obj.faces.GetFace(0).Points) != 5 {
+		t.Errorf("convex pentagon was split into %d faces", n)
+	}
+}
+
+func TestLoadObjectFromOBJReader_Normals(t *testing.T) {
+	src := `v 0 0 0
+v 1 0 0
+v 0 1 0
+vn 0 0 1
+vn 0.1 0 1
+vn 0 0.1 1
+f 1//1 2//2 3//3
+`
+	obj, err := LoadObjectFromOBJReader(strings.NewReader(src), nil, FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromOBJReader: %v", err)
+	}
+	if !obj.hasVertexNormals || len(obj.vertexNormalIndices) != 1 {
+		t.Fatal("vertex normals from the file were not used")
+	}
+
+	faceNormal := obj.normalMesh.Points[obj.normalIndices[0]]
+	for i, ni := range obj.vertexNormalIndices[0] {
+		if n := obj.normalMesh.Points[ni]; Dot(n, faceNormal) <= 0 {
+			t.Errorf("vertex normal %d = %v points away from face normal %v", i, n, faceNormal)
+		}
+	}
+}
+
+func TestLoadObjectFromOBJReader_TexCoords(t *testing.T) {
+	src := `v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 0.75
+vt 0 1
+f 1/1 2/2 3/3 4/4
+f 1/1 2/2 3
+`
+	obj, err := LoadObjectFromOBJReader(strings.NewReader(src), nil, FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromOBJReader: %v", err)
+	}
+
+	// V is flipped, as OBJ counts it from the bottom of the image.
+	want := []UV{{U: 0, V: 1}, {U: 1, V: 1}, {U: 1, V: 0.25}, {U: 0, V: 0}}
+	uvs := obj.faces.GetFace(0).UVs
+	if len(uvs) != len(want) {
+		t.Fatalf("first face UVs = %v; want %v", uvs, want)
+	}
+	for i := range want {
+		if uvs[i] != want[i] {
+			t.Errorf("first face UV %d = %v; want %v", i, uvs[i], want[i])
+		}
+	}
+
+	// A face with a corner lacking a texture coordinate gets no UVs.
+	if uvs := obj.faces.GetFace(1).UVs; uvs != nil {
+		t.Errorf("second face UVs = %v; want none", uvs)
+	}
+}
+
+func TestLoadObjectGroupsFromOBJReader(t *testing.T) {
+	src := `v 0 0 0
+v 1 0 0
+v 0 1 0
+f 1 2 3
+o first
+f 3 2 1
+g second
+f 1 2 3
+f 1 3 2
+o first
+f 2 3 1
+`
+	groups, err := LoadObjectGroupsFromOBJReader(strings.NewReader(src), nil, FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectGroupsFromOBJReader: %v", err)
+	}
+
+	want := []struct {
+		name  string
+		faces int
+	}{{"default", 1}, {"first", 2}, {"second", 2}}
+	if len(groups) != len(want) {
+		t.Fatalf("group count = %d; want %d", len(groups), len(want))
+	}
+	for i, w := range want {
+		if groups[i].Name != w.name || groups[i].Model.faces.FaceCount() != w.faces {
+			t.Errorf("group %d = %q with %d faces; want %q with %d", i, groups[i].Name, groups[i].Model.faces.FaceCount(), w.name, w.faces)
+		}
+	}
+}
+
+func TestLoadObjectFromOBJReader_Errors(t *testing.T) {
+	tests := map[string]string{
+		"index out of range": "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 4\n",
+		"zero index":         "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 0 1 2\n",
+		"too few vertices":   "v 0 0 0\nv 1 0 0\nf 1 2\n",
+		"bad coordinate":     "v 0 zero 0\n",
+		"missing normal":     "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1//1 2//1 3//1\n",
+	}
+	for name, src := range tests {
+		if _, err := LoadObjectFromOBJReader(strings.NewReader(src), nil, FACE_NORMAL, false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadObjectFromOBJFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "scene.obj"), []byte(objQuadsWithMaterials), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scene.mtl"), []byte(mtlRedBlue), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := LoadObjectFromOBJFile(filepath.Join(dir, "scene.obj"), FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromOBJFile: %v", err)
+	}
+	if got := obj.faces.GetFace(1).Col; got != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("material colour from scene.mtl = %v; want blue", got)
+	}
+
+	if _, err := LoadObjectFromOBJFile(filepath.Join(dir, "missing.obj"), FACE_NORMAL, false); err == nil {
+		t.Error("LoadObjectFromOBJFile succeeded for a missing file")
+	}
+}
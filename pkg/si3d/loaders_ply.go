@@ -0,0 +1,579 @@
+package si3d
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// plyFormat is the encoding of the data section of a PLY file.
+type plyFormat int
+
+const (
+	plyASCII plyFormat = iota
+	plyBinaryLittleEndian
+	plyBinaryBigEndian
+)
+
+// plyType is the scalar type of a PLY property.
+type plyType int
+
+const (
+	plyInt8 plyType = iota
+	plyUint8
+	plyInt16
+	plyUint16
+	plyInt32
+	plyUint32
+	plyFloat32
+	plyFloat64
+)
+
+// plyTypeNames maps both the original and the sized PLY type names.
+var plyTypeNames = map[string]plyType{
+	"char": plyInt8, "int8": plyInt8,
+	"uchar": plyUint8, "uint8": plyUint8,
+	"short": plyInt16, "int16": plyInt16,
+	"ushort": plyUint16, "uint16": plyUint16,
+	"int": plyInt32, "int32": plyInt32,
+	"uint": plyUint32, "uint32": plyUint32,
+	"float": plyFloat32, "float32": plyFloat32,
+	"double": plyFloat64, "float64": plyFloat64,
+}
+
+func (t plyType) size() int {
+	switch t {
+	case plyInt8, plyUint8:
+		return 1
+	case plyInt16, plyUint16:
+		return 2
+	case plyInt32, plyUint32, plyFloat32:
+		return 4
+	}
+	return 8
+}
+
+func (t plyType) isFloat() bool {
+	return t == plyFloat32 || t == plyFloat64
+}
+
+// plyProperty is a scalar or list property of a PLY element.
+type plyProperty struct {
+	name      string
+	typ       plyType
+	isList    bool
+	countType plyType
+}
+
+type plyElement struct {
+	name  string
+	count int
+	props []plyProperty
+}
+
+// index returns the position of the named property, or -1.
+func (e *plyElement) index(names ...string) int {
+	for _, name := range names {
+		for i, p := range e.props {
+			if p.name == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// plyHeader describes the layout of a PLY file.
+type plyHeader struct {
+	format   plyFormat
+	elements []plyElement
+}
+
+func LoadObjectFromPLYFile(fileName string, reverse int, useBsp bool) (*Model, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not open PLY file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	obj, err := LoadObjectFromPLYReader(file, reverse, useBsp)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing PLY file %s: %w", fileName, err)
+	}
+
+	return obj, nil
+}
+
+// LoadObjectFromPLYReader reads an ASCII or binary (either endianness) PLY
+// file. Properties may appear in any order and with any PLY type. Vertices
+// need x, y and z and may carry red, green, blue and alpha (or their
+// diffuse_ forms) and nx, ny, nz normals; faces need a vertex_indices (or
+// vertex_index) list and may carry their own colour. Colours stored as
+// floats are read in the range 0 to 1, and colours with alpha are
+// premultiplied. Faces without a colour average their vertex colours, or
+// are grey. Elements other than vertex and face are skipped.
+//
+// Vertex normals become the model's vertex normals for Gouraud shading when
+// no BSP tree is built.
+func LoadObjectFromPLYReader(reader io.Reader, reverse int, useBsp bool) (*Model, error) {
+	r := bufio.NewReader(reader)
+	header, err := readPLYHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var values plyValueReader
+	if header.format == plyASCII {
+		values = &plyASCIIReader{r: r}
+	} else {
+		order := binary.ByteOrder(binary.LittleEndian)
+		if header.format == plyBinaryBigEndian {
+			order = binary.BigEndian
+		}
+		values = &plyBinaryReader{r: r, order: order}
+	}
+
+	var vertices []Vertex
+	var vertexColors bool
+	var normals []Vector3
+	var faceCorners [][]int
+	var faceColors []color.RGBA
+	var faceHasColor []bool
+
+	for ei := range header.elements {
+		elem := &header.elements[ei]
+		switch elem.name {
+		case "vertex":
+			vertices, normals, err = readPLYVertices(values, elem)
+			vertexColors = newPLYColorProps(elem).present()
+		case "face":
+			faceCorners, faceColors, faceHasColor, err = readPLYFaces(values, elem)
+		default:
+			err = skipPLYElement(values, elem)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	obj := NewModel()
+	var vertexNormals [][]Vector3
+	for i, corners := range faceCorners {
+		for _, idx := range corners {
+			if idx < 0 || idx >= len(vertices) {
+				return nil, fmt.Errorf("face %d: vertex index %d out of range (%d vertices)", i, idx, len(vertices))
+			}
+		}
+
+		faceColor := faceColors[i]
+		if !faceHasColor[i] {
+			if vertexColors {
+				faceColor = averageVertexColor(vertices, corners)
+			} else {
+				faceColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+			}
+		}
+
+		points := make([]Vector3, len(corners))
+		for k, idx := range corners {
+			points[k] = NewVector3(vertices[idx].X, vertices[idx].Y, vertices[idx].Z)
+		}
+		polys := [][]int{corners}
+		if tris := triangulatePolygon(points); tris != nil {
+			polys = polys[:0]
+			for _, tri := range tris {
+				polys = append(polys, []int{corners[tri[0]], corners[tri[1]], corners[tri[2]]})
+			}
+		}
+
+		for _, poly := range polys {
+			aFace := NewFace(nil, faceColor, Vector3{})
+			for _, idx := range poly {
+				vert := vertices[idx]
+				aFace.AddPoint(vert.X, vert.Y, vert.Z)
+			}
+			aFace.Finished(reverse)
+			obj.faces.AddFace(aFace)
+
+			if normals != nil {
+				faceNormals := make([]Vector3, len(poly))
+				for k, idx := range poly {
+					faceNormals[k] = normals[idx]
+				}
+				vertexNormals = append(vertexNormals, faceNormals)
+			}
+		}
+	}
+
+	if useBsp {
+		obj.BuildBSP()
+	}
+	obj.Compile()
+
+	if vertexNormals != nil && !useBsp {
+		obj.setFaceVertexNormals(vertexNormals)
+	}
+	return obj, nil
+}
+
+// readPLYHeader parses the header up to and including end_header.
+func readPLYHeader(r *bufio.Reader) (*plyHeader, error) {
+	header := &plyHeader{}
+	lineNum := 0
+	hasFormat := false
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return nil, fmt.Errorf("unexpected end of file in PLY header")
+			}
+			return nil, fmt.Errorf("error reading PLY header: %w", err)
+		}
+		lineNum++
+
+		parts := strings.Fields(line)
+		if lineNum == 1 {
+			if len(parts) != 1 || parts[0] != "ply" {
+				return nil, fmt.Errorf("not a PLY file: missing \"ply\" magic line")
+			}
+			continue
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		switch parts[0] {
+		case "format":
+			if len(parts) < 3 {
+				return nil, fmt.Errorf("header line %d: invalid format line", lineNum)
+			}
+			switch parts[1] {
+			case "ascii":
+				header.format = plyASCII
+			case "binary_little_endian":
+				header.format = plyBinaryLittleEndian
+			case "binary_big_endian":
+				header.format = plyBinaryBigEndian
+			default:
+				return nil, fmt.Errorf("header line %d: unsupported format %q", lineNum, parts[1])
+			}
+			hasFormat = true
+		case "element":
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("header line %d: invalid element line", lineNum)
+			}
+			count, err := strconv.Atoi(parts[2])
+			if err != nil || count < 0 {
+				return nil, fmt.Errorf("header line %d: invalid element count %q", lineNum, parts[2])
+			}
+			header.elements = append(header.elements, plyElement{name: parts[1], count: count})
+		case "property":
+			if len(header.elements) == 0 {
+				return nil, fmt.Errorf("header line %d: property before any element", lineNum)
+			}
+			prop, err := parsePLYProperty(parts[1:])
+			if err != nil {
+				return nil, fmt.Errorf("header line %d: %w", lineNum, err)
+			}
+			elem := &header.elements[len(header.elements)-1]
+			elem.props = append(elem.props, prop)
+		case "end_header":
+			if !hasFormat {
+				return nil, fmt.Errorf("PLY header has no format line")
+			}
+			return header, nil
+		case "comment", "obj_info":
+		default:
+			return nil, fmt.Errorf("header line %d: unknown keyword %q", lineNum, parts[0])
+		}
+	}
+}
+
+func parsePLYProperty(parts []string) (plyProperty, error) {
+	if len(parts) >= 1 && parts[0] == "list" {
+		if len(parts) != 4 {
+			return plyProperty{}, fmt.Errorf("invalid list property")
+		}
+		countType, ok := plyTypeNames[parts[1]]
+		if !ok || countType.isFloat() {
+			return plyProperty{}, fmt.Errorf("invalid list count type %q", parts[1])
+		}
+		typ, ok := plyTypeNames[parts[2]]
+		if !ok {
+			return plyProperty{}, fmt.Errorf("unknown property type %q", parts[2])
+		}
+		return plyProperty{name: parts[3], typ: typ, isList: true, countType: countType}, nil
+	}
+
+	if len(parts) != 2 {
+		return plyProperty{}, fmt.Errorf("invalid property")
+	}
+	typ, ok := plyTypeNames[parts[0]]
+	if !ok {
+		return plyProperty{}, fmt.Errorf("unknown property type %q", parts[0])
+	}
+	return plyProperty{name: parts[1], typ: typ}, nil
+}
+
+// plyValueReader reads the scalar values of the data section in order.
+type plyValueReader interface {
+	read(t plyType) (float64, error)
+}
+
+// plyASCIIReader reads whitespace separated values.
+type plyASCIIReader struct {
+	r *bufio.Reader
+}
+
+func (a *plyASCIIReader) read(t plyType) (float64, error) {
+	var token []byte
+	for {
+		c, err := a.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(token) > 0 {
+				break
+			}
+			if err == io.EOF {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			if len(token) > 0 {
+				break
+			}
+			continue
+		}
+		token = append(token, c)
+	}
+
+	s := string(token)
+	if t.isFloat() {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse float value '%s': %w", s, err)
+		}
+		return v, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse integer value '%s': %w", s, err)
+	}
+	return float64(v), nil
+}
+
+// plyBinaryReader reads values in the given byte order.
+type plyBinaryReader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (b *plyBinaryReader) read(t plyType) (float64, error) {
+	buf := b.buf[:t.size()]
+	if _, err := io.ReadFull(b.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	switch t {
+	case plyInt8:
+		return float64(int8(buf[0])), nil
+	case plyUint8:
+		return float64(buf[0]), nil
+	case plyInt16:
+		return float64(int16(b.order.Uint16(buf))), nil
+	case plyUint16:
+		return float64(b.order.Uint16(buf)), nil
+	case plyInt32:
+		return float64(int32(b.order.Uint32(buf))), nil
+	case plyUint32:
+		return float64(b.order.Uint32(buf)), nil
+	case plyFloat32:
+		return float64(math.Float32frombits(b.order.Uint32(buf))), nil
+	}
+	return math.Float64frombits(b.order.Uint64(buf)), nil
+}
+
+// readPLYRecord reads one element record. Scalar properties go into
+// scalars; the list property at listIndex, if any, goes into list.
+func readPLYRecord(values plyValueReader, elem *plyElement, scalars []float64, listIndex int, list []int) ([]int, error) {
+	list = list[:0]
+	for i, prop := range elem.props {
+		if !prop.isList {
+			v, err := values.read(prop.typ)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", prop.name, err)
+			}
+			scalars[i] = v
+			continue
+		}
+
+		n, err := values.read(prop.countType)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", prop.name, err)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("property %s: negative list length %v", prop.name, n)
+		}
+		for k := 0; k < int(n); k++ {
+			v, err := values.read(prop.typ)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", prop.name, err)
+			}
+			if i == listIndex {
+				list = append(list, int(v))
+			}
+		}
+	}
+	return list, nil
+}
+
+// plyMaxPrealloc caps the records allocated up front for an element, since
+// the count in the header is untrusted; slices grow past it as records are
+// actually read.
+const plyMaxPrealloc = 1 << 16
+
+// capacity returns the number of records to allocate for up front.
+func (e *plyElement) capacity() int {
+	return min(e.count, plyMaxPrealloc)
+}
+
+func readPLYVertices(values plyValueReader, elem *plyElement) ([]Vertex, []Vector3, error) {
+	ix, iy, iz := elem.index("x"), elem.index("y"), elem.index("z")
+	if ix < 0 || iy < 0 || iz < 0 {
+		return nil, nil, fmt.Errorf("vertex element lacks x, y or z")
+	}
+	colors := newPLYColorProps(elem)
+	inx, iny, inz := elem.index("nx"), elem.index("ny"), elem.index("nz")
+	hasNormals := inx >= 0 && iny >= 0 && inz >= 0
+
+	vertices := make([]Vertex, 0, elem.capacity())
+	var normals []Vector3
+	if hasNormals {
+		normals = make([]Vector3, 0, elem.capacity())
+	}
+
+	scalars := make([]float64, len(elem.props))
+	for i := 0; i < elem.count; i++ {
+		if _, err := readPLYRecord(values, elem, scalars, -1, nil); err != nil {
+			return nil, nil, fmt.Errorf("vertex %d: %w", i, err)
+		}
+
+		clr, _ := colors.color(scalars, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		vertices = append(vertices, Vertex{X: scalars[ix], Y: scalars[iy], Z: scalars[iz], Color: clr})
+		if hasNormals {
+			normals = append(normals, NewVector3(scalars[inx], scalars[iny], scalars[inz]).Normalize())
+		}
+	}
+
+	return vertices, normals, nil
+}
+
+func readPLYFaces(values plyValueReader, elem *plyElement) ([][]int, []color.RGBA, []bool, error) {
+	listIndex := elem.index("vertex_indices", "vertex_index")
+	if listIndex < 0 || !elem.props[listIndex].isList {
+		return nil, nil, nil, fmt.Errorf("face element lacks a vertex_indices list")
+	}
+	colors := newPLYColorProps(elem)
+
+	faces := make([][]int, 0, elem.capacity())
+	faceColors := make([]color.RGBA, 0, elem.capacity())
+	hasColor := make([]bool, 0, elem.capacity())
+
+	scalars := make([]float64, len(elem.props))
+	for i := 0; i < elem.count; i++ {
+		list, err := readPLYRecord(values, elem, scalars, listIndex, nil)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("face %d: %w", i, err)
+		}
+		if len(list) < 3 {
+			return nil, nil, nil, fmt.Errorf("face %d: has %d vertices, need at least 3", i, len(list))
+		}
+
+		clr, ok := colors.color(scalars, color.RGBA{})
+		faces = append(faces, list)
+		faceColors = append(faceColors, clr)
+		hasColor = append(hasColor, ok)
+	}
+
+	return faces, faceColors, hasColor, nil
+}
+
+func skipPLYElement(values plyValueReader, elem *plyElement) error {
+	scalars := make([]float64, len(elem.props))
+	for i := 0; i < elem.count; i++ {
+		if _, err := readPLYRecord(values, elem, scalars, -1, nil); err != nil {
+			return fmt.Errorf("%s %d: %w", elem.name, i, err)
+		}
+	}
+	return nil
+}
+
+// plyColorProps locates the colour properties of an element.
+type plyColorProps struct {
+	r, g, b, a int
+	elem       *plyElement
+}
+
+func newPLYColorProps(elem *plyElement) plyColorProps {
+	return plyColorProps{
+		r:    elem.index("red", "diffuse_red"),
+		g:    elem.index("green", "diffuse_green"),
+		b:    elem.index("blue", "diffuse_blue"),
+		a:    elem.index("alpha", "diffuse_alpha"),
+		elem: elem,
+	}
+}
+
+func (c plyColorProps) present() bool {
+	return c.r >= 0 && c.g >= 0 && c.b >= 0
+}
+
+// color returns the premultiplied colour of a record, or def and false if
+// the element has no colour.
+func (c plyColorProps) color(scalars []float64, def color.RGBA) (color.RGBA, bool) {
+	if !c.present() {
+		return def, false
+	}
+
+	channel := func(i int) float64 {
+		v := scalars[i]
+		if c.elem.props[i].typ.isFloat() {
+			v *= 255
+		}
+		return math.Max(0, math.Min(255, v))
+	}
+
+	alpha := 255.0
+	if c.a >= 0 {
+		alpha = channel(c.a)
+	}
+	premul := func(v float64) uint8 {
+		return uint8(math.Round(v * alpha / 255))
+	}
+	return color.RGBA{R: premul(channel(c.r)), G: premul(channel(c.g)), B: premul(channel(c.b)), A: uint8(math.Round(alpha))}, true
+}
+
+// averageVertexColor averages the colours of a face's vertices.
+func averageVertexColor(vertices []Vertex, corners []int) color.RGBA {
+	var r, g, b, a uint32
+	for _, idx := range corners {
+		clr := vertices[idx].Color
+		r += uint32(clr.R)
+		g += uint32(clr.G)
+		b += uint32(clr.B)
+		a += uint32(clr.A)
+	}
+	n := uint32(len(corners))
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)}
+}
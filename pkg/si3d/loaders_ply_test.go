@@ -0,0 +1,292 @@
+package si3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const asciiPLYQuad = `ply
+format ascii 1.0
+comment a coloured quad and a triangle
+element vertex 5
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face 2
+property list uchar int vertex_indices
+end_header
+0 0 0 255 0 0
+1 0 0 255 0 0
+1 1 0 255 0 0
+0 1 0 255 0 0
+2 2 0 0 0 255
+4 0 1 2 3
+3 1 4 2
+`
+
+func TestLoadObjectFromPLYReader_ASCII(t *testing.T) {
+	obj, err := LoadObjectFromPLYReader(strings.NewReader(asciiPLYQuad), FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromPLYReader: %v", err)
+	}
+
+	if n := obj.faces.FaceCount(); n != 2 {
+		t.Fatalf("face count = %d; want 2", n)
+	}
+	if c := obj.faces.GetFace(0).Col; c != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("quad colour = %v; want red", c)
+	}
+	// The triangle averages two red corners and one blue one.
+	if c := obj.faces.GetFace(1).Col; c != (color.RGBA{R: 170, B: 85, A: 255}) {
+		t.Errorf("triangle colour = %v; want the vertex average", c)
+	}
+}
+
+func TestLoadObjectFromPLYReader_NoColours(t *testing.T) {
+	src := `ply
+format ascii 1.0
+element vertex 3
+property float x
+property float y
+property float z
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0
+1 0 0
+0 1 0
+3 0 1 2
+`
+	obj, err := LoadObjectFromPLYReader(strings.NewReader(src), FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromPLYReader: %v", err)
+	}
+	if c := obj.faces.GetFace(0).Col; c != (color.RGBA{R: 128, G: 128, B: 128, A: 255}) {
+		t.Errorf("uncoloured face = %v; want grey", c)
+	}
+}
+
+// binaryPLY builds a PLY file whose vertices have mixed property types in a
+// non-standard order, an extra element to skip, and a coloured face.
+func binaryPLY(order binary.ByteOrder, format string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ply\nformat " + format + " 1.0\n" +
+		"element vertex 3\n" +
+		"property uchar red\n" +
+		"property double z\n" +
+		"property float x\n" +
+		"property int16 y\n" +
+		"property uchar green\n" +
+		"property uchar blue\n" +
+		"element material 1\n" +
+		"property list uchar float coefficients\n" +
+		"property int id\n" +
+		"element face 1\n" +
+		"property uint8 intensity\n" +
+		"property list uint8 uint32 vertex_index\n" +
+		"property float red\n" +
+		"property float green\n" +
+		"property float blue\n" +
+		"end_header\n")
+
+	verts := []struct {
+		x float32
+		y int16
+		z float64
+	}{{0, 0, 5}, {1, 0, 5}, {0, 1, 5}}
+	for _, v := range verts {
+		buf.WriteByte(10)
+		binary.Write(&buf, order, v.z)
+		binary.Write(&buf, order, v.x)
+		binary.Write(&buf, order, v.y)
+		buf.Write([]byte{20, 30})
+	}
+
+	buf.WriteByte(2)
+	binary.Write(&buf, order, [2]float32{0.5, 0.25})
+	binary.Write(&buf, order, int32(7))
+
+	buf.WriteByte(99)
+	buf.WriteByte(3)
+	binary.Write(&buf, order, [3]uint32{0, 1, 2})
+	binary.Write(&buf, order, [3]float32{0, 1, 0.5})
+	return buf.Bytes()
+}
+
+func TestLoadObjectFromPLYReader_Binary(t *testing.T) {
+	tests := map[string]binary.ByteOrder{
+		"binary_little_endian": binary.LittleEndian,
+		"binary_big_endian":    binary.BigEndian,
+	}
+	for format, order := range tests {
+		obj, err := LoadObjectFromPLYReader(bytes.NewReader(binaryPLY(order, format)), FACE_NORMAL, false)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		if n := obj.faces.FaceCount(); n != 1 {
+			t.Fatalf("%s: face count = %d; want 1", format, n)
+		}
+		f := obj.faces.GetFace(0)
+		want := []Vector3{NewVector3(0, 0, 5), NewVector3(1, 0, 5), NewVector3(0, 1, 5)}
+		for i, p := range want {
+			if got := f.Points[i]; got != p {
+				t.Errorf("%s: point %d = %v; want %v", format, i, got, p)
+			}
+		}
+		// Float colours are scaled from 0..1.
+		if f.Col != (color.RGBA{R: 0, G: 255, B: 128, A: 255}) {
+			t.Errorf("%s: face colour = %v", format, f.Col)
+		}
+	}
+}
+
+func TestLoadObjectFromPLYReader_Alpha(t *testing.T) {
+	src := `ply
+format ascii 1.0
+element vertex 3
+property float x
+property float y
+property float z
+element face 1
+property list uchar int vertex_indices
+property uchar red
+property uchar green
+property uchar blue
+property uchar alpha
+end_header
+0 0 0
+1 0 0
+0 1 0
+3 0 1 2 200 100 0 128
+`
+	obj, err := LoadObjectFromPLYReader(strings.NewReader(src), FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromPLYReader: %v", err)
+	}
+	if c := obj.faces.GetFace(0).Col; c != (color.RGBA{R: 100, G: 50, B: 0, A: 128}) {
+		t.Errorf("colour = %v; want premultiplied {100 50 0 128}", c)
+	}
+}
+
+func TestLoadObjectFromPLYReader_Normals(t *testing.T) {
+	src := `ply
+format ascii 1.0
+element vertex 3
+property float x
+property float y
+property float z
+property float nx
+property float ny
+property float nz
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0 0 0 2
+1 0 0 0.1 0 1
+0 1 0 0 0.1 1
+3 0 1 2
+`
+	obj, err := LoadObjectFromPLYReader(strings.NewReader(src), FACE_NORMAL, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromPLYReader: %v", err)
+	}
+	if !obj.hasVertexNormals || len(obj.vertexNormalIndices) != 1 {
+		t.Fatal("vertex normals from the file were not used")
+	}
+
+	faceNormal := obj.normalMesh.Points[obj.normalIndices[0]]
+	for i, ni := range obj.vertexNormalIndices[0] {
+		n := obj.normalMesh.Points[ni]
+		if Dot(n, faceNormal) <= 0 {
+			t.Errorf("vertex normal %d = %v points away from face normal %v", i, n, faceNormal)
+		}
+		if l := GetLength2(n); l < 0.999 || l > 1.001 {
+			t.Errorf("vertex normal %d has length %v; want 1", i, l)
+		}
+	}
+}
+
+func TestLoadObjectFromPLYReader_Errors(t *testing.T) {
+	header := "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\n" +
+		"element face 1\nproperty list uchar int vertex_indices\nend_header\n"
+	truncated := binaryPLY(binary.LittleEndian, "binary_little_endian")
+	truncated = truncated[:len(truncated)-5]
+
+	tests := map[string]string{
+		"bad magic":          "plx\nformat ascii 1.0\nend_header\n",
+		"missing format":     "ply\nelement vertex 0\nend_header\n",
+		"unknown format":     "ply\nformat binary_middle_endian 1.0\nend_header\n",
+		"unknown type":       "ply\nformat ascii 1.0\nelement vertex 1\nproperty quad x\nend_header\n",
+		"unterminated":       "ply\nformat ascii 1.0\nelement vertex 1\n",
+		"missing coordinate": "ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nend_header\n1\n",
+		"bad number":         header + "0 0 0\n1 zero 0\n0 1 0\n3 0 1 2\n",
+		"index out of range": header + "0 0 0\n1 0 0\n0 1 0\n3 0 1 3\n",
+		"too few vertices":   header + "0 0 0\n1 0 0\n0 1 0\n2 0 1\n",
+		"truncated ascii":    header + "0 0 0\n1 0 0\n0 1 0\n3 0 1\n",
+		"truncated binary":   string(truncated),
+		"huge vertex count":  "ply\nformat ascii 1.0\nelement vertex 4000000000\nproperty float x\nproperty float y\nproperty float z\nend_header\n",
+		"huge face count":    strings.Replace(header, "element face 1", "element face 4000000000", 1) + "0 0 0\n1 0 0\n0 1 0\n3 0 1 2\n",
+	}
+	for name, src := range tests {
+		if _, err := LoadObjectFromPLYReader(strings.NewReader(src), FACE_NORMAL, false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestModel_WritePLYWithFaceColors_RoundTrip(t *testing.T) {
+	cube := NewCube()
+
+	for _, bin := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := cube.WritePLYWithFaceColors(&buf, bin); err != nil {
+			t.Fatalf("WritePLYWithFaceColors(binary=%v): %v", bin, err)
+		}
+
+		// The writer keeps the stored winding, so the cube reloads with the
+		// same FACE_REVERSE it was built with.
+		loaded, err := LoadObjectFromPLYReader(&buf, FACE_REVERSE, false)
+		if err != nil {
+			t.Fatalf("LoadObjectFromPLYReader(binary=%v): %v", bin, err)
+		}
+		if n := loaded.faces.FaceCount(); n != cube.faces.FaceCount() {
+			t.Fatalf("binary=%v: face count = %d; want %d", bin, n, cube.faces.FaceCount())
+		}
+		for i, f := range loaded.faces.faces {
+			orig := cube.faces.GetFace(i)
+			if f.Col != orig.Col {
+				t.Errorf("binary=%v: face %d colour = %v; want %v", bin, i, f.Col, orig.Col)
+			}
+			if Dot(f.GetNormal(), orig.GetNormal()) < 0.999 {
+				t.Errorf("binary=%v: face %d normal = %v; want %v", bin, i, f.GetNormal(), orig.GetNormal())
+			}
+		}
+	}
+}
+
+func TestModel_SaveBinaryPLYWithFaceColors(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "cube.ply")
+	if err := NewCube().SaveBinaryPLYWithFaceColors(fileName); err != nil {
+		t.Fatalf("SaveBinaryPLYWithFaceColors: %v", err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("format binary_little_endian 1.0\n")) {
+		t.Error("file is not binary_little_endian")
+	}
+	if _, err := LoadObjectFromPLYFile(fileName, FACE_NORMAL, true); err != nil {
+		t.Errorf("LoadObjectFromPLYFile: %v", err)
+	}
+}
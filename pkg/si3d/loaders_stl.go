@@ -0,0 +1,227 @@
+package si3d
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+const (
+	stlHeaderSize   = 80
+	stlTriangleSize = 50
+)
+
+// defaultSTLColor is used for triangles without a colour attribute.
+var defaultSTLColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+func LoadObjectFromSTLFile(fileName string, reverse int, useBsp bool) (*Model, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not open STL file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	obj, err := LoadObjectFromSTLReader(file, reverse, useBsp)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing STL file %s: %w", fileName, err)
+	}
+
+	return obj, nil
+}
+
+// LoadObjectFromSTLReader reads an STL file, detecting whether it is ASCII or
+// little-endian binary. Shared corners are welded into single points by the
+// model's mesh. Binary files may carry VisCAM/SolidView face colours; other
+// triangles are grey. STL triangles are wound counter-clockwise seen from
+// outside, so pass FACE_REVERSE for outward facing solids. useBsp builds a
+// BSP tree as for LoadObjectFromPLYReader.
+func LoadObjectFromSTLReader(reader io.Reader, reverse int, useBsp bool) (*Model, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading from STL source: %w", err)
+	}
+
+	obj := NewModel()
+	if isBinarySTL(data) {
+		err = readBinarySTL(data, obj, reverse)
+	} else {
+		err = readASCIISTL(data, obj, reverse)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if useBsp {
+		obj.BuildBSP()
+	}
+	obj.Compile()
+	return obj, nil
+}
+
+// isBinarySTL reports whether data looks like a binary STL file. Binary
+// headers may also start with "solid", so the triangle count is checked
+// against the data length first.
+func isBinarySTL(data []byte) bool {
+	if len(data) >= stlHeaderSize+4 {
+		count := binary.LittleEndian.Uint32(data[stlHeaderSize:])
+		if uint64(len(data)) == stlHeaderSize+4+uint64(count)*stlTriangleSize {
+			return true
+		}
+	}
+	return !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("solid"))
+}
+
+func readBinarySTL(data []byte, obj *Model, reverse int) error {
+	if len(data) < stlHeaderSize+4 {
+		return fmt.Errorf("binary STL too short for its header")
+	}
+
+	count := int(binary.LittleEndian.Uint32(data[stlHeaderSize:]))
+	body := data[stlHeaderSize+4:]
+	if len(body) < count*stlTriangleSize {
+		return fmt.Errorf("binary STL declares %d triangles but holds %d", count, len(body)/stlTriangleSize)
+	}
+
+	readFloat := func(b []byte) float64 {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	for i := 0; i < count; i++ {
+		tri := body[i*stlTriangleSize : (i+1)*stlTriangleSize]
+
+		faceColor := defaultSTLColor
+		if attr := binary.LittleEndian.Uint16(tri[48:]); attr&(1<<15) != 0 {
+			faceColor = stlAttributeColor(attr)
+		}
+
+		// The stored facet normal is skipped; it is recomputed from the
+		// winding.
+		aFace := NewFace(nil, faceColor, Vector3{})
+		for v := 0; v < 3; v++ {
+			off := 12 + v*12
+			aFace.AddPoint(readFloat(tri[off:]), readFloat(tri[off+4:]), readFloat(tri[off+8:]))
+		}
+		aFace.Finished(reverse)
+		obj.faces.AddFace(aFace)
+	}
+
+	return nil
+}
+
+func readASCIISTL(data []byte, obj *Model, reverse int) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(bufio.ScanWords)
+
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		return scanner.Text(), true
+	}
+
+	expect := func(want string) error {
+		got, ok := next()
+		if !ok {
+			return fmt.Errorf("unexpected end of file, expected %q", want)
+		}
+		if got != want {
+			return fmt.Errorf("expected %q, got %q", want, got)
+		}
+		return nil
+	}
+
+	readFloat := func() (float64, error) {
+		tok, ok := next()
+		if !ok {
+			return 0, fmt.Errorf("unexpected end of file, expected a number")
+		}
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse float value '%s': %w", tok, err)
+		}
+		return v, nil
+	}
+
+	for {
+		tok, ok := next()
+		if !ok {
+			break
+		}
+
+		// solid and endsolid lines, including the solid's name, are
+		// skipped along with anything else outside a facet.
+		if tok != "facet" {
+			continue
+		}
+
+		if err := expect("normal"); err != nil {
+			return err
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := readFloat(); err != nil {
+				return fmt.Errorf("facet normal: %w", err)
+			}
+		}
+		if err := expect("outer"); err != nil {
+			return err
+		}
+		if err := expect("loop"); err != nil {
+			return err
+		}
+
+		aFace := NewFace(nil, defaultSTLColor, Vector3{})
+		vertices := 0
+		for {
+			tok, ok := next()
+			if !ok {
+				return fmt.Errorf("unexpected end of file inside facet")
+			}
+			if tok == "endloop" {
+				break
+			}
+			if tok != "vertex" {
+				return fmt.Errorf("expected \"vertex\" or \"endloop\", got %q", tok)
+			}
+
+			var p [3]float64
+			for i := range p {
+				v, err := readFloat()
+				if err != nil {
+					return fmt.Errorf("vertex %d: %w", vertices, err)
+				}
+				p[i] = v
+			}
+			aFace.AddPoint(p[0], p[1], p[2])
+			vertices++
+		}
+		if err := expect("endfacet"); err != nil {
+			return err
+		}
+
+		if vertices < 3 {
+			return fmt.Errorf("facet has %d vertices, need at least 3", vertices)
+		}
+		aFace.Finished(reverse)
+		obj.faces.AddFace(aFace)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading from STL source: %w", err)
+	}
+	return nil
+}
+
+// stlAttributeColor unpacks a 15-bit VisCAM/SolidView colour.
+func stlAttributeColor(attr uint16) color.RGBA {
+	expand := func(v uint16) uint8 {
+		v &= 0x1f
+		return uint8(v<<3 | v>>2)
+	}
+	return color.RGBA{R: expand(attr >> 10), G: expand(attr >> 5), B: expand(attr), A: 255}
+}
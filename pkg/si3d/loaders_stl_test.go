@@ -0,0 +1,172 @@
+package si3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+const asciiSTLTetra = `solid tetra
+  facet normal 0 0 -1
+    outer loop
+      vertex 0 0 0
+      vertex 0 1 0
+      vertex 1 0 0
+    endloop
+  endfacet
+  facet normal 0 -1 0
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 0 0 1
+    endloop
+  endfacet
+  facet normal -1 0 0
+    outer loop
+      vertex 0 0 0
+      vertex 0 0 1
+      vertex 0 1 0
+    endloop
+  endfacet
+  facet normal 1 1 1
+    outer loop
+      vertex 1 0 0
+      vertex 0 1 0
+      vertex 0 0 1
+    endloop
+  endfacet
+endsolid tetra
+`
+
+func TestLoadObjectFromSTLReader_ASCII(t *testing.T) {
+	obj, err := LoadObjectFromSTLReader(strings.NewReader(asciiSTLTetra), FACE_REVERSE, false)
+	if err != nil {
+		t.Fatalf("LoadObjectFromSTLReader: %v", err)
+	}
+
+	if n := obj.faces.FaceCount(); n != 4 {
+		t.Errorf("face count = %d; want 4", n)
+	}
+	// The twelve corners weld into the tetrahedron's four points.
+	if n := len(obj.faceMesh.Points); n != 4 {
+		t.Errorf("point count = %d; want 4", n)
+	}
+
+	// With FACE_REVERSE the engine normal of the z=0 face points into the
+	// solid, along +Z.
+	if n := obj.faces.GetFace(0).GetNormal(); math.Abs(n.Z-1) > 1e-9 {
+		t.Errorf("bottom face normal = %v; want (0, 0, 1)", n)
+	}
+}
+
+func TestLoadObjectFromSTLReader_Binary(t *testing.T) {
+	var buf bytes.Buffer
+	// Binary headers may begin with "solid" too.
+	header := make([]byte, 80)
+	copy(header, "solid but really binary")
+	buf.Write(header)
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+
+	tris := [][9]float32{
+		{0, 0, 0, 0, 1, 0, 1, 0, 0},
+		{1, 0, 0, 0, 1, 0, 1, 1, 0},
+	}
+	attrs := []uint16{0, stlColorAttribute(color.RGBA{R: 255, A: 255})}
+	for i, tri := range tris {
+		binary.Write(&buf, binary.LittleEndian, [3]float32{0, 0, -1})
+		binary.Write(&buf, binary.LittleEndian, tri)
+		binary.Write(&buf, binary.LittleEndian, attrs[i])
+	}
+
+	obj, err := LoadObjectFromSTLReader(&buf, FACE_REVERSE, true)
+	if err != nil {
+		t.Fatalf("LoadObjectFromSTLReader: %v", err)
+	}
+	if obj.root == nil {
+		t.Error("useBsp did not build a BSP tree")
+	}
+	if n := len(obj.faceMesh.Points); n != 4 {
+		t.Errorf("point count = %d; want 4", n)
+	}
+
+	colors := map[color.RGBA]bool{}
+	for _, f := range obj.collectColoredFaces() {
+		colors[f.color] = true
+	}
+	if !colors[defaultSTLColor] || !colors[color.RGBA{R: 255, A: 255}] {
+		t.Errorf("face colours = %v; want grey and red", colors)
+	}
+}
+
+func TestLoadObjectFromSTLReader_Errors(t *testing.T) {
+	tests := map[string]string{
+		"truncated binary":  "short",
+		"missing loop":      "solid x\nfacet normal 0 0 1\nvertex 0 0 0\n",
+		"bad number":        "solid x\nfacet normal 0 0 1\nouter loop\nvertex 0 zero 0\n",
+		"too few vertices":  "solid x\nfacet normal 0 0 1\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nendloop\nendfacet\nendsolid\n",
+		"unterminated loop": "solid x\nfacet normal 0 0 1\nouter loop\nvertex 0 0 0\n",
+	}
+	for name, src := range tests {
+		if _, err := LoadObjectFromSTLReader(strings.NewReader(src), FACE_NORMAL, false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestModel_SaveSTL_RoundTrip(t *testing.T) {
+	cube := NewCube()
+
+	for _, bin := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := cube.SaveSTL(&buf, bin); err != nil {
+			t.Fatalf("SaveSTL(binary=%v): %v", bin, err)
+		}
+		if bin && buf.Len() != 84+12*50 {
+			t.Errorf("binary STL is %d bytes; want %d", buf.Len(), 84+12*50)
+		}
+
+		loaded, err := LoadObjectFromSTLReader(&buf, FACE_REVERSE, false)
+		if err != nil {
+			t.Fatalf("LoadObjectFromSTLReader(binary=%v): %v", bin, err)
+		}
+
+		// Six quads become twelve triangles on the same eight corners.
+		if n := loaded.faces.FaceCount(); n != 12 {
+			t.Errorf("binary=%v: face count = %d; want 12", bin, n)
+		}
+		if n := len(loaded.faceMesh.Points); n != 8 {
+			t.Errorf("binary=%v: point count = %d; want 8", bin, n)
+		}
+
+		// Every reloaded triangle keeps the orientation of the cube face
+		// it came from, so the solid still points the same way.
+		for _, f := range loaded.faces.faces {
+			n := f.GetNormal()
+			mid := f.GetMidPoint()
+			if Dot(n, mid) >= 0 {
+				t.Errorf("binary=%v: face at %v has normal %v pointing out of the cube", bin, mid, n)
+			}
+		}
+
+		if bin {
+			if c := loaded.faces.GetFace(0).Col; c.R < 248 || c.G != 0 || c.B != 0 {
+				t.Errorf("binary colour of the red face = %v", c)
+			}
+		}
+	}
+}
+
+func TestModel_SaveSTL_ASCIINormals(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewCube().SaveSTL(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cube's first face lies at z = -40, so STL's outward normal is -Z.
+	if !strings.Contains(buf.String(), "facet normal 0.000000e+00 0.000000e+00 -1.000000e+00") {
+		t.Errorf("ASCII STL lacks an outward -Z facet normal:\n%s", buf.String()[:200])
+	}
+}
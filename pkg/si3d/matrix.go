@@ -241,6 +241,49 @@ func ToGoSieMatrixFromQuat(q mgl64.Quat) Matrix {
 	return ToGoSieMatrix(q.Mat4())
 }
 
+// Inverse returns the inverse of m, or a zero matrix if m is singular.
+func (m Matrix) Inverse() Matrix {
+	t := m.ThisMatrix
+	inv := mgl64.Mat4{
+		t[0][0], t[0][1], t[0][2], t[0][3],
+		t[1][0], t[1][1], t[1][2], t[1][3],
+		t[2][0], t[2][1], t[2][2], t[2][3],
+		t[3][0], t[3][1], t[3][2], t[3][3],
+	}.Inv()
+	return ToGoSieMatrix(inv)
+}
+
+// NormalMatrix returns the matrix that carries surface normals through m:
+// the inverse transpose of m's 3x3 part, without translation. For rotations
+// it is m's 3x3 part; when m scales, the normals it gives need normalising.
+// A singular m, which flattens a model, gives m's own 3x3 part.
+func (m Matrix) NormalMatrix() Matrix {
+	t := m.ThisMatrix
+	a := mgl64.Mat3{
+		t[0][0], t[0][1], t[0][2],
+		t[1][0], t[1][1], t[1][2],
+		t[2][0], t[2][1], t[2][2],
+	}
+	if a.Det() != 0 {
+		a = a.Inv().Transpose()
+	}
+	return NewMatrixFromData([][]float64{
+		{a[0], a[1], a[2], 0},
+		{a[3], a[4], a[5], 0},
+		{a[6], a[7], a[8], 0},
+		{0, 0, 0, 1},
+	})
+}
+
+// TransformPoint transforms a single point, as TransformObj does.
+func (m Matrix) TransformPoint(p Vector3) Vector3 {
+	return NewVector3(
+		m.ThisMatrix[0][0]*p.X+m.ThisMatrix[1][0]*p.Y+m.ThisMatrix[2][0]*p.Z+m.ThisMatrix[3][0],
+		m.ThisMatrix[0][1]*p.X+m.ThisMatrix[1][1]*p.Y+m.ThisMatrix[2][1]*p.Z+m.ThisMatrix[3][1],
+		m.ThisMatrix[0][2]*p.X+m.ThisMatrix[1][2]*p.Y+m.ThisMatrix[2][2]*p.Z+m.ThisMatrix[3][2],
+	)
+}
+
 // RotateVector3 rotates a Vector3 by the matrix's 3x3 rotation component.
 // It does not apply translation, making it suitable for direction vectors.
 func (m Matrix) RotateVector3(v Vector3) Vector3 {
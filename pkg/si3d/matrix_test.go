@@ -113,6 +113,25 @@ func TestMatrix_RotateVector3(t *testing.T) {
 	}
 }
 
+func TestMatrix_NormalMatrix(t *testing.T) {
+	// Squashing a slope along X tilts its normal the other way; the normal
+	// must stay perpendicular to the transformed surface.
+	m := ScaleMatrix(0.5, 1, 1).MultiplyBy(NewRotationMatrix(ROTZ, 0.3))
+	tangent := m.RotateVector3(NewVector3(1, 1, 0))
+	normal := m.NormalMatrix().RotateVector3(NewVector3(1, -1, 0))
+	if dot := tangent.X*normal.X + tangent.Y*normal.Y + tangent.Z*normal.Z; math.Abs(dot) > 1e-9 {
+		t.Errorf("transformed normal %v is not perpendicular to %v", normal, tangent)
+	}
+
+	// Rotations carry normals as they do points, without translation.
+	r := TransMatrix(5, 6, 7).MultiplyBy(NewRotationMatrix(ROTY, 0.7))
+	want := r.Copy()
+	want.ThisMatrix[3][0], want.ThisMatrix[3][1], want.ThisMatrix[3][2] = 0, 0, 0
+	if !matricesEqual(r.NormalMatrix(), want) {
+		t.Errorf("rotation NormalMatrix = %v, want %v", r.NormalMatrix(), want)
+	}
+}
+
 func matricesEqual(m1, m2 Matrix) bool {
 	for i := 0; i < 4; i++ {
 		for j := 0; j < 4; j++ {
@@ -3,6 +3,7 @@ package si3d
 import (
 	"image/color"
 	"math"
+	"slices"
 )
 
 type Model struct {
@@ -26,6 +27,13 @@ type Model struct {
 	normalIndices    []int
 	drawAllFaces     bool // If true, draw all faces regardless of visibility
 	dontDrawOutlines bool // If true, don't draw outlines of polygons
+
+	// Gouraud shading, see shading.go
+	shadingMode         ShadingMode
+	hasVertexNormals    bool
+	vertexNormalIndices [][]int // Vertex normal index per corner of each face in faceIndices
+
+	texture *Texture // see texture.go
 }
 
 func (o *Model) SetDontDrawOutlines(dontDraw bool) {
@@ -48,14 +56,33 @@ func (o *Model) GetDrawLinesOnly() bool {
 	return o.drawLinesOnly
 }
 
+// PaintObject paints the geometry transformed by the last ApplyMatrixTemp,
+// with the render options in ctx.Options. It projects as a default Camera
+// does, with a focal length of the screen width and no far plane clipping;
+// use PaintObjectWithProjection for other projections.
 func (o *Model) PaintObject(batcher PolygonBatcher, x, y int, lightingChange bool, screenWidth, screenHeight float32, nearPlane float64, ctx *RenderContext) {
+	proj := NewProjection(screenWidth, screenHeight, 0, 0, 0, 0, nearPlane, 0)
+	o.PaintObjectWithProjection(batcher, x, y, lightingChange, screenWidth, screenHeight, proj, ctx)
+}
+
+// PaintObjectWithProjection is PaintObject with the projection, frustum
+// included, given explicitly, as from Camera.Projection.
+func (o *Model) PaintObjectWithProjection(batcher PolygonBatcher, x, y int, lightingChange bool, screenWidth, screenHeight float32, proj *Projection, ctx *RenderContext) {
+	o.paintTransformed(batcher, x, y, lightingChange, screenWidth, screenHeight, proj, ctx, o.transFaceMesh.Points, o.transNormalMesh.Points)
+}
+
+// paintTransformed paints the model given its points and normals in camera
+// space.
+func (o *Model) paintTransformed(batcher PolygonBatcher, x, y int, lightingChange bool, screenWidth, screenHeight float32, proj *Projection, ctx *RenderContext, transPoints, transNormals []Vector3) {
 	if o.canPaintWithoutBSP {
-		o.paintWithoutBSP(batcher, x, y, screenHeight, screenWidth, nearPlane, ctx)
+		o.paintWithoutBSP(batcher, x, y, screenHeight, screenWidth, proj, ctx, transPoints, transNormals)
 
 	} else {
 		if o.root != nil {
-			o.root.PaintWithShading(batcher, x, y, o.transFaceMesh.Points, o.transNormalMesh.Points, lightingChange, o.drawLinesOnly, screenWidth, screenHeight,
-				o.dontDrawOutlines, nearPlane, ctx)
+			ctx.texture = o.texture
+			o.root.PaintWithShading(batcher, x, y, transPoints, transNormals, lightingChange, o.shadingMode == ShadingGouraud, o.drawLinesOnly, screenWidth, screenHeight,
+				o.dontDrawOutlines, proj, ctx)
+			ctx.texture = nil
 		}
 	}
 }
@@ -120,6 +147,11 @@ func (o *Model) Clone() *Model {
 		faceIndices:   o.faceIndices,
 		normalIndices: o.normalIndices,
 
+		shadingMode:         o.shadingMode,
+		hasVertexNormals:    o.hasVertexNormals,
+		vertexNormalIndices: o.vertexNormalIndices,
+		texture:             o.texture,
+
 		// instance-specific
 		transFaceMesh:      o.transFaceMesh.Copy(),
 		transNormalMesh:    o.transNormalMesh.Copy(),
@@ -258,16 +290,49 @@ func (o *Model) CalcSize() {
 	// log.Printf("Object size: X: %.2f, Y: %.2f, Z: %.2f", o.xLength, o.yLength, o.zLength)
 }
 
+// ApplyMatrixTemp transforms the model's points and normals by its Transform
+// and then aMatrix, into the model's own transformed meshes, for use by
+// PaintObject and BspNodesIntersectingLine. World rendering does not use it.
 func (o *Model) ApplyMatrixTemp(aMatrix Matrix) {
 	rotMatrixTemp := aMatrix.MultiplyBy(o.Transform.GetMatrix())
 
-	// Use the new, correct method to transform the normals (rotation only).
-	rotMatrixTemp.TransformNormals(o.normalMesh.Points, o.transNormalMesh.Points)
+	// Vertex normals added after this model was cloned grow the shared
+	// normal mesh.
+	if len(o.transNormalMesh.Points) < len(o.normalMesh.Points) {
+		o.transNormalMesh = o.normalMesh.Copy()
+	}
+
+	transformNormals(rotMatrixTemp, o.normalMesh.Points, o.transNormalMesh.Points)
 
 	// Use the original method to transform the vertex positions (rotation and translation).
 	rotMatrixTemp.TransformObj(o.faceMesh.Points, o.transFaceMesh.Points)
 }
 
+// transformInto transforms the model's points and normals as
+// ApplyMatrixTemp does, but into the render context's scratch buffers rather
+// than the model, so that several renders can paint the model at once.
+func (o *Model) transformInto(aMatrix Matrix, ctx *RenderContext) (transPoints, transNormals []Vector3) {
+	rotMatrixTemp := aMatrix.MultiplyBy(o.Transform.GetMatrix())
+
+	ctx.transPoints = slices.Grow(ctx.transPoints[:0], len(o.faceMesh.Points))[:len(o.faceMesh.Points)]
+	ctx.transNormals = slices.Grow(ctx.transNormals[:0], len(o.normalMesh.Points))[:len(o.normalMesh.Points)]
+
+	transformNormals(rotMatrixTemp, o.normalMesh.Points, ctx.transNormals)
+	rotMatrixTemp.TransformObj(o.faceMesh.Points, ctx.transPoints)
+	return ctx.transPoints, ctx.transNormals
+}
+
+// transformNormals transforms the normals in src by m's normal matrix into
+// dest and normalises them, so that a scaled model shades and culls as the
+// unscaled model does.
+func transformNormals(m Matrix, src, dest []Vector3) {
+	nm := m.NormalMatrix()
+	nm.TransformNormals(src, dest)
+	for i := range dest[:len(src)] {
+		dest[i] = dest[i].Normalize()
+	}
+}
+
 func (o *Model) ApplyMatrixPermanent(aMatrix Matrix) {
 	o.ApplyMatrixTemp(aMatrix)
 
@@ -312,6 +377,7 @@ func (o *Model) createBspTree(faces *FaceStore, newFaces *FaceMesh, newNormMesh
 	parentFace.SetNormal(NewVector3(originalNormal.X, originalNormal.Y, originalNormal.Z))
 	newFace, parentIndices := newFaces.AddFace(parentFace)
 	parent := NewBspNode(newFace.Points, newFace.GetNormal(), newFace.Col, parentIndices, normalIndex)
+	parent.uvs = newFace.UVs
 	pPlane := NewPlane(newFace, newFace.GetNormal())
 
 	// Create two new lists to hold the faces that fall on either side of the plane.
@@ -331,9 +397,11 @@ func (o *Model) createBspTree(faces *FaceStore, newFaces *FaceMesh, newNormMesh
 				if facePart != nil && len(facePart.Points) > 0 {
 					if pPlane.Where(facePart) <= 0 {
 						f1 := NewFace(facePart.Points, currentFace.Col, currentFace.GetNormal())
+						f1.UVs = facePart.UVs
 						fvLeft.AddFace(f1)
 					} else {
 						f2 := NewFace(facePart.Points, currentFace.Col, currentFace.GetNormal())
+						f2.UVs = facePart.UVs
 						fvRight.AddFace(f2)
 					}
 				}
@@ -452,41 +520,62 @@ func (o *Model) GetTransFaceMesh() *FaceMesh {
 	return o.transFaceMesh
 }
 
-func (o *Model) paintWithoutBSP(batcher PolygonBatcher, x, y int, screenHeight, screenWidth float32, nearPlane float64, ctx *RenderContext) {
-	for i := 0; i < len(o.faceIndices); i++ {
+func (o *Model) paintWithoutBSP(batcher PolygonBatcher, x, y int, screenHeight, screenWidth float32, proj *Projection, ctx *RenderContext, transPoints, transNormals []Vector3) {
+	if ctx.workers > 1 && len(o.faceIndices) >= minParallelFaces &&
+		o.paintFacesParallel(batcher, x, y, screenHeight, screenWidth, proj, ctx, transPoints, transNormals) {
+		return
+	}
+	o.paintFaces(batcher, 0, len(o.faceIndices), x, y, screenHeight, screenWidth, proj, ctx, transPoints, transNormals)
+}
+
+// paintFaces paints faces first to last-1 of the model.
+func (o *Model) paintFaces(batcher PolygonBatcher, first, last int, x, y int, screenHeight, screenWidth float32, proj *Projection, ctx *RenderContext, transPoints, transNormals []Vector3) {
+	for i := first; i < last; i++ {
 		faceIndices := o.faceIndices[i]
 		normalIndex := o.normalIndices[i]
 		facePointsInCameraSpace := ctx.Buffer3D[:0]
 		for _, index := range faceIndices {
-			point := o.transFaceMesh.Points[index]
+			point := transPoints[index]
 			facePointsInCameraSpace = append(facePointsInCameraSpace, point)
 		}
 		face := o.faces.faces[i]
 
-		normal := o.transNormalMesh.Points[normalIndex]
+		normal := transNormals[normalIndex]
+
+		var vertexNormals []int
+		if o.shadingMode == ShadingGouraud && o.vertexNormalIndices != nil {
+			vertexNormals = o.vertexNormalIndices[i]
+		}
 
-		o.paintFace(batcher, x, y, facePointsInCameraSpace, normal, screenWidth, screenHeight, face, nearPlane, ctx)
+		o.paintFace(batcher, x, y, facePointsInCameraSpace, normal, vertexNormals, transNormals, screenWidth, screenHeight, face, proj, ctx)
 	}
 }
 
+// BspNodesIntersectingLine returns the BSP node whose polygon the segment
+// from startLine to endLine crosses first, or nil if it crosses none. The
+// points are in the space of the model's transformed points, as left by
+// ApplyMatrixTemp.
 func (o *Model) BspNodesIntersectingLine(startLine, endLine Vector3) *BspNode {
-	nodes := make([]*BspNode, 0, 5)
 	points := make([]Vector3, 0, 10)
 
+	var closestNode *BspNode
+	closestT := math.MaxFloat64
+
 	var traverseNodes func(node *BspNode)
 	traverseNodes = func(node *BspNode) {
 		if node == nil {
 			return
 		}
 
-		facePointsInCameraSpace := points[:]
+		facePointsInCameraSpace := points[:0]
 		for _, index := range node.facePointIndices {
 			point := o.transFaceMesh.Points[index]
 			facePointsInCameraSpace = append(facePointsInCameraSpace, point)
 		}
-		intersects := LineIntersectsPolygon(startLine, endLine, facePointsInCameraSpace)
-		if intersects {
-			nodes = append(nodes, node)
+		t, ok := linePolygonIntersection(startLine, endLine, facePointsInCameraSpace)
+		if ok && t >= 0.0-epsilon && t <= 1.0+epsilon && t < closestT {
+			closestT = t
+			closestNode = node
 		}
 
 		traverseNodes(node.Left)
@@ -494,37 +583,15 @@ func (o *Model) BspNodesIntersectingLine(startLine, endLine Vector3) *BspNode {
 	}
 
 	traverseNodes(o.root)
-
-	if len(nodes) == 0 {
-		return nil
-	}
-
-	// loop through the nodes and find the one closest to startLine point
-	closestNode := nodes[0]
-	distance := math.MaxFloat64
-	for _, node := range nodes {
-		nodex, nodey, nodez := node.GetAveragePoint(o.transFaceMesh.Points)
-		dx := nodex - startLine.X
-		dy := nodey - startLine.Y
-		dz := nodez - startLine.Z
-		d := math.Sqrt(dx*dx + dy*dy + dz*dz)
-		if d < distance {
-			distance = d
-			closestNode = node
-		}
-	}
-
 	return closestNode
 }
 
-func (o *Model) paintFace(batcher PolygonBatcher, x, y int, points []Vector3, normal Vector3, screenWidth, screenHeight float32, face *Face, nearPlane float64, ctx *RenderContext) {
+func (o *Model) paintFace(batcher PolygonBatcher, x, y int, points []Vector3, normal Vector3, vertexNormals []int, transNormals []Vector3, screenWidth, screenHeight float32, face *Face, proj *Projection, ctx *RenderContext) {
 
 	firstPoint := points[0]
 	where := 1.0
-	if !o.drawAllFaces {
-		where = normal.X*firstPoint.X +
-			normal.Y*firstPoint.Y +
-			normal.Z*firstPoint.Z
+	if ctx.Options.cullBackFaces(!o.drawAllFaces) {
+		where = proj.FacingAmount(normal, firstPoint)
 	}
 
 	if where > 0 { // Facing the camera
@@ -534,10 +601,12 @@ func (o *Model) paintFace(batcher PolygonBatcher, x, y int, points []Vector3, no
 			firstPoint,
 			points,
 			normal,
+			vertexNormals,
+			transNormals,
 			screenWidth,
 			screenHeight,
 			face,
-			nearPlane,
+			proj,
 			ctx,
 		)
 	}
@@ -550,78 +619,71 @@ func (o *Model) paintFace2(
 	firstTransformedPoint Vector3,
 	initial3DPoints []Vector3,
 	transformedNormal Vector3,
+	vertexNormals []int,
+	transNormals []Vector3,
 	screenWidth, screenHeight float32,
 	face *Face,
-	nearPlane float64,
+	proj *Projection,
 	ctx *RenderContext,
 ) bool {
 
-	buffer := ctx.Buffer3D[len(initial3DPoints):len(initial3DPoints)]
-	pointsToUse := clipPolygonAgainstNearPlane(initial3DPoints, nearPlane, buffer)
-	if len(pointsToUse) < 3 {
+	if o.textured(face.UVs, len(initial3DPoints)) {
+		attrs := lightTexturedVertices(initial3DPoints, face.UVs, transformedNormal, vertexNormals, transNormals, true, ctx)
+		black := color.RGBA{R: 0, G: 0, B: 0, A: 25}
+		strokeClr, strokeWidth, hasStroke := ctx.outline(black)
+		addTexturedPolygon(batcher, initial3DPoints, attrs, o.texture, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
 		return false
 	}
 
-	ctx.BufferPoints = ctx.BufferPoints[:0]
-	for _, point := range pointsToUse {
-		// cf := float64(screenWidth)
-		// At this stage, point[2] (z) is guaranteed to be >= nearPlaneZ,
-		// so perspective division is safe.
-		z := float32(point.Z)
-		ctx.BufferPoints = append(ctx.BufferPoints, Point{
-			X: ConvertToScreenX(float64(screenWidth), float64(screenHeight), point.X, float64(z)),
-			Y: ConvertToScreenY(float64(screenWidth), float64(screenHeight), point.Y, float64(z)),
-		})
-	}
-
-	// clip to screen
-	clippedPoints := batcher.ClipPolygon(ctx.BufferPoints, screenWidth, screenHeight)
-	if len(clippedPoints) < 3 {
+	if vertexNormals != nil && !o.drawLinesOnly {
+		attrs := shadeVertices(initial3DPoints, vertexNormals, transNormals, face.Col, ctx)
+		black := color.RGBA{R: 0, G: 0, B: 0, A: 25}
+		strokeClr, strokeWidth, hasStroke := ctx.outline(black)
+		addGouraudPolygon(batcher, initial3DPoints, attrs, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
 		return false
 	}
 
-	ctx.BufferFloatX = ctx.BufferFloatX[:0]
-	ctx.BufferFloatY = ctx.BufferFloatY[:0]
-	for _, p := range clippedPoints {
-		ctx.BufferFloatX = append(ctx.BufferFloatX, p.X)
-		ctx.BufferFloatY = append(ctx.BufferFloatY, p.Y)
+	pointsToUse := proj.Frustum.ClipPolygon(initial3DPoints, ctx.ClipBufA, ctx.ClipBufB)
+	if len(pointsToUse) < 3 {
+		return false
 	}
 
-	// Copy the data to new slices so the batcher can store them
-	finalScreenPointsX := make([]float32, len(ctx.BufferFloatX))
-	copy(finalScreenPointsX, ctx.BufferFloatX)
-	finalScreenPointsY := make([]float32, len(ctx.BufferFloatY))
-	copy(finalScreenPointsY, ctx.BufferFloatY)
-
-	col := face.Col
-	polyColor := col
-	if true {
-		shadingRefPoint := firstTransformedPoint
-		polyColor = getColor(shadingRefPoint, transformedNormal, polyColor)
-	}
+	polyColor := ctx.shade(firstTransformedPoint, transformedNormal, face.Col)
+	polyColor = ctx.fog.apply(polyColor, firstTransformedPoint.Z)
 
 	if !o.drawLinesOnly {
 		// black := color.RGBA{R: 50, G: 50, B: 50, A: 25}
 		black := color.RGBA{R: 0, G: 0, B: 0, A: 25}
-		batcher.AddPolygonAndOutline(finalScreenPointsX, finalScreenPointsY, polyColor, black, 1.0)
+		strokeClr, strokeWidth, hasStroke := ctx.outline(black)
+		addPolygon(batcher, pointsToUse, proj, screenWidth, screenHeight, polyColor, strokeClr, strokeWidth, hasStroke, ctx)
 
 	} else {
 		black := color.RGBA{R: 0, G: 0, B: 0, A: 255}
-		batcher.AddPolygonAndOutline(finalScreenPointsX, finalScreenPointsY, black, polyColor, 1.0)
+		lineClr := ctx.wireframeColor(polyColor, firstTransformedPoint.Z)
+		addPolygon(batcher, pointsToUse, proj, screenWidth, screenHeight, black, lineClr, ctx.outlineWidth(), true, ctx)
 	}
 
 	return false
 }
 
-// GetColor calculates the color of a polygon based on simple lighting.
+// Defaults of the built-in light: the share of its brightness that is
+// ambient, and the lowest value it darkens a colour channel to.
+const (
+	defaultAmbient        = 0.65
+	defaultShadingMinimum = 7
+)
+
+// getColor calculates the color of a polygon lit by the built-in light, a
+// spotlight attached to the camera.
 func getColor(
 	firstTransformedPoint Vector3,
 	transformedNormal Vector3,
 	polyColor color.RGBA,
+	ambientLight float64,
+	min int,
 ) color.RGBA {
-	const ambientLight = 0.65
 	const spotlightConePower = 10.0
-	const spotlightLightAmount = 1.0 - ambientLight
+	spotlightLightAmount := 1.0 - ambientLight
 
 	diffuseFactor := transformedNormal.Z
 	if diffuseFactor < 0 {
@@ -648,7 +710,6 @@ func getColor(
 	finalBrightness := ambientLight + spotlightBrightness
 
 	c := 240 - int(finalBrightness*240)
-	min := 7
 	r1 := clamp2(int(polyColor.R)-c, min, 255)
 	g1 := clamp2(int(polyColor.G)-c, min, 255)
 	b1 := clamp2(int(polyColor.B)-c, min, 255)
@@ -674,9 +735,19 @@ const epsilon = 1e-6
 // LineIntersectsPolygon determines if a line segment intersects with a 3D polygon.
 // The polygon is assumed to be planar and convex.
 func LineIntersectsPolygon(lineStart, lineEnd Vector3, polygonPoints []Vector3) bool {
+	t, ok := linePolygonIntersection(lineStart, lineEnd, polygonPoints)
+	// If t is not between 0 and 1, the intersection is outside the segment.
+	return ok && t >= 0.0-epsilon && t <= 1.0+epsilon
+}
+
+// linePolygonIntersection intersects the infinite line through lineStart and
+// lineEnd with a planar polygon. It returns the line parameter t of the
+// intersection, lineStart + t*(lineEnd-lineStart), and whether the line
+// meets the polygon at all.
+func linePolygonIntersection(lineStart, lineEnd Vector3, polygonPoints []Vector3) (float64, bool) {
 	if len(polygonPoints) < 3 {
 		// A polygon must have at least 3 vertices.
-		return false
+		return 0, false
 	}
 
 	// 1. Define the plane of the polygon using the first three points.
@@ -699,28 +770,22 @@ func LineIntersectsPolygon(lineStart, lineEnd Vector3, polygonPoints []Vector3)
 	// Check if the line is parallel to the plane.
 	dotNormalDir := Dot(planeNormal, lineDir)
 	if math.Abs(dotNormalDir) < epsilon {
-		return false // Line is parallel, no intersection.
+		return 0, false // Line is parallel, no intersection.
 	}
 
 	// Calculate the 't' parameter for the line equation.
 	w := Subtract(lineStart, p0)
 	t := -Dot(planeNormal, w) / dotNormalDir
 
-	// 3. Check if the intersection point is within the line segment.
-	// If t is not between 0 and 1, the intersection is outside the segment.
-	if t < 0.0-epsilon || t > 1.0+epsilon {
-		return false
-	}
-
-	// 4. Calculate the actual intersection point.
+	// 3. Calculate the actual intersection point.
 	intersectionPoint := NewVector3(
 		lineStart.X+t*lineDir.X,
 		lineStart.Y+t*lineDir.Y,
 		lineStart.Z+t*lineDir.Z,
 	)
 
-	// 5. Check if the intersection point is inside the polygon.
-	return isPointInPolygon(intersectionPoint, polygonPoints, planeNormal)
+	// 4. Check if the intersection point is inside the polygon.
+	return t, isPointInPolygon(intersectionPoint, polygonPoints, planeNormal)
 }
 
 // isPointInPolygon checks if a 3D point (known to be on the polygon's plane)
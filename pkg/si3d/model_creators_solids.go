@@ -44,13 +44,16 @@ func NewCube() *Model {
 		{4, 0, 1, 5}, // Bottom face (Normal: 0, -1, 0)
 	}
 
+	// Each face shows the whole texture, upright when seen from the front
+	// and sides.
+	cornerUVs := []UV{{0, 0}, {0, 1}, {1, 1}, {1, 0}}
+
 	for i, q := range quads {
 		face := NewFace(nil, colors[i], Vector3{})
 		// Vertices are added in the specified order to ensure correct normal
-		face.AddPoint(points[q[0]][0], points[q[0]][1], points[q[0]][2])
-		face.AddPoint(points[q[1]][0], points[q[1]][1], points[q[1]][2])
-		face.AddPoint(points[q[2]][0], points[q[2]][1], points[q[2]][2])
-		face.AddPoint(points[q[3]][0], points[q[3]][1], points[q[3]][2])
+		for k, index := range q {
+			face.AddPointUV(points[index][0], points[index][1], points[index][2], cornerUVs[k].U, cornerUVs[k].V)
+		}
 
 		// Use FACE_NORMAL because our winding order is now correct.
 		face.Finished(FACE_REVERSE)
@@ -316,6 +319,12 @@ func gen(xWidth, yLength float64, clr color.RGBA, subdivisions int, useTriangles
 			}
 		}
 
+		// The texture lies across the plane as seen from above, with its
+		// top edge at the far (+Z) side.
+		uvAt := func(i, j int) UV {
+			return UV{U: float64(i) / float64(subdivisions), V: 1 - float64(j)/float64(subdivisions)}
+		}
+
 		// Create two triangles for each quad in the subdivision grid.
 		for i := 0; i < subdivisions; i++ {
 			for j := 0; j < subdivisions; j++ {
@@ -324,32 +333,33 @@ func gen(xWidth, yLength float64, clr color.RGBA, subdivisions int, useTriangles
 				p2 := vertices[i+1][j]
 				p3 := vertices[i+1][j+1]
 				p4 := vertices[i][j+1]
+				uv1, uv2, uv3, uv4 := uvAt(i, j), uvAt(i+1, j), uvAt(i+1, j+1), uvAt(i, j+1)
 
 				if useTriangles {
 					// Create the first triangle for the quad (p1, p2, p3).
 					face1 := NewFace(nil, clr, Vector3{})
-					face1.AddPoint(p1[0], p1[1], p1[2])
-					face1.AddPoint(p2[0], p2[1], p2[2])
-					face1.AddPoint(p3[0], p3[1], p3[2])
+					face1.AddPointUV(p1[0], p1[1], p1[2], uv1.U, uv1.V)
+					face1.AddPointUV(p2[0], p2[1], p2[2], uv2.U, uv2.V)
+					face1.AddPointUV(p3[0], p3[1], p3[2], uv3.U, uv3.V)
 					// The vertices are wound counter-clockwise to produce an outward-facing normal.
 					face1.Finished(FACE_REVERSE)
 					obj.faces.AddFace(face1)
 
 					// Create the second triangle for the quad (p1, p3, p4).
 					face2 := NewFace(nil, clr, Vector3{})
-					face2.AddPoint(p1[0], p1[1], p1[2])
-					face2.AddPoint(p3[0], p3[1], p3[2])
-					face2.AddPoint(p4[0], p4[1], p4[2])
+					face2.AddPointUV(p1[0], p1[1], p1[2], uv1.U, uv1.V)
+					face2.AddPointUV(p3[0], p3[1], p3[2], uv3.U, uv3.V)
+					face2.AddPointUV(p4[0], p4[1], p4[2], uv4.U, uv4.V)
 					face2.Finished(FACE_REVERSE)
 					obj.faces.AddFace(face2)
 
 				} else {
 					// Create the first triangle for the quad (p1, p2, p3).
 					face1 := NewFace(nil, clr, Vector3{})
-					face1.AddPoint(p1[0], p1[1], p1[2])
-					face1.AddPoint(p2[0], p2[1], p2[2])
-					face1.AddPoint(p3[0], p3[1], p3[2])
-					face1.AddPoint(p4[0], p4[1], p4[2])
+					face1.AddPointUV(p1[0], p1[1], p1[2], uv1.U, uv1.V)
+					face1.AddPointUV(p2[0], p2[1], p2[2], uv2.U, uv2.V)
+					face1.AddPointUV(p3[0], p3[1], p3[2], uv3.U, uv3.V)
+					face1.AddPointUV(p4[0], p4[1], p4[2], uv4.U, uv4.V)
 					// The vertices are wound counter-clockwise to produce an outward-facing normal.
 					face1.Finished(FACE_REVERSE)
 					obj.faces.AddFace(face1)
@@ -716,7 +726,11 @@ func NewUVSphere(radius float64, sectors, stacks int, bodyClr, stripeClr color.R
 	obj := NewModel()
 
 	// We loop through stacks (latitude) and sectors (longitude).
+	// uvs maps the texture around the sphere equirectangularly, U following
+	// the sectors and V running from the +Z pole to the -Z pole. The seam
+	// has its own column of vertices so that U runs all the way to 1.
 	vertices := make([][3]float64, 0)
+	uvs := make([]UV, 0)
 	for i := 0; i <= stacks; i++ {
 		stackAngle := math.Pi/2 - float64(i)*math.Pi/float64(stacks) // phi
 		xy := radius * math.Cos(stackAngle)
@@ -727,6 +741,7 @@ func NewUVSphere(radius float64, sectors, stacks int, bodyClr, stripeClr color.R
 			x := xy * math.Cos(sectorAngle)
 			y := xy * math.Sin(sectorAngle)
 			vertices = append(vertices, [3]float64{x, y, z})
+			uvs = append(uvs, UV{U: float64(j) / float64(sectors), V: float64(i) / float64(stacks)})
 		}
 	}
 
@@ -759,9 +774,9 @@ func NewUVSphere(radius float64, sectors, stacks int, bodyClr, stripeClr color.R
 			if i != 0 {
 				// First triangle of the quad
 				f1 := NewFace(nil, faceColor, Vector3{})
-				f1.AddPoint(vertices[k1j][0], vertices[k1j][1], vertices[k1j][2])
-				f1.AddPoint(vertices[k2j][0], vertices[k2j][1], vertices[k2j][2])
-				f1.AddPoint(vertices[k1j1][0], vertices[k1j1][1], vertices[k1j1][2])
+				f1.AddPointUV(vertices[k1j][0], vertices[k1j][1], vertices[k1j][2], uvs[k1j].U, uvs[k1j].V)
+				f1.AddPointUV(vertices[k2j][0], vertices[k2j][1], vertices[k2j][2], uvs[k2j].U, uvs[k2j].V)
+				f1.AddPointUV(vertices[k1j1][0], vertices[k1j1][1], vertices[k1j1][2], uvs[k1j1].U, uvs[k1j1].V)
 				f1.Finished(FACE_REVERSE)
 				obj.faces.AddFace(f1)
 			}
@@ -769,9 +784,9 @@ func NewUVSphere(radius float64, sectors, stacks int, bodyClr, stripeClr color.R
 			if i != (stacks - 1) {
 				// Second triangle of the quad
 				f2 := NewFace(nil, faceColor, Vector3{})
-				f2.AddPoint(vertices[k1j1][0], vertices[k1j1][1], vertices[k1j1][2])
-				f2.AddPoint(vertices[k2j][0], vertices[k2j][1], vertices[k2j][2])
-				f2.AddPoint(vertices[k2j1][0], vertices[k2j1][1], vertices[k2j1][2])
+				f2.AddPointUV(vertices[k1j1][0], vertices[k1j1][1], vertices[k1j1][2], uvs[k1j1].U, uvs[k1j1].V)
+				f2.AddPointUV(vertices[k2j][0], vertices[k2j][1], vertices[k2j][2], uvs[k2j].U, uvs[k2j].V)
+				f2.AddPointUV(vertices[k2j1][0], vertices[k2j1][1], vertices[k2j1][2], uvs[k2j1].U, uvs[k2j1].V)
 				f2.Finished(FACE_REVERSE)
 				obj.faces.AddFace(f2)
 			}
@@ -890,6 +905,11 @@ func NewCylinder(radius, height float64, segments int, clr color.RGBA) *Model {
 	topVertices := make([][3]float64, segments)
 	baseVertices := make([][3]float64, segments)
 
+	// The caps take the texture as seen from above, fitted to the circle.
+	capUV := func(x, z float64) UV {
+		return UV{U: 0.5 + x/(2*radius), V: 0.5 + z/(2*radius)}
+	}
+
 	// Calculate the vertex positions for one full circle.
 	for i := 0; i < segments; i++ {
 		angle := (2.0 * math.Pi / float64(segments)) * float64(i)
@@ -903,7 +923,8 @@ func NewCylinder(radius, height float64, segments int, clr color.RGBA) *Model {
 		// Add the calculated vertex to the top face polygon.
 		// The points are added in counter-clockwise (CCW) order, which will
 		// result in a normal vector pointing up (+Y) after finalization.
-		topFace.AddPoint(x, height, z)
+		uv := capUV(x, z)
+		topFace.AddPointUV(x, height, z, uv.U, uv.V)
 	}
 
 	// For the base face, vertices must be in clockwise (CW) order to produce a
@@ -911,7 +932,8 @@ func NewCylinder(radius, height float64, segments int, clr color.RGBA) *Model {
 	// base vertices to the face in reverse order.
 	for i := segments - 1; i >= 0; i-- {
 		v := baseVertices[i]
-		baseFace.AddPoint(v[0], v[1], v[2])
+		uv := capUV(v[0], v[2])
+		baseFace.AddPointUV(v[0], v[1], v[2], uv.U, uv.V)
 	}
 
 	// Finalize the cap faces and add them to the object.
@@ -932,12 +954,17 @@ func NewCylinder(radius, height float64, segments int, clr color.RGBA) *Model {
 		p1_top := topVertices[i]
 		p2_top := topVertices[(i+1)%segments]
 
+		// The texture wraps once around the side, its top edge along y = 0,
+		// which is uppermost in the -Y up world.
+		u1 := float64(i) / float64(segments)
+		u2 := float64(i+1) / float64(segments)
+
 		// Create the side face with vertices in CCW order for an outward normal.
 		sideFace := NewFace(nil, clr, Vector3{})
-		sideFace.AddPoint(p1_base[0], p1_base[1], p1_base[2]) // Bottom-start
-		sideFace.AddPoint(p2_base[0], p2_base[1], p2_base[2]) // Bottom-end
-		sideFace.AddPoint(p2_top[0], p2_top[1], p2_top[2])    // Top-end
-		sideFace.AddPoint(p1_top[0], p1_top[1], p1_top[2])    // Top-start
+		sideFace.AddPointUV(p1_base[0], p1_base[1], p1_base[2], u1, 0) // Bottom-start
+		sideFace.AddPointUV(p2_base[0], p2_base[1], p2_base[2], u2, 0) // Bottom-end
+		sideFace.AddPointUV(p2_top[0], p2_top[1], p2_top[2], u2, 1)    // Top-end
+		sideFace.AddPointUV(p1_top[0], p1_top[1], p1_top[2], u1, 1)    // Top-start
 
 		sideFace.Finished(FACE_NORMAL)
 		obj.faces.AddFace(sideFace)
@@ -1,6 +1,12 @@
 package si3d
 
 import (
+	"bytes"
+	"image"
+	"image/color"
+	imgdraw "image/draw"
+	"math"
+	"sync"
 	"testing"
 )
 
@@ -137,3 +143,84 @@ func TestModel_Center(t *testing.T) {
 		t.Errorf("Center p1 failed, got (%f, %f, %f)", p1.X, p1.Y, p1.Z)
 	}
 }
+
+func TestModel_ConcurrentRenders(t *testing.T) {
+	// Models shared by every world: one with a BSP tree, one drawn from its
+	// face list and one Gouraud shaded.
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(0, 1, 0), 0.4)
+	plane := NewSubdividedPlane(400, 400, color.RGBA{R: 90, G: 160, B: 90, A: 255}, 8, true)
+	sphere := NewSphere(60, 2, color.RGBA{R: 200, G: 120, B: 60, A: 255}, true)
+	sphere.SetShadingMode(ShadingGouraud)
+
+	newWorld := func(camX, camZ float64) *World {
+		w := NewWorld3d()
+		cam := NewCamera(0, 0, 0, 0, 0, 0)
+		cam.SetCameraPosition(camX, -150, camZ)
+		cam.LookAt(NewVector3(0, 0, 0), NewVector3(0, -1, 0))
+		w.AddCamera(cam, camX, -150, camZ)
+		w.AddObject(&Entity{Model: cube})
+		w.AddObject(&Entity{Model: sphere, X: 150})
+		w.AddObjectDrawFirst(&Entity{Model: plane, Y: 60})
+		return w
+	}
+
+	var worlds []*World
+	var want []*image.RGBA
+	for i := 0; i < 4; i++ {
+		angle := float64(i) * math.Pi / 2
+		w := newWorld(400*math.Sin(angle), -400*math.Cos(angle))
+		worlds = append(worlds, w)
+		want = append(want, w.Render(120, 90, color.RGBA{A: 255}))
+	}
+
+	var wg sync.WaitGroup
+	got := make([][]*image.RGBA, len(worlds))
+	for i, w := range worlds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				got[i] = append(got[i], w.Render(120, 90, color.RGBA{A: 255}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range worlds {
+		for _, img := range got[i] {
+			if !bytes.Equal(img.Pix, want[i].Pix) {
+				t.Errorf("world %d: concurrent render differs from serial render", i)
+				break
+			}
+		}
+	}
+}
+
+func TestModel_PaintObject(t *testing.T) {
+	// PaintObject projects as a default camera does, so painting a cube
+	// moved 300 units in front of the eye matches rendering it from a
+	// camera 300 units behind it.
+	cube := NewCube()
+	cube.ApplyMatrixTemp(TransMatrix(0, 0, 300))
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 150))
+	imgdraw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{A: 255}}, image.Point{}, imgdraw.Src)
+	batcher := NewDefaultBatcher(100)
+	cube.PaintObject(batcher, 100, 75, true, 200, 150, 10, NewRenderContext())
+	batcher.Draw(img)
+
+	want := renderEntities(&Entity{Model: NewCube()})
+	if !compareImages(t, img, want) {
+		t.Error("PaintObject differs from a World render")
+	}
+
+	// A zero RenderContext paints at one pixel per output pixel too,
+	// outlines included.
+	imgdraw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{A: 255}}, image.Point{}, imgdraw.Src)
+	cube.PaintObject(batcher, 100, 75, true, 200, 150, 10, &RenderContext{})
+	batcher.Draw(img)
+	if !compareImages(t, img, want) {
+		t.Error("PaintObject with a zero RenderContext differs from a World render")
+	}
+}
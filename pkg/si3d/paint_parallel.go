@@ -0,0 +1,110 @@
+package si3d
+
+import "sync"
+
+// minParallelFaces is the fewest faces a model needs for its faces to be
+// painted on several goroutines. Smaller models are not worth the overhead.
+const minParallelFaces = 4096
+
+// paintWorker is one goroutine painting a share of a model's faces, with
+// its own render context and a batcher of the same kind as the world's.
+type paintWorker struct {
+	ctx         *RenderContext
+	batcher     PolygonBatcher
+	commands    *DefaultBatcher
+	translucent translucentQueue
+}
+
+// batcherCommands returns the DefaultBatcher that holds b's polygons, for
+// the batchers in this package, which all keep their polygons as
+// DefaultBatcher commands until Draw. Other batchers, whose Add methods may
+// do anything, give nil.
+func batcherCommands(b PolygonBatcher) *DefaultBatcher {
+	switch b := b.(type) {
+	case *DefaultBatcher:
+		return b
+	case *DepthBatcher:
+		return &b.DefaultBatcher
+	case *SVGBatcher:
+		return &b.DefaultBatcher
+	case *PDFBatcher:
+		return &b.DefaultBatcher
+	case *EPSBatcher:
+		return &b.DefaultBatcher
+	}
+	return nil
+}
+
+// forkBatcher returns an empty batcher of the same kind as b, which must be
+// one batcherCommands accepts.
+func forkBatcher(b PolygonBatcher) PolygonBatcher {
+	switch b.(type) {
+	case *DepthBatcher:
+		return NewDepthBatcher(0)
+	case *SVGBatcher:
+		return NewSVGBatcher(0)
+	case *PDFBatcher:
+		return NewPDFBatcher(0)
+	case *EPSBatcher:
+		return NewEPSBatcher(0)
+	}
+	return NewDefaultBatcher(0)
+}
+
+// fork returns a render context for another goroutine painting the same
+// render: the same options, lighting, fog and scale, with scratch buffers
+// of its own.
+func (ctx *RenderContext) fork() *RenderContext {
+	f := NewRenderContext()
+	f.Options = ctx.Options
+	f.Lighting = ctx.Lighting
+	f.fog = ctx.fog
+	f.pixelScale = ctx.pixelScale
+	f.texture = ctx.texture
+	return f
+}
+
+// paintFacesParallel paints the model's faces as paintFaces does, split into
+// contiguous runs painted concurrently, each with its own render context
+// and batcher. The runs' polygons, and the translucent ones they hold back,
+// are then joined onto batcher and ctx in face order, so the batch is the
+// one a serial paint builds. It reports false, having painted nothing, if
+// batcher is not one of this package's.
+func (o *Model) paintFacesParallel(batcher PolygonBatcher, x, y int, screenHeight, screenWidth float32, proj *Projection, ctx *RenderContext, transPoints, transNormals []Vector3) bool {
+	main := batcherCommands(batcher)
+	if main == nil {
+		return false
+	}
+
+	n := len(o.faceIndices)
+	numWorkers := min(ctx.workers, n/(minParallelFaces/4))
+	run := (n + numWorkers - 1) / numWorkers
+
+	workers := make([]paintWorker, numWorkers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wk := &workers[i]
+		wk.ctx = ctx.fork()
+		if ctx.translucent != nil {
+			wk.ctx.translucent = &wk.translucent
+		}
+		wk.batcher = forkBatcher(batcher)
+		wk.commands = batcherCommands(wk.batcher)
+
+		first, last := i*run, min((i+1)*run, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o.paintFaces(wk.batcher, first, last, x, y, screenHeight, screenWidth, proj, wk.ctx, transPoints, transNormals)
+		}()
+	}
+	wg.Wait()
+
+	for i := range workers {
+		main.commands = append(main.commands, workers[i].commands.commands...)
+		if ctx.translucent != nil {
+			ctx.translucent.polys = append(ctx.translucent.polys, workers[i].translucent.polys...)
+		}
+	}
+	return true
+}
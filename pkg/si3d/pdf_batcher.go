@@ -0,0 +1,167 @@
+package si3d
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PDFBatcher is a PolygonBatcher that records polygons as the content of a
+// single PDF page, one unit per pixel. Like SVGBatcher it clips polygons to
+// the page and fills Gouraud shaded and textured polygons with their
+// average colour. Translucent fills and strokes keep their alpha through PDF 1.4
+// graphics states.
+type PDFBatcher struct {
+	vectorBatcher
+	content bytes.Buffer
+	// alphas maps a fill and stroke alpha pair to its graphics state name.
+	alphas     map[[2]uint8]string
+	alphaOrder [][2]uint8
+	curAlpha   [2]uint8
+}
+
+func NewPDFBatcher(initialCap int) *PDFBatcher {
+	b := &PDFBatcher{vectorBatcher: newVectorBatcher(initialCap)}
+	b.reset()
+	return b
+}
+
+func (b *PDFBatcher) reset() {
+	b.content.Reset()
+	b.alphas = make(map[[2]uint8]string)
+	b.alphaOrder = b.alphaOrder[:0]
+	b.curAlpha = [2]uint8{255, 255}
+}
+
+// writePolygon appends cmd to the page content.
+func (b *PDFBatcher) writePolygon(cmd polygonCommand) {
+	if len(cmd.xp) == 0 {
+		return
+	}
+
+	fill := unpremultiply(cmd.flatFill())
+	stroke := unpremultiply(cmd.strokeClr)
+	hasFill := cmd.hasFill && fill.A > 0
+	hasStroke := cmd.hasStroke && stroke.A > 0
+	if !hasFill && !hasStroke {
+		return
+	}
+
+	alpha := b.curAlpha
+	if hasFill {
+		alpha[0] = fill.A
+	}
+	if hasStroke {
+		alpha[1] = stroke.A
+	}
+	if alpha != b.curAlpha {
+		fmt.Fprintf(&b.content, "/%s gs\n", b.alphaState(alpha))
+		b.curAlpha = alpha
+	}
+
+	if hasFill {
+		fmt.Fprintf(&b.content, "%s rg\n", formatRGB(fill))
+	}
+	if hasStroke {
+		fmt.Fprintf(&b.content, "%s RG %s w\n", formatRGB(stroke), formatCoord(cmd.strokeW))
+	}
+
+	fmt.Fprintf(&b.content, "%s %s m", formatCoord(cmd.xp[0]), formatCoord(cmd.yp[0]))
+	for i := 1; i < len(cmd.xp); i++ {
+		fmt.Fprintf(&b.content, " %s %s l", formatCoord(cmd.xp[i]), formatCoord(cmd.yp[i]))
+	}
+	switch {
+	case hasFill && hasStroke:
+		b.content.WriteString(" b\n")
+	case hasFill:
+		b.content.WriteString(" h f\n")
+	default:
+		b.content.WriteString(" s\n")
+	}
+}
+
+// alphaState returns the name of the graphics state with the given fill and
+// stroke alpha, adding it to the page's resources if needed.
+func (b *PDFBatcher) alphaState(alpha [2]uint8) string {
+	if name, ok := b.alphas[alpha]; ok {
+		return name
+	}
+	name := "GS" + strconv.Itoa(len(b.alphaOrder))
+	b.alphas[alpha] = name
+	b.alphaOrder = append(b.alphaOrder, alpha)
+	return name
+}
+
+// WritePDF writes a one-page PDF of the given size holding every polygon
+// drawn since the last call, over a background of bg, and then clears the
+// page.
+func (b *PDFBatcher) WritePDF(w io.Writer, width, height int, bg color.Color) error {
+	for _, cmd := range b.takeDocument(width, height) {
+		b.writePolygon(cmd)
+	}
+
+	var content bytes.Buffer
+	// Flip the page so that y grows downwards, as on screen, and stroke
+	// with round joins and caps as gg does.
+	fmt.Fprintf(&content, "1 0 0 -1 0 %d cm\n1 j 1 J\n", height)
+	if bg != nil {
+		c := unpremultiply(color.RGBAModel.Convert(bg).(color.RGBA))
+		if c.A > 0 {
+			if c.A < 255 {
+				fmt.Fprintf(&content, "/%s gs\n", b.alphaState([2]uint8{c.A, 255}))
+			}
+			fmt.Fprintf(&content, "%s rg 0 0 %d %d re f\n", formatRGB(c), width, height)
+			if c.A < 255 {
+				fmt.Fprintf(&content, "/%s gs\n", b.alphaState([2]uint8{255, 255}))
+			}
+		}
+	}
+	content.Write(b.content.Bytes())
+
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+	_, _ = zw.Write(content.Bytes())
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	var resources strings.Builder
+	if len(b.alphaOrder) > 0 {
+		resources.WriteString("/ExtGState <<")
+		for _, alpha := range b.alphaOrder {
+			fmt.Fprintf(&resources, " /%s << /Type /ExtGState /ca %s /CA %s >>",
+				b.alphas[alpha], formatUnit(float64(alpha[0])/255), formatUnit(float64(alpha[1])/255))
+		}
+		resources.WriteString(" >>")
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << %s >> /Contents 4 0 R >>", width, height, resources.String()),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()),
+	}
+	b.reset()
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,186 @@
+package si3d
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pdfContent checks a PDF's cross-reference table and returns its
+// decompressed page content and page object.
+func pdfContent(t *testing.T, data []byte) (string, string) {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("PDF lacks its header or trailer")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("PDF lacks startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n0 5\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := strings.Split(string(data[xref:]), "\n")[3:7]
+	for i, entry := range entries {
+		off, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, data[off:off+10])
+		}
+	}
+
+	page := regexp.MustCompile(`(?s)3 0 obj\n(.*?)\nendobj`).FindSubmatch(data)
+	stream := regexp.MustCompile(`(?s)/Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindSubmatchIndex(data)
+	if page == nil || stream == nil {
+		t.Fatal("PDF lacks its page or content stream")
+	}
+	length, _ := strconv.Atoi(string(data[stream[2]:stream[3]]))
+	zr, err := zlib.NewReader(bytes.NewReader(data[stream[1] : stream[1]+length]))
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+	return string(content), string(page[1])
+}
+
+func TestPDFBatcher_Polygons(t *testing.T) {
+	b := NewPDFBatcher(10)
+	var _ ShadedPolygonBatcher = b
+
+	xp, yp := square(1, 2, 10.5, 20)
+	b.AddPolygon(xp, yp, color.RGBA{R: 255, A: 255})
+	b.AddPolygonOutline(xp, yp, 2, color.RGBA{G: 64, A: 128})
+	b.AddPolygonAndOutline(xp, yp, color.RGBA{B: 255, A: 255}, color.RGBA{A: 255}, 0.5)
+	b.AddShadedPolygon(xp, yp, nil, []color.RGBA{{R: 200, A: 255}, {R: 100, A: 255}, {R: 200, A: 255}, {R: 100, A: 255}}, color.RGBA{}, 0, false)
+	b.Draw(nil)
+
+	var buf bytes.Buffer
+	if err := b.WritePDF(&buf, 32, 24, color.RGBA{R: 255, A: 255}); err != nil {
+		t.Fatalf("WritePDF: %v", err)
+	}
+	content, page := pdfContent(t, buf.Bytes())
+
+	if !strings.Contains(page, "/MediaBox [0 0 32 24]") {
+		t.Errorf("page has the wrong size: %s", page)
+	}
+	if !strings.Contains(page, "/GS0 << /Type /ExtGState /ca 1 /CA 0.502 >>") ||
+		!strings.Contains(page, "/GS1 << /Type /ExtGState /ca 1 /CA 1 >>") {
+		t.Errorf("page lacks the translucent stroke's graphics states: %s", page)
+	}
+
+	want := []string{
+		"1 0 0 -1 0 24 cm\n",
+		"1 0 0 rg 0 0 32 24 re f\n",
+		"1 0 0 rg\n1 2 m 10.5 2 l 10.5 20 l 1 20 l h f\n",
+		// Translucent colours are unpremultiplied, with their alpha set
+		// through a graphics state.
+		"/GS0 gs\n0 0.498 0 RG 2 w\n1 2 m 10.5 2 l 10.5 20 l 1 20 l s\n",
+		"/GS1 gs\n0 0 1 rg\n0 0 0 RG 0.5 w\n1 2 m 10.5 2 l 10.5 20 l 1 20 l b\n",
+		"0.5882 0 0 rg\n1 2 m",
+	}
+	last := -1
+	for _, w := range want {
+		i := strings.Index(content, w)
+		if i < 0 {
+			t.Errorf("content lacks %q\n%s", w, content)
+			continue
+		}
+		if i < last {
+			t.Errorf("%q is out of draw order", w)
+		}
+		last = i
+	}
+
+	// WritePDF starts the next page afresh.
+	buf.Reset()
+	if err := b.WritePDF(&buf, 32, 24, nil); err != nil {
+		t.Fatal(err)
+	}
+	content, page = pdfContent(t, buf.Bytes())
+	if strings.Contains(content, " m") || strings.Contains(page, "ExtGState") {
+		t.Errorf("second page is not empty:\n%s\n%s", page, content)
+	}
+}
+
+func TestWorld_RenderToPDF(t *testing.T) {
+	w := NewWorld3d()
+	counter := &countingBatcher{DefaultBatcher: *NewDefaultBatcher(100)}
+	w.SetPolygonBatcher(counter)
+
+	cam := NewCamera(0, 0, -300, 0, 0, 0)
+	w.AddCamera(cam, 0, 0, -300)
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(0, 1, 0), 0.5)
+	w.AddObject(&Entity{Model: cube})
+
+	var buf bytes.Buffer
+	if err := w.RenderToPDF(&buf, 200, 150, color.RGBA{A: 255}); err != nil {
+		t.Fatalf("RenderToPDF: %v", err)
+	}
+	if w.batcher != counter || counter.count != 0 {
+		t.Error("RenderToPDF did not leave the world's batcher alone")
+	}
+
+	content, _ := pdfContent(t, buf.Bytes())
+	w.Render(200, 150, color.RGBA{A: 255})
+	if paths := strings.Count(content, " m "); paths == 0 || paths != counter.count {
+		t.Errorf("PDF has %d paths; the raster render drew %d", paths, counter.count)
+	}
+}
+
+func TestWorld_RenderToPDFOutlines(t *testing.T) {
+	// The default outlines are written in their own colours at an alpha of
+	// 25: black for face lists and grey, not white, for BSP models.
+	for _, tc := range []struct {
+		name   string
+		model  *Model
+		stroke string
+	}{
+		{"face list", NewCube(), "0 0 0 RG 1 w\n"},
+		{"BSP", newBSPCube(), "0.3922 0.3922 0.3922 RG 1 w\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewWorld3d()
+			w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+			w.AddObject(&Entity{Model: tc.model})
+
+			var buf bytes.Buffer
+			if err := w.RenderToPDF(&buf, 200, 150, color.RGBA{A: 255}); err != nil {
+				t.Fatalf("RenderToPDF: %v", err)
+			}
+			content, page := pdfContent(t, buf.Bytes())
+			if !strings.Contains(content, tc.stroke) {
+				t.Errorf("content lacks stroke %q\n%s", tc.stroke, content)
+			}
+			if strings.Contains(content, "1 1 1 RG") {
+				t.Error("outline written white")
+			}
+			if !strings.Contains(page, "/CA 0.098 >>") {
+				t.Errorf("page lacks the outline alpha: %s", page)
+			}
+		})
+	}
+}
+
+func TestWorld_RenderToPDFClipsShaded(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newEdgeWorld().RenderToPDF(&buf, 200, 150, color.RGBA{A: 255}); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := pdfContent(t, buf.Bytes())
+	point := regexp.MustCompile(`(-?[\d.]+) (-?[\d.]+) [ml]\b`)
+	if n := pointsOutside(t, content, point, 200, 150); n > 0 {
+		t.Errorf("%d PDF points lie off the page", n)
+	}
+}
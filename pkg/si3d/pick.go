@@ -0,0 +1,115 @@
+package si3d
+
+import "math"
+
+// PickResult describes the surface found under a screen pixel by World.Pick.
+type PickResult struct {
+	Entity *Entity
+	// Face is the face hit on a model drawn without a BSP tree, and Node
+	// the node hit on one drawn with a BSP tree. The other is nil.
+	Face *Face
+	Node *BspNode
+	// Point is the world space point hit.
+	Point Vector3
+	// Distance is how far Point is along the view ray, in world units: from
+	// the camera for perspective cameras, and from the camera's plane for
+	// orthographic ones.
+	Distance float64
+}
+
+// Pick finds the nearest surface under pixel (x, y) of a width by height
+// render from the current camera. The ray runs through the centre of the
+// pixel, and only surfaces the render would draw can be hit: faces the
+// render culls for facing away from the camera are skipped, as are hidden
+// entities and hits outside the near and far planes. It returns nil when
+// nothing is hit. Pass the RenderOptions the render was made with, if any,
+// so that Culling matches it; the other options do not affect picking.
+func (w *World) Pick(x, y int, width, height int, opts ...RenderOptions) *PickResult {
+	cam := w.CurrentCamera()
+	if cam == nil {
+		return nil
+	}
+	_, restore := w.useOptions(opts)
+	defer restore()
+	proj := cam.Projection(float32(width), float32(height))
+	origin, dir := proj.ray(float64(x)+0.5, float64(y)+0.5)
+
+	var best *PickResult
+	for _, list := range [][]*Entity{w.entitiesDrawFirst, w.entities, w.entitiesDrawLast} {
+		for _, e := range drawableEntities(list) {
+			objToCam := cam.camMatrixRev.MultiplyBy(e.WorldMatrix())
+			transPoints, transNormals := e.Model.transformInto(objToCam, w.ctx)
+
+			hit := e.Model.pick(origin, dir, cam.NearPlane, cam.FarPlane, proj, transPoints, transNormals, w.ctx)
+			if hit != nil && (best == nil || hit.Distance < best.Distance) {
+				hit.Entity = e
+				best = hit
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	// Point is in camera space until here.
+	best.Point = cam.camMatrixRev.Inverse().TransformPoint(best.Point)
+	return best
+}
+
+// pick returns the nearest hit of a camera space ray on the model, given its
+// camera space points and normals, with Point in camera space. Hits nearer
+// than near, or further than far when far is positive, are ignored, and
+// faces are culled as ctx's options cull them when painting.
+func (o *Model) pick(origin, dir Vector3, near, far float64, proj *Projection, transPoints, transNormals []Vector3, ctx *RenderContext) *PickResult {
+	var best *PickResult
+	bestDepth := math.Inf(1)
+
+	try := func(indices []int, normal Vector3, cull bool) bool {
+		if len(indices) < 3 {
+			return false
+		}
+		points := ctx.Buffer3D[:0]
+		for _, index := range indices {
+			points = append(points, transPoints[index])
+		}
+		ctx.Buffer3D = points
+		if cull && proj.FacingAmount(normal, points[0]) <= 0 {
+			return false
+		}
+
+		depth, ok := linePolygonIntersection(origin, origin.Add(dir), points)
+		if !ok || depth < near || (far > 0 && depth > far) || depth >= bestDepth {
+			return false
+		}
+		bestDepth = depth
+		return true
+	}
+
+	if o.canPaintWithoutBSP {
+		for i, indices := range o.faceIndices {
+			if try(indices, transNormals[o.normalIndices[i]], ctx.Options.cullBackFaces(!o.drawAllFaces)) {
+				best = &PickResult{Face: o.faces.faces[i]}
+			}
+		}
+	} else {
+		var walk func(node *BspNode)
+		walk = func(node *BspNode) {
+			if node == nil {
+				return
+			}
+			if try(node.facePointIndices, transNormals[node.normalIndex], ctx.Options.cullBackFaces(true)) {
+				best = &PickResult{Node: node}
+			}
+			walk(node.Left)
+			walk(node.Right)
+		}
+		walk(o.root)
+	}
+
+	if best == nil {
+		return nil
+	}
+	best.Point = NewVector3(origin.X+dir.X*bestDepth, origin.Y+dir.Y*bestDepth, origin.Z+dir.Z*bestDepth)
+	best.Distance = GetLength2(Subtract(best.Point, origin))
+	return best
+}
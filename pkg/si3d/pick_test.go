@@ -0,0 +1,135 @@
+package si3d
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func newBSPCube() *Model {
+	m := NewModel()
+	m.AddFacesFromObject(NewCube())
+	m.BuildBSP()
+	m.Compile()
+	return m
+}
+
+func TestWorld_Pick(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		model *Model
+	}{
+		{"face list", NewCube()},
+		{"BSP", newBSPCube()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cube := &Entity{Model: tc.model, X: 20}
+			w := newTestWorld(cube)
+
+			// The centre of the screen looks along +Z at the cube's front face.
+			hit := w.Pick(100, 75, 200, 150)
+			if hit == nil {
+				t.Fatal("Pick missed the cube")
+			}
+			if hit.Entity != cube {
+				t.Errorf("hit entity %v, want the cube", hit.Entity)
+			}
+			if (hit.Face == nil) == (hit.Node == nil) {
+				t.Errorf("hit has face %v and node %v; want exactly one", hit.Face, hit.Node)
+			}
+			if tc.model.root != nil && hit.Node == nil {
+				t.Error("BSP model hit has no node")
+			}
+			if math.Abs(hit.Point.Z+40) > 1e-6 || math.Abs(hit.Point.X) > 1 || math.Abs(hit.Point.Y) > 1 {
+				t.Errorf("hit point %v, want about (0, 0, -40)", hit.Point)
+			}
+			if math.Abs(hit.Distance-hit.Point.DistanceTo(NewVector3(0, 0, -300))) > 1e-6 {
+				t.Errorf("distance %v does not match hit point %v", hit.Distance, hit.Point)
+			}
+
+			if hit := w.Pick(2, 2, 200, 150); hit != nil {
+				t.Errorf("corner pixel hit %+v", hit)
+			}
+		})
+	}
+}
+
+func TestWorld_PickNearest(t *testing.T) {
+	far := &Entity{Model: NewCube(), Z: 200, Name: "far"}
+	near := &Entity{Model: NewCube(), Name: "near"}
+	w := newTestWorld(near, far)
+
+	if hit := w.Pick(100, 75, 200, 150); hit == nil || hit.Entity != near {
+		t.Fatalf("Pick = %+v; want the near cube", hit)
+	}
+
+	// Hidden entities cannot be picked.
+	near.SetVisible(false)
+	if hit := w.Pick(100, 75, 200, 150); hit == nil || hit.Entity != far {
+		t.Fatalf("Pick = %+v; want the far cube behind the hidden one", hit)
+	}
+
+	// Children are picked where their parent puts them.
+	pivot := &Entity{X: -60}
+	child := &Entity{Model: NewCube(), X: 60, Z: -100}
+	pivot.AddChild(child)
+	w.AddObject(pivot)
+	if hit := w.Pick(100, 75, 200, 150); hit == nil || hit.Entity != child || math.Abs(hit.Point.Z+140) > 1e-6 {
+		t.Fatalf("Pick = %+v; want the child cube's front face at z -140", hit)
+	}
+}
+
+func TestWorld_PickMatchesRender(t *testing.T) {
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(1, 1, 0).Normalize(), 0.7)
+	w := newTestWorld(&Entity{Model: cube})
+	ortho := NewOrthographicCamera(OrthoIsometric, NewVector3(0, 0, 0), 500, 200)
+	pos := ortho.GetPosition()
+
+	bg := color.RGBA{A: 255}
+	for _, orthographic := range []bool{false, true} {
+		if orthographic {
+			w.AddCamera(ortho, pos.X, pos.Y, pos.Z)
+		}
+		img := w.Render(80, 60, bg)
+
+		// Pixels the render covers are the ones Pick hits, apart from a few
+		// partly covered along the silhouette.
+		mismatches, hits := 0, 0
+		for y := 0; y < 60; y++ {
+			for x := 0; x < 80; x++ {
+				hit := w.Pick(x, y, 80, 60)
+				if hit != nil {
+					hits++
+				}
+				if (hit != nil) != (img.RGBAAt(x, y) != bg) {
+					mismatches++
+				}
+			}
+		}
+		if hits == 0 || mismatches > hits/10 {
+			t.Errorf("orthographic %v: %d of %d hits disagree with the render", orthographic, mismatches, hits)
+		}
+	}
+}
+
+func TestModel_BspNodesIntersectingLine(t *testing.T) {
+	m := newBSPCube()
+	m.ApplyMatrixTemp(IdentMatrix())
+
+	// The segment runs through the cube's front face at z -40 and its back
+	// face at z 40, and the front face is crossed first.
+	node := m.BspNodesIntersectingLine(NewVector3(30, 30, -100), NewVector3(30, 30, 100))
+	if node == nil {
+		t.Fatal("segment through the cube hit nothing")
+	}
+	for _, i := range node.facePointIndices {
+		if z := m.transFaceMesh.Points[i].Z; z != -40 {
+			t.Fatalf("hit face has a corner at z %v; want the front face", z)
+		}
+	}
+
+	if node := m.BspNodesIntersectingLine(NewVector3(30, 30, -100), NewVector3(30, 30, -50)); node != nil {
+		t.Error("segment stopping short of the cube hit it")
+	}
+}
@@ -43,6 +43,12 @@ func (p *Plane) PointOnPlane(x, y, z float64) float64 {
 	return num
 }
 
+// signedDistance returns the raw plane equation value for v, without the
+// planeThickness snapping applied by PointOnPlane.
+func (p *Plane) signedDistance(v Vector3) float64 {
+	return p.A*v.X + p.B*v.Y + p.C*v.Z + p.D
+}
+
 // lIntersect checks if a line segment defined by two points (p1, p2) intersects the plane.
 func (p *Plane) lIntersect(p1, p2 Vector3) bool {
 	a := p.PointOnPlane(p1.X, p1.Y, p1.Z)
@@ -119,6 +125,16 @@ func (p *Plane) SplitFace(aFace *Face) []*Face {
 		return faces
 	}
 
+	// Texture coordinates are carried across, interpolated at the split.
+	textured := len(aFace.UVs) == aFace.Cnum
+	addPoint := func(face int, pnt Vector3, uv UV) {
+		if textured {
+			faces[face].AddPointUV(pnt.X, pnt.Y, pnt.Z, uv.U, uv.V)
+		} else {
+			faces[face].AddPoint(pnt.X, pnt.Y, pnt.Z)
+		}
+	}
+
 	currentFace := 0
 	pnts := NewClist(aFace.Cnum)
 	for i := 0; i < aFace.Cnum; i++ {
@@ -130,23 +146,33 @@ func (p *Plane) SplitFace(aFace *Face) []*Face {
 		p3d2 := pnts.NextPoint()
 		pnts.Back()
 
+		var uv1, uv2 UV
+		if textured {
+			uv1, uv2 = aFace.UVs[pnt], aFace.UVs[(pnt+1)%aFace.Cnum]
+		}
+
 		if p.lIntersect(p3d1, p3d2) {
 			pointIntersect, ok := p.LineIntersect(p3d1, p3d2)
 			inter = true
-			faces[currentFace].AddPoint(p3d1.X, p3d1.Y, p3d1.Z)
+			addPoint(currentFace, p3d1, uv1)
 			if ok {
-				faces[currentFace].AddPoint(pointIntersect.X, pointIntersect.Y, pointIntersect.Z)
+				var uvI UV
+				if textured {
+					t := GetLength2(Subtract(pointIntersect, p3d1)) / GetLength2(Subtract(p3d2, p3d1))
+					uvI = UV{U: uv1.U + t*(uv2.U-uv1.U), V: uv1.V + t*(uv2.V-uv1.V)}
+				}
+				addPoint(currentFace, pointIntersect, uvI)
 				currentFace = 1 - currentFace // flip
-				faces[currentFace].AddPoint(pointIntersect.X, pointIntersect.Y, pointIntersect.Z)
+				addPoint(currentFace, pointIntersect, uvI)
 			}
 		} else {
 			if p.PointOnPlane(p3d1.X, p3d1.Y, p3d1.Z) == 0 {
 				inter = true
-				faces[currentFace].AddPoint(p3d1.X, p3d1.Y, p3d1.Z)
+				addPoint(currentFace, p3d1, uv1)
 				currentFace = 1 - currentFace // flip
-				faces[currentFace].AddPoint(p3d1.X, p3d1.Y, p3d1.Z)
+				addPoint(currentFace, p3d1, uv1)
 			} else {
-				faces[currentFace].AddPoint(p3d1.X, p3d1.Y, p3d1.Z)
+				addPoint(currentFace, p3d1, uv1)
 			}
 		}
 	}
@@ -0,0 +1,145 @@
+package si3d
+
+import "math"
+
+// Projection maps camera-space points onto a screen of a particular size.
+// It is derived from a Camera by Camera.Projection once per render.
+type Projection struct {
+	Width, Height float64
+	// FocalX and FocalY are the focal lengths in pixels.
+	FocalX, FocalY float64
+	// CenterX and CenterY are the principal point in pixels.
+	CenterX, CenterY float64
+	// Orthographic disables the perspective divide. The focal lengths are
+	// then in pixels per world unit.
+	Orthographic bool
+	// Frustum is the camera-space clipping volume matching this projection.
+	Frustum *Frustum
+}
+
+// NewProjection builds a perspective projection for the given screen size.
+// fovY is the vertical field of view in radians; 0 selects the legacy
+// projection where both focal lengths equal the screen width. aspect is the
+// ratio of the horizontal to vertical view extent and defaults to the screen
+// aspect ratio when 0. offsetX and offsetY move the principal point away from
+// the centre of the screen.
+func NewProjection(screenWidth, screenHeight float32, fovY, aspect, offsetX, offsetY, near, far float64) *Projection {
+	w, h := float64(screenWidth), float64(screenHeight)
+	p := &Projection{
+		Width:   w,
+		Height:  h,
+		FocalX:  w,
+		FocalY:  w,
+		CenterX: w/2.0 + offsetX,
+		CenterY: h/2.0 + offsetY,
+	}
+
+	if fovY > 0 {
+		if aspect <= 0 {
+			aspect = w / h
+		}
+		tanHalf := math.Tan(fovY / 2.0)
+		p.FocalY = (h / 2.0) / tanHalf
+		p.FocalX = (w / 2.0) / (aspect * tanHalf)
+	}
+
+	p.Frustum = NewFrustum(near, far, p)
+	return p
+}
+
+// NewOrthographicProjection builds a parallel projection for the given screen
+// size. viewHeight is the height of the view volume in world units and aspect
+// is the ratio of its width to its height, defaulting to the screen aspect
+// ratio when 0. A viewHeight that is not positive falls back to the screen
+// height, one world unit per pixel.
+func NewOrthographicProjection(screenWidth, screenHeight float32, viewHeight, aspect, offsetX, offsetY, near, far float64) *Projection {
+	w, h := float64(screenWidth), float64(screenHeight)
+	if !(viewHeight > 0) {
+		viewHeight = h
+	}
+	if aspect <= 0 {
+		aspect = w / h
+	}
+
+	p := &Projection{
+		Width:        w,
+		Height:       h,
+		FocalX:       w / (viewHeight * aspect),
+		FocalY:       h / viewHeight,
+		CenterX:      w/2.0 + offsetX,
+		CenterY:      h/2.0 + offsetY,
+		Orthographic: true,
+	}
+
+	p.Frustum = NewFrustum(near, far, p)
+	return p
+}
+
+// ToScreenX projects a camera-space X coordinate at depth z onto the screen.
+func (p *Projection) ToScreenX(x, z float64) float32 {
+	if p.Orthographic {
+		return float32((p.FocalX * x) + p.CenterX)
+	}
+	return float32(((p.FocalX * x) / z) + p.CenterX)
+}
+
+// ToScreenY projects a camera-space Y coordinate at depth z onto the screen.
+func (p *Projection) ToScreenY(y, z float64) float32 {
+	if p.Orthographic {
+		return float32((p.FocalY * y) + p.CenterY)
+	}
+	return float32(((p.FocalY * y) / z) + p.CenterY)
+}
+
+// Depth converts a camera-space depth into the value handed to depth-aware
+// batchers. It varies linearly across a polygon in screen space and smaller
+// values are nearer the camera: -1/z for perspective projections and z
+// itself for orthographic ones.
+func (p *Projection) Depth(z float64) float32 {
+	if p.Orthographic {
+		return float32(z)
+	}
+	return float32(-1.0 / z)
+}
+
+// perspectiveWeight returns the weight a vertex at camera-space depth z is
+// given when attributes are interpolated perspective-correctly in screen
+// space: 1/z for perspective projections and 1 for orthographic ones.
+func (p *Projection) perspectiveWeight(z float64) float32 {
+	if p.Orthographic {
+		return 1
+	}
+	return float32(1.0 / z)
+}
+
+// FacingAmount returns the dot product of a camera-space face normal with the
+// view ray that reaches point. Positive values mean the face is turned
+// towards the camera. Perspective rays start at the camera origin, while
+// orthographic rays all run along +Z.
+func (p *Projection) FacingAmount(normal, point Vector3) float64 {
+	if p.Orthographic {
+		return normal.Z
+	}
+	return normal.X*point.X + normal.Y*point.Y + normal.Z*point.Z
+}
+
+// EyeSide returns the signed side of a camera-space plane that the eye is on,
+// using the same sign convention as Plane.PointOnPlane.
+func (p *Projection) EyeSide(plane *Plane) float64 {
+	if p.Orthographic {
+		// The eye sits infinitely far back along -Z.
+		return -plane.C
+	}
+	return plane.PointOnPlane(0, 0, 0)
+}
+
+// ray returns the camera space view ray through screen point (sx, sy). Its
+// direction has a Z of 1, so the ray parameter of a point is its depth.
+func (p *Projection) ray(sx, sy float64) (origin, dir Vector3) {
+	u := (sx - p.CenterX) / p.FocalX
+	v := (sy - p.CenterY) / p.FocalY
+	if p.Orthographic {
+		return NewVector3(u, v, 0), NewVector3(0, 0, 1)
+	}
+	return NewVector3(0, 0, 0), NewVector3(u, v, 1)
+}
@@ -0,0 +1,135 @@
+package si3d
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewProjection_Legacy(t *testing.T) {
+	p := NewProjection(320, 240, 0, 0, 0, 0, 10, 0)
+	if p.FocalX != 320 || p.FocalY != 320 {
+		t.Errorf("Legacy focal lengths expected 320, got %f/%f", p.FocalX, p.FocalY)
+	}
+	if p.CenterX != 160 || p.CenterY != 120 {
+		t.Errorf("Legacy principal point expected (160, 120), got (%f, %f)", p.CenterX, p.CenterY)
+	}
+
+	// The legacy projection must match ConvertToScreenX/Y exactly.
+	x, y, z := 12.5, -7.25, 40.0
+	if got, want := p.ToScreenX(x, z), ConvertToScreenX(320, 240, x, z); got != want {
+		t.Errorf("ToScreenX mismatch: got %f, want %f", got, want)
+	}
+	if got, want := p.ToScreenY(y, z), ConvertToScreenY(320, 240, y, z); got != want {
+		t.Errorf("ToScreenY mismatch: got %f, want %f", got, want)
+	}
+}
+
+func TestNewProjection_FieldOfView(t *testing.T) {
+	// A 90 degree vertical FOV puts a point at y == z on the bottom edge.
+	p := NewProjection(400, 200, math.Pi/2, 0, 0, 0, 10, 0)
+	if math.Abs(p.FocalY-100) > 1e-9 {
+		t.Errorf("FocalY expected 100, got %f", p.FocalY)
+	}
+	if math.Abs(p.FocalX-p.FocalY) > 1e-9 {
+		t.Errorf("Default aspect should give square pixels, got %f/%f", p.FocalX, p.FocalY)
+	}
+	if sy := p.ToScreenY(50, 50); math.Abs(float64(sy)-200) > 1e-4 {
+		t.Errorf("ToScreenY expected 200, got %f", sy)
+	}
+
+	// Doubling the aspect ratio halves the horizontal focal length.
+	wide := NewProjection(400, 200, math.Pi/2, 4, 0, 0, 10, 0)
+	if math.Abs(wide.FocalX-p.FocalX/2) > 1e-9 {
+		t.Errorf("FocalX expected %f, got %f", p.FocalX/2, wide.FocalX)
+	}
+}
+
+func TestNewProjection_PrincipalPoint(t *testing.T) {
+	p := NewProjection(200, 100, 0, 0, 15, -5, 10, 0)
+	if p.CenterX != 115 || p.CenterY != 45 {
+		t.Errorf("Principal point expected (115, 45), got (%f, %f)", p.CenterX, p.CenterY)
+	}
+	if sx := p.ToScreenX(0, 100); sx != 115 {
+		t.Errorf("A point on the view axis should project to the principal point, got %f", sx)
+	}
+}
+
+func TestCamera_Projection(t *testing.T) {
+	c := NewCamera(0, 0, 0, 0, 0, 0)
+	c.SetFieldOfViewDegrees(60)
+	c.SetPrincipalPoint(3, 4)
+	c.FarPlane = 1000
+
+	p := c.Projection(640, 480)
+	wantFocal := 240 / math.Tan(degreesToRadians(30))
+	if math.Abs(p.FocalY-wantFocal) > 1e-9 {
+		t.Errorf("FocalY expected %f, got %f", wantFocal, p.FocalY)
+	}
+	if p.CenterX != 323 || p.CenterY != 244 {
+		t.Errorf("Principal point expected (323, 244), got (%f, %f)", p.CenterX, p.CenterY)
+	}
+	if p.Frustum == nil || p.Frustum.Near != c.NearPlane || p.Frustum.Far != 1000 {
+		t.Errorf("Projection frustum does not match camera clip planes: %+v", p.Frustum)
+	}
+}
+
+func TestNewOrthographicProjection(t *testing.T) {
+	p := NewOrthographicProjection(400, 200, 100, 0, 0, 0, 10, 0)
+	if p.FocalX != 2 || p.FocalY != 2 {
+		t.Errorf("expected 2 pixels per unit, got %f/%f", p.FocalX, p.FocalY)
+	}
+
+	// Depth must not affect the projected position.
+	near := p.ToScreenX(25, 20)
+	far := p.ToScreenX(25, 2000)
+	if near != far || near != 250 {
+		t.Errorf("ToScreenX expected 250 at any depth, got %f and %f", near, far)
+	}
+	if sy := p.ToScreenY(-50, 300); sy != 0 {
+		t.Errorf("ToScreenY expected 0, got %f", sy)
+	}
+
+	// Side planes are parallel to the view axis.
+	inside := NewVector3(99, 49, 5000)
+	outside := NewVector3(0, 200, 15)
+	for i, side := range p.Frustum.Sides {
+		if side.signedDistance(inside) < 0 {
+			t.Errorf("side plane %d rejects a point inside the view volume", i)
+		}
+	}
+	if p.Frustum.Sides[3].signedDistance(outside) >= 0 {
+		t.Error("bottom plane accepts a point below the view volume")
+	}
+}
+
+func TestNewOrthographicProjection_BadHeight(t *testing.T) {
+	// Heights that are not positive fall back to one unit per pixel rather
+	// than infinite or NaN focal lengths.
+	for _, h := range []float64{0, -50, math.NaN()} {
+		p := NewOrthographicProjection(400, 200, h, 0, 0, 0, 10, 0)
+		if p.FocalX != 1 || p.FocalY != 1 {
+			t.Errorf("height %v: focal lengths %f/%f, want 1", h, p.FocalX, p.FocalY)
+		}
+	}
+
+	cam := NewCamera(0, 0, -300, 0, 0, 0)
+	cam.SetOrthographic(0)
+	if p := cam.Projection(400, 200); math.IsInf(p.FocalX, 0) || math.IsNaN(p.FocalX) {
+		t.Errorf("SetOrthographic(0) gives focal length %f", p.FocalX)
+	}
+}
+
+func TestProjection_FacingAmount(t *testing.T) {
+	normal := NewVector3(0, 0, 1)
+	point := NewVector3(100, 0, -1)
+
+	persp := NewProjection(100, 100, 0, 0, 0, 0, 10, 0)
+	if persp.FacingAmount(normal, point) >= 0 {
+		t.Error("perspective: expected face to point away from a camera ray towards -Z")
+	}
+
+	ortho := NewOrthographicProjection(100, 100, 100, 0, 0, 0, 10, 0)
+	if ortho.FacingAmount(normal, point) <= 0 {
+		t.Error("orthographic: expected a +Z normal to face the camera")
+	}
+}
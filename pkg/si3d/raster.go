@@ -0,0 +1,198 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// scanCrossing is where a polygon edge crosses the centre of a scanline,
+// with the depth and colour interpolated to that point. For textured
+// polygons u and v are the texture coordinates multiplied by the
+// perspective weight q, which are linear in screen space, and f is the fog
+// visibility.
+type scanCrossing struct {
+	x, z       float32
+	r, g, b, a float32
+	u, v, q    float32
+	f          float32
+}
+
+// fillPolygon fills a polygon command into target using the even-odd rule,
+// sampling at pixel centres. Depth and per-vertex colours are interpolated
+// linearly in screen space, and texture coordinates perspective-correctly.
+// When zbuf is non-nil and the command carries depth, each pixel is depth
+// tested and opaque fills write to zbuf.
+// crossings is scratch space; the possibly grown slice is returned.
+func fillPolygon(target *image.RGBA, cmd polygonCommand, zbuf []float32, crossings []scanCrossing) []scanCrossing {
+	return fillPolygonRows(target, cmd, zbuf, crossings, 0, target.Bounds().Dy())
+}
+
+// fillPolygonRows is fillPolygon restricted to the rows [rowMin, rowMax) of
+// target.
+func fillPolygonRows(target *image.RGBA, cmd polygonCommand, zbuf []float32, crossings []scanCrossing, rowMin, rowMax int) []scanCrossing {
+	width := target.Bounds().Dx()
+
+	minY, maxY := cmd.yp[0], cmd.yp[0]
+	for _, y := range cmd.yp[1:] {
+		minY = min(minY, y)
+		maxY = max(maxY, y)
+	}
+
+	rowStart := max(rowMin, int(math.Ceil(float64(minY)-0.5)))
+	rowEnd := min(rowMax-1, int(math.Ceil(float64(maxY)-0.5))-1)
+	depthTest := zbuf != nil && cmd.zp != nil
+	writeDepth := depthTest && isOpaqueFill(cmd)
+	shaded := cmd.colors != nil
+	textured := cmd.tex != nil
+	n := len(cmd.xp)
+
+	for py := rowStart; py <= rowEnd; py++ {
+		yc := float32(py) + 0.5
+
+		crossings = crossings[:0]
+		for i := 0; i < n; i++ {
+			j := (i + 1) % n
+			yi, yj := cmd.yp[i], cmd.yp[j]
+			if (yi <= yc) == (yj <= yc) {
+				continue
+			}
+			t := (yc - yi) / (yj - yi)
+			c := scanCrossing{x: cmd.xp[i] + t*(cmd.xp[j]-cmd.xp[i])}
+			if cmd.zp != nil {
+				c.z = cmd.zp[i] + t*(cmd.zp[j]-cmd.zp[i])
+			}
+			if shaded {
+				ci, cj := cmd.colors[i], cmd.colors[j]
+				c.r = float32(ci.R) + t*(float32(cj.R)-float32(ci.R))
+				c.g = float32(ci.G) + t*(float32(cj.G)-float32(ci.G))
+				c.b = float32(ci.B) + t*(float32(cj.B)-float32(ci.B))
+				c.a = float32(ci.A) + t*(float32(cj.A)-float32(ci.A))
+			}
+			if textured {
+				qi, qj := cmd.invZ[i], cmd.invZ[j]
+				ui, uj := float32(cmd.uvs[i].U)*qi, float32(cmd.uvs[j].U)*qj
+				vi, vj := float32(cmd.uvs[i].V)*qi, float32(cmd.uvs[j].V)*qj
+				c.q = qi + t*(qj-qi)
+				c.u = ui + t*(uj-ui)
+				c.v = vi + t*(vj-vi)
+				if cmd.fog != nil {
+					c.f = cmd.fog[i] + t*(cmd.fog[j]-cmd.fog[i])
+				}
+			}
+			crossings = append(crossings, c)
+		}
+		sortCrossings(crossings)
+
+		for k := 0; k+1 < len(crossings); k += 2 {
+			left, right := crossings[k], crossings[k+1]
+			colStart := max(0, int(math.Ceil(float64(left.x)-0.5)))
+			colEnd := min(width-1, int(math.Ceil(float64(right.x)-0.5))-1)
+
+			var dx float32
+			if right.x != left.x {
+				dx = 1 / (right.x - left.x)
+			}
+
+			clr := cmd.fillClr
+			for px := colStart; px <= colEnd; px++ {
+				t := (float32(px) + 0.5 - left.x) * dx
+				if depthTest {
+					z := left.z + t*(right.z-left.z)
+					zi := py*width + px
+					if z > zbuf[zi] {
+						continue
+					}
+					if writeDepth {
+						zbuf[zi] = z
+					}
+				}
+				if textured {
+					q := left.q + t*(right.q-left.q)
+					u := (left.u + t*(right.u-left.u)) / q
+					v := (left.v + t*(right.v-left.v)) / q
+					clr = modulate(cmd.tex.sample(u, v), lerpCrossingColor(left, right, t))
+					if cmd.fog != nil {
+						clr = fogBlend(clr, cmd.fogClr, float64(left.f+t*(right.f-left.f)))
+					}
+				} else if shaded {
+					clr = lerpCrossingColor(left, right, t)
+				}
+				blendPixel(target, px, py, clr)
+			}
+		}
+	}
+
+	return crossings
+}
+
+// isOpaqueFill reports whether every pixel of the command's fill is opaque.
+func isOpaqueFill(cmd polygonCommand) bool {
+	if cmd.tex != nil {
+		return cmd.tex.opaque
+	}
+	if cmd.colors == nil {
+		return cmd.fillClr.A == 255
+	}
+	for _, c := range cmd.colors {
+		if c.A != 255 {
+			return false
+		}
+	}
+	return true
+}
+
+func lerpCrossingColor(left, right scanCrossing, t float32) color.RGBA {
+	return color.RGBA{
+		R: uint8(clampUnit(left.r+t*(right.r-left.r)) + 0.5),
+		G: uint8(clampUnit(left.g+t*(right.g-left.g)) + 0.5),
+		B: uint8(clampUnit(left.b+t*(right.b-left.b)) + 0.5),
+		A: uint8(clampUnit(left.a+t*(right.a-left.a)) + 0.5),
+	}
+}
+
+// clampUnit clamps a colour channel to [0, 254.5] so that rounding it
+// cannot overflow a uint8.
+func clampUnit(v float32) float32 {
+	return min(max(v, 0), 254.5)
+}
+
+// sortCrossings sorts the crossings of one scanline by x. The lists are
+// short, so an insertion sort avoids the overhead of sort.Slice.
+func sortCrossings(c []scanCrossing) {
+	for i := 1; i < len(c); i++ {
+		v := c[i]
+		j := i - 1
+		for j >= 0 && c[j].x > v.x {
+			c[j+1] = c[j]
+			j--
+		}
+		c[j+1] = v
+	}
+}
+
+// blendPixel composites an alpha-premultiplied colour over one pixel, as
+// draw.Over does.
+func blendPixel(target *image.RGBA, x, y int, c color.RGBA) {
+	bounds := target.Bounds()
+	i := target.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+	pix := target.Pix[i : i+4 : i+4]
+
+	if c.A == 255 {
+		pix[0], pix[1], pix[2], pix[3] = c.R, c.G, c.B, c.A
+		return
+	}
+
+	inv := 255 - uint32(c.A)
+	pix[0] = uint8(min(255, uint32(c.R)+uint32(pix[0])*inv/255))
+	pix[1] = uint8(min(255, uint32(c.G)+uint32(pix[1])*inv/255))
+	pix[2] = uint8(min(255, uint32(c.B)+uint32(pix[2])*inv/255))
+	pix[3] = uint8(min(255, uint32(c.A)+uint32(pix[3])*inv/255))
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
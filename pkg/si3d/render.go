@@ -5,37 +5,149 @@ import (
 	"image/color"
 	imgdraw "image/draw"
 	"image/png"
+	"io"
 	"os"
 )
 
+// DownsampleFilter selects how a supersampled render is filtered down to
+// its output size.
+type DownsampleFilter int
+
+const (
+	// DownsampleBox averages the samples of each output pixel.
+	DownsampleBox DownsampleFilter = iota
+	// DownsampleLanczos applies a Lanczos-3 filter, which keeps edges
+	// sharper than the box filter at some cost in speed.
+	DownsampleLanczos
+)
+
+// CullMode selects which polygons are drawn by the way they face.
+type CullMode int
+
+const (
+	// CullDefault leaves culling to each model: back faces are skipped
+	// unless the model has SetDrawAllFaces, which BSP models ignore.
+	CullDefault CullMode = iota
+	// CullBackFaces skips polygons facing away from the camera.
+	CullBackFaces
+	// CullNone draws polygons whichever way they face.
+	CullNone
+)
+
+// RenderOptions controls how a world is rendered to an image. The zero
+// RenderOptions renders as the world always has: one sample per pixel, lit,
+// with faint outlines and each model's own culling. nil pointer fields keep
+// their defaults.
+type RenderOptions struct {
+	// Supersample renders the image at Supersample times its size in each
+	// direction and filters it back down, anti-aliasing polygon edges and
+	// outlines. Values below 2 turn supersampling off, and values above
+	// MaxSupersample (8) are reduced to it, as the image painted grows
+	// with the square of the factor. Outline widths and the camera's
+	// principal point offsets keep their size in output pixels.
+	Supersample int
+	// Downsample is the filter the supersampled image is reduced with.
+	Downsample DownsampleFilter
+
+	// OutlineColor is the alpha-premultiplied colour polygon outlines are
+	// drawn in. By default models without a BSP tree are outlined in black
+	// and BSP models in grey, both at an alpha of 25.
+	OutlineColor *color.RGBA
+	// OutlineWidth is the width of polygon outlines and of the lines of
+	// models drawn with SetDrawLinesOnly, in pixels. 0 selects 1.
+	OutlineWidth float32
+	// NoOutlines turns polygon outlines off. Models drawn with
+	// SetDrawLinesOnly still draw their lines.
+	NoOutlines bool
+	// WireframeColor is the colour the lines of models drawn with
+	// SetDrawLinesOnly are drawn in. By default they take the shaded
+	// colour of their polygon.
+	WireframeColor *color.RGBA
+
+	// NoShading turns lighting off, so polygons are filled with their own
+	// colours.
+	NoShading bool
+	// Ambient is the share of the built-in camera light that reaches a
+	// surface whatever its angle, from 0 to 1. It defaults to 0.65. Worlds
+	// with lights use SetAmbientLight instead.
+	Ambient *float64
+	// ShadingMinimum is the lowest value the built-in camera light darkens a
+	// colour channel to. It defaults to 7.
+	ShadingMinimum *uint8
+
+	// Culling selects which polygons are drawn by the way they face.
+	Culling CullMode
+}
+
+// cullBackFaces reports whether back faces are skipped on a model that skips
+// them by default if modelCulls is set.
+func (o *RenderOptions) cullBackFaces(modelCulls bool) bool {
+	switch o.Culling {
+	case CullBackFaces:
+		return true
+	case CullNone:
+		return false
+	}
+	return modelCulls
+}
+
+// renderOptions returns the options passed to a variadic render method, or
+// the zero RenderOptions if there are none.
+func renderOptions(opts []RenderOptions) RenderOptions {
+	if len(opts) == 0 {
+		return RenderOptions{}
+	}
+	return opts[0]
+}
+
+// useOptions makes the options passed to a variadic render method those of
+// the world's render context. It returns them and a function that restores
+// the previous options.
+func (w *World) useOptions(opts []RenderOptions) (RenderOptions, func()) {
+	o := renderOptions(opts)
+	prev := w.ctx.Options
+	w.ctx.Options = o
+	return o, func() { w.ctx.Options = prev }
+}
+
+// paintImage paints the world as seen by cam over img, supersampled if o
+// asks for it.
+func (w *World) paintImage(img *image.RGBA, cam *Camera, o RenderOptions) {
+	if o.Supersample > 1 {
+		w.paintSupersampled(img, cam, min(o.Supersample, MaxSupersample), o.Downsample)
+		return
+	}
+	w.paintCamera(img, cam, img.Bounds().Dx(), img.Bounds().Dy())
+}
+
 // Render draws the current state of the world into a new *image.RGBA
 // of the given dimensions.
 // The background is filled with bgColor before any 3D objects are drawn.
-func (w *World) Render(width, height int, bgColor color.Color) *image.RGBA {
+// At most one RenderOptions may be given.
+func (w *World) Render(width, height int, bgColor color.Color, opts ...RenderOptions) *image.RGBA {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
 
 	// Fast background fill
 	imgdraw.Draw(img, img.Bounds(), &image.Uniform{bgColor}, image.ZP, imgdraw.Src)
 
-	w.PaintObjects(img, width, height)
-	return img
+	return w.RenderToImage(img, opts...)
 }
 
-func (w *World) RenderToImage(img *image.RGBA) *image.RGBA {
-	// img := image.NewRGBA(image.Rect(0, 0, width, height))
-	width := img.Bounds().Dx()
-	height := img.Bounds().Dy()
+// RenderToImage draws the current state of the world over img and returns
+// it. At most one RenderOptions may be given.
+func (w *World) RenderToImage(img *image.RGBA, opts ...RenderOptions) *image.RGBA {
+	o, restore := w.useOptions(opts)
+	defer restore()
 
-	// Fast background fill
-	// imgdraw.Draw(img, img.Bounds(), &image.Uniform{bgColor}, image.ZP, imgdraw.Src)
-
-	w.PaintObjects(img, width, height)
+	if cam := w.CurrentCamera(); cam != nil {
+		w.paintImage(img, cam, o)
+	}
 	return img
 }
 
 // RenderToFile renders the world and writes the result as a PNG file.
-func (w *World) RenderToFile(width, height int, bgColor color.Color, filePath string) error {
-	img := w.Render(width, height, bgColor)
+func (w *World) RenderToFile(width, height int, bgColor color.Color, filePath string, opts ...RenderOptions) error {
+	img := w.Render(width, height, bgColor, opts...)
 
 	f, err := os.Create(filePath)
 	if err != nil {
@@ -45,3 +157,43 @@ func (w *World) RenderToFile(width, height int, bgColor color.Color, filePath st
 
 	return png.Encode(f, img)
 }
+
+// paintWith paints the world through batcher instead of the world's own
+// batcher, which is restored afterwards, with the options passed to a
+// vector render method. Vector batchers ignore the target, so none is
+// passed.
+func (w *World) paintWith(batcher PolygonBatcher, width, height int, opts []RenderOptions) {
+	prev := w.batcher
+	w.batcher = batcher
+	defer func() { w.batcher = prev }()
+	_, restore := w.useOptions(opts)
+	defer restore()
+
+	w.PaintObjects(nil, width, height)
+}
+
+// RenderToSVG renders the world as an SVG document of the given size, with
+// each polygon written as an SVG polygon in the same painter's order as
+// Render. At most one RenderOptions may be given; Supersample and
+// Downsample do not apply to vector output and are ignored.
+func (w *World) RenderToSVG(out io.Writer, width, height int, bgColor color.Color, opts ...RenderOptions) error {
+	batcher := NewSVGBatcher(5000)
+	w.paintWith(batcher, width, height, opts)
+	return batcher.WriteSVG(out, width, height, bgColor)
+}
+
+// RenderToPDF renders the world as a single-page PDF of the given size in
+// points, as RenderToSVG does.
+func (w *World) RenderToPDF(out io.Writer, width, height int, bgColor color.Color, opts ...RenderOptions) error {
+	batcher := NewPDFBatcher(5000)
+	w.paintWith(batcher, width, height, opts)
+	return batcher.WritePDF(out, width, height, bgColor)
+}
+
+// RenderToEPS renders the world as Encapsulated PostScript of the given size
+// in points, as RenderToSVG does.
+func (w *World) RenderToEPS(out io.Writer, width, height int, bgColor color.Color, opts ...RenderOptions) error {
+	batcher := NewEPSBatcher(5000)
+	w.paintWith(batcher, width, height, opts)
+	return batcher.WriteEPS(out, width, height, bgColor)
+}
@@ -123,6 +123,11 @@ func TestRenderGolden(t *testing.T) {
 // buildMountainsScene constructs a deterministic mountains scene using Perlin noise
 // with a fixed seed, mirroring cmd/main.go but at a smaller size for test speed.
 func buildMountainsScene() *image.RGBA {
+	world, bgColor := buildMountainsWorld()
+	return world.Render(goldenMountainsWidth, goldenMountainsHeight, bgColor)
+}
+
+func buildMountainsWorld() (*World, color.RGBA) {
 	mountains := NewSubdividedPlaneHeightMapPerlin(
 		10000, 10000,
 		color.RGBA{R: 153, G: 196, B: 210, A: 255},
@@ -140,8 +145,7 @@ func buildMountainsScene() *image.RGBA {
 	world.AddCamera(cam, camX, -200, camZ)
 	world.AddObjectDrawFirst(&Entity{Model: mountains, X: 0, Y: 0, Z: 0})
 
-	bgColor := color.RGBA{R: 10, G: 10, B: 30, A: 255}
-	return world.Render(goldenMountainsWidth, goldenMountainsHeight, bgColor)
+	return world, color.RGBA{R: 10, G: 10, B: 30, A: 255}
 }
 
 // TestRenderGoldenMountains is an E2E golden image regression test for the mountains scene.
@@ -0,0 +1,225 @@
+package si3d
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestRenderOptions_Defaults(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		model *Model
+	}{
+		{"face list", NewCube()},
+		{"BSP", newBSPCube()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plain := renderEntities(&Entity{Model: tc.model})
+			if !compareImages(t, renderWorld(newTestWorld(&Entity{Model: tc.model}), RenderOptions{}), plain) {
+				t.Error("zero RenderOptions changed the render")
+			}
+		})
+	}
+}
+
+func TestRenderOptions_Outlines(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	for _, tc := range []struct {
+		name  string
+		model *Model
+	}{
+		{"face list", NewCube()},
+		{"BSP", newBSPCube()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plain := renderEntities(&Entity{Model: tc.model})
+
+			// Both paths draw the themed outline, so they agree along the
+			// front face's top edge.
+			got := renderWorld(newTestWorld(&Entity{Model: tc.model}), RenderOptions{OutlineColor: &red, OutlineWidth: 3})
+			var edge image.Point
+			for y := 0; y < 75; y++ {
+				if plain.RGBAAt(100, y) != plain.RGBAAt(100, 0) {
+					edge = image.Pt(100, y)
+					break
+				}
+			}
+			if p := got.RGBAAt(edge.X, edge.Y); p.R != 255 || p.G > 100 {
+				t.Errorf("outline at %v is %v, want red", edge, p)
+			}
+
+			none := renderWorld(newTestWorld(&Entity{Model: tc.model}), RenderOptions{NoOutlines: true})
+			if differingPixels(none, plain) == 0 {
+				t.Error("NoOutlines did not change the render")
+			}
+		})
+	}
+}
+
+func TestRenderOptions_Shading(t *testing.T) {
+	base := color.RGBA{R: 200, G: 120, B: 40, A: 255}
+	box := func() *Entity { return &Entity{Model: NewRectangle(100, 100, 100, base)} }
+
+	got := renderWorld(newTestWorld(box()), RenderOptions{NoShading: true, NoOutlines: true})
+	if p := got.RGBAAt(100, 75); p != base {
+		t.Errorf("unshaded face is %v, want %v", p, base)
+	}
+
+	// With all of the built-in light ambient every face is fully lit.
+	full := 1.0
+	got = renderWorld(newTestWorld(box()), RenderOptions{Ambient: &full, NoOutlines: true})
+	if p := got.RGBAAt(100, 75); p != base {
+		t.Errorf("fully ambient face is %v, want %v", p, base)
+	}
+
+	// Darkened channels stop at the shading minimum.
+	none := 0.0
+	minimum := uint8(60)
+	got = renderWorld(newTestWorld(&Entity{Model: NewRectangle(100, 100, 100, color.RGBA{R: 255, A: 255})}),
+		RenderOptions{Ambient: &none, ShadingMinimum: &minimum, NoOutlines: true})
+	if p := got.RGBAAt(100, 75); p.G != minimum || p.B != minimum {
+		t.Errorf("dark face is %v, want G and B at %d", p, minimum)
+	}
+}
+
+func TestRenderOptions_Culling(t *testing.T) {
+	// Seen from inside a box every face faces away from the camera.
+	inside := func(model *Model, opts RenderOptions) *image.RGBA {
+		w := NewWorld3d()
+		w.AddCamera(NewCamera(0, 0, 0, 0, 0, 0), 0, 0, 0)
+		w.AddObject(&Entity{Model: model})
+		return w.Render(200, 150, color.RGBA{A: 255}, opts)
+	}
+	drawn := func(img *image.RGBA) bool {
+		return img.RGBAAt(100, 75) != (color.RGBA{A: 255})
+	}
+
+	for _, tc := range []struct {
+		name  string
+		model func() *Model
+	}{
+		{"face list", NewCube},
+		{"BSP", newBSPCube},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if drawn(inside(tc.model(), RenderOptions{})) {
+				t.Error("back faces drawn by default")
+			}
+			if !drawn(inside(tc.model(), RenderOptions{Culling: CullNone})) {
+				t.Error("CullNone did not draw back faces")
+			}
+		})
+	}
+
+	allFaces := NewCube()
+	allFaces.SetDrawAllFaces(true)
+	if !drawn(inside(allFaces, RenderOptions{})) {
+		t.Error("SetDrawAllFaces model culled by default")
+	}
+	if drawn(inside(allFaces, RenderOptions{Culling: CullBackFaces})) {
+		t.Error("CullBackFaces drew back faces of a SetDrawAllFaces model")
+	}
+}
+
+func TestRenderOptions_PickCulling(t *testing.T) {
+	// From inside a box, the render draws nothing by default, so there is
+	// nothing to pick, unless the pick is given the render's CullNone.
+	for _, tc := range []struct {
+		name  string
+		model func() *Model
+	}{
+		{"face list", NewCube},
+		{"BSP", newBSPCube},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewWorld3d()
+			w.AddCamera(NewCamera(0, 0, 0, 0, 0, 0), 0, 0, 0)
+			w.AddObject(&Entity{Model: tc.model()})
+			if hit := w.Pick(100, 75, 200, 150); hit != nil {
+				t.Errorf("picked a back face by default: %+v", hit)
+			}
+			hit := w.Pick(100, 75, 200, 150, RenderOptions{Culling: CullNone})
+			if hit == nil || hit.Point.Z != 40 {
+				t.Errorf("CullNone pick = %+v, want the back wall at z 40", hit)
+			}
+			if w.ctx.Options.Culling != CullDefault {
+				t.Error("Pick left its options set")
+			}
+		})
+	}
+}
+
+func TestRenderOptions_VectorOutput(t *testing.T) {
+	w := NewWorld3d()
+	w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+	w.AddObject(&Entity{Model: NewCube()})
+
+	var plain, none bytes.Buffer
+	if err := w.RenderToSVG(&plain, 200, 150, color.RGBA{A: 255}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.RenderToSVG(&none, 200, 150, color.RGBA{A: 255}, RenderOptions{NoOutlines: true, Supersample: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plain.String(), ` stroke="`) {
+		t.Error("default SVG has no outlines")
+	}
+	if strings.Contains(none.String(), ` stroke="`) {
+		t.Error("NoOutlines SVG has outlines")
+	}
+	if w.ctx.Options.NoOutlines {
+		t.Error("RenderToSVG left its options set")
+	}
+}
+
+func TestRenderOptions_Viewports(t *testing.T) {
+	w := NewWorld3d()
+	cam := NewCamera(0, 0, -300, 0, 0, 0)
+	w.AddCamera(cam, 0, 0, -300)
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(0, 1, 0), 0.4)
+	w.AddObject(&Entity{Model: cube})
+
+	bg := color.RGBA{A: 255}
+	opts := RenderOptions{NoShading: true, Supersample: 2}
+	got := w.RenderViewports(200, 150, bg, []Viewport{{Camera: cam, Rect: image.Rect(0, 0, 200, 150)}}, opts)
+	if !compareImages(t, got, w.Render(200, 150, bg, opts)) {
+		t.Error("viewport differs from a render with the same options")
+	}
+	if differingPixels(got, w.Render(200, 150, bg)) == 0 {
+		t.Error("options did not change the viewport")
+	}
+}
+
+func TestRenderOptions_WireframeColor(t *testing.T) {
+	green := color.RGBA{G: 255, A: 255}
+	model := NewRectangle(100, 100, 100, color.RGBA{R: 255, A: 255})
+	model.SetDrawLinesOnly(true)
+
+	got := renderWorld(newTestWorld(&Entity{Model: model}), RenderOptions{WireframeColor: &green})
+	var greenLines, redLines int
+	for i := 0; i < len(got.Pix); i += 4 {
+		if got.Pix[i+1] > 128 {
+			greenLines++
+		}
+		if got.Pix[i] > 0 {
+			redLines++
+		}
+	}
+	if greenLines == 0 || redLines != 0 {
+		t.Errorf("%d green and %d red line pixels, want only green", greenLines, redLines)
+	}
+}
+
+func TestWorld_RenderRestoresOptions(t *testing.T) {
+	w := NewWorld3d()
+	w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+	w.AddObject(&Entity{Model: NewCube()})
+	w.Render(50, 50, color.RGBA{A: 255}, RenderOptions{NoShading: true, Supersample: 2})
+	if w.ctx.Options.NoShading || w.ctx.pixelScale != 1 {
+		t.Error("render options left set after Render")
+	}
+}
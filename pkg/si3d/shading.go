@@ -0,0 +1,187 @@
+package si3d
+
+import (
+	"image/color"
+	"math"
+)
+
+// ShadingMode selects how lit colours are applied across a polygon.
+type ShadingMode int
+
+const (
+	// ShadingFlat lights each polygon once using its face normal.
+	ShadingFlat ShadingMode = iota
+	// ShadingGouraud lights each vertex using its vertex normal and
+	// interpolates the colours across the polygon.
+	ShadingGouraud
+)
+
+// DefaultCreaseAngle is the crease angle, in radians, used when Gouraud
+// shading is enabled on a model without vertex normals.
+const DefaultCreaseAngle = 60 * math.Pi / 180
+
+// SetShadingMode selects flat or Gouraud shading. Enabling Gouraud shading on
+// a model without vertex normals computes them with DefaultCreaseAngle.
+func (o *Model) SetShadingMode(mode ShadingMode) {
+	o.shadingMode = mode
+	if mode == ShadingGouraud && !o.hasVertexNormals {
+		o.ComputeVertexNormals(DefaultCreaseAngle)
+	}
+}
+
+func (o *Model) GetShadingMode() ShadingMode {
+	return o.shadingMode
+}
+
+// ComputeVertexNormals gives every polygon corner a normal averaged from the
+// faces that share the vertex. Faces whose normals differ from the corner's
+// own face normal by more than creaseAngle radians are left out, so hard
+// edges stay sharp. The model must be compiled, and clones made earlier do
+// not see the new normals.
+func (o *Model) ComputeVertexNormals(creaseAngle float64) {
+	if o.faceMesh == nil || o.normalMesh == nil {
+		return
+	}
+
+	// Each polygon as corner point indices plus a face normal index, taken
+	// from the BSP tree or the face list.
+	var polys [][]int
+	var polyNormals []int
+	var nodes []*BspNode
+	if o.canPaintWithoutBSP {
+		polys = o.faceIndices
+		polyNormals = o.normalIndices
+	} else {
+		var collect func(n *BspNode)
+		collect = func(n *BspNode) {
+			if n == nil {
+				return
+			}
+			if len(n.facePointIndices) > 0 {
+				nodes = append(nodes, n)
+				polys = append(polys, n.facePointIndices)
+				polyNormals = append(polyNormals, n.normalIndex)
+			}
+			collect(n.Left)
+			collect(n.Right)
+		}
+		collect(o.root)
+	}
+
+	// Group the face normals around each vertex. Vertices are matched by
+	// position so that seams and BSP splits are still smoothed.
+	adjacent := make(map[vertexKey][]Vector3)
+	for i, poly := range polys {
+		normal := o.normalMesh.Points[polyNormals[i]]
+		for _, pointIndex := range poly {
+			key := newVertexKey(o.faceMesh.Points[pointIndex])
+			adjacent[key] = append(adjacent[key], normal)
+		}
+	}
+
+	cosCrease := math.Cos(creaseAngle)
+	vertexNormalIndices := make([][]int, len(polys))
+	for i, poly := range polys {
+		faceNormal := o.normalMesh.Points[polyNormals[i]]
+		indices := make([]int, len(poly))
+		for k, pointIndex := range poly {
+			var sum Vector3
+			for _, n := range adjacent[newVertexKey(o.faceMesh.Points[pointIndex])] {
+				if Dot(n, faceNormal) >= cosCrease {
+					sum = sum.Add(n)
+				}
+			}
+			if GetLength2(sum) < 1e-12 {
+				sum = faceNormal
+			}
+			_, indices[k] = o.normalMesh.AddNormal(sum.Normalize())
+		}
+		vertexNormalIndices[i] = indices
+	}
+
+	if o.canPaintWithoutBSP {
+		o.vertexNormalIndices = vertexNormalIndices
+	} else {
+		for i, n := range nodes {
+			n.vertexNormalIndices = vertexNormalIndices[i]
+		}
+	}
+
+	o.transNormalMesh = o.normalMesh.Copy()
+	o.hasVertexNormals = true
+}
+
+// setFaceVertexNormals installs vertex normals supplied per corner of each
+// face, in the order the faces were added, on a compiled model without a BSP
+// tree. Normals are flipped where needed to agree with their face normal.
+func (o *Model) setFaceVertexNormals(normals [][]Vector3) {
+	if !o.canPaintWithoutBSP || len(normals) != len(o.faceIndices) {
+		return
+	}
+
+	vertexNormalIndices := make([][]int, len(normals))
+	for i, faceNormals := range normals {
+		faceNormal := o.normalMesh.Points[o.normalIndices[i]]
+		indices := make([]int, len(faceNormals))
+		for k, n := range faceNormals {
+			if Dot(n, faceNormal) < 0 {
+				n = NewVector3(-n.X, -n.Y, -n.Z)
+			}
+			_, indices[k] = o.normalMesh.AddNormal(n)
+		}
+		vertexNormalIndices[i] = indices
+	}
+
+	o.vertexNormalIndices = vertexNormalIndices
+	o.transNormalMesh = o.normalMesh.Copy()
+	o.hasVertexNormals = true
+}
+
+// vertexKey identifies a vertex position, rounded so that points generated
+// by different faces at the same place compare equal.
+type vertexKey [3]int64
+
+func newVertexKey(v Vector3) vertexKey {
+	const scale = 1e6
+	return vertexKey{
+		int64(math.Round(v.X * scale)),
+		int64(math.Round(v.Y * scale)),
+		int64(math.Round(v.Z * scale)),
+	}
+}
+
+// shadeVertices lights every corner of a polygon with its camera-space vertex
+// normal and fades it into the fog, writing the colours into the attribute
+// buffer of ctx.
+func shadeVertices(points []Vector3, normalIndices []int, transNormals []Vector3, base color.RGBA, ctx *RenderContext) []vertexAttrs {
+	attrs := ctx.vertexAttrs[:0]
+	for i, point := range points {
+		c := ctx.shade(point, transNormals[normalIndices[i]], base)
+		c = ctx.fog.apply(c, point.Z)
+		attrs = append(attrs, vertexAttrs{r: float64(c.R), g: float64(c.G), b: float64(c.B), a: float64(c.A)})
+	}
+	ctx.vertexAttrs = attrs
+	return attrs
+}
+
+// addGouraudPolygon frustum clips a polygon with shaded vertex colours and
+// adds it to the batcher.
+func addGouraudPolygon(batcher PolygonBatcher, points []Vector3, attrs []vertexAttrs, proj *Projection, screenWidth, screenHeight float32, strokeClr color.RGBA, strokeWidth float32, hasStroke bool, ctx *RenderContext) {
+	clipped, clippedAttrs := proj.Frustum.clipPolygonAttrs(points, attrs, ctx)
+	if len(clipped) < 3 {
+		return
+	}
+
+	colors := ctx.vertexColors[:0]
+	for _, a := range clippedAttrs {
+		colors = append(colors, color.RGBA{
+			R: uint8(math.Round(a.r)),
+			G: uint8(math.Round(a.g)),
+			B: uint8(math.Round(a.b)),
+			A: uint8(math.Round(a.a)),
+		})
+	}
+	ctx.vertexColors = colors
+
+	addShadedPolygon(batcher, clipped, colors, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
+}
@@ -0,0 +1,124 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestModel_ComputeVertexNormals_Crease(t *testing.T) {
+	cube := NewCube()
+	cube.ComputeVertexNormals(DefaultCreaseAngle)
+
+	if len(cube.vertexNormalIndices) != len(cube.faceIndices) {
+		t.Fatalf("vertex normal faces = %d; want %d", len(cube.vertexNormalIndices), len(cube.faceIndices))
+	}
+
+	// The cube's edges are sharper than the crease angle, so every corner
+	// keeps its face normal.
+	for i, indices := range cube.vertexNormalIndices {
+		faceNormal := cube.normalMesh.Points[cube.normalIndices[i]]
+		for _, ni := range indices {
+			if n := cube.normalMesh.Points[ni]; Dot(n, faceNormal) < 1-1e-9 {
+				t.Errorf("face %d corner normal %v; want face normal %v", i, n, faceNormal)
+			}
+		}
+	}
+}
+
+func TestModel_ComputeVertexNormals_Smooth(t *testing.T) {
+	cube := NewCube()
+	cube.ComputeVertexNormals(math.Pi)
+
+	// With no crease every corner averages the three faces meeting there,
+	// so each normal points out along a diagonal.
+	want := 1 / math.Sqrt(3)
+	for i, indices := range cube.vertexNormalIndices {
+		for _, ni := range indices {
+			n := cube.normalMesh.Points[ni]
+			if math.Abs(math.Abs(n.X)-want) > 1e-9 || math.Abs(math.Abs(n.Y)-want) > 1e-9 || math.Abs(math.Abs(n.Z)-want) > 1e-9 {
+				t.Errorf("face %d corner normal %v; want a unit diagonal", i, n)
+			}
+		}
+	}
+
+	if len(cube.transNormalMesh.Points) != len(cube.normalMesh.Points) {
+		t.Errorf("transNormalMesh has %d normals; want %d", len(cube.transNormalMesh.Points), len(cube.normalMesh.Points))
+	}
+}
+
+func TestModel_SetShadingMode(t *testing.T) {
+	m := NewUVSphere(50, 8, 6, color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}, 2)
+	if m.GetShadingMode() != ShadingFlat {
+		t.Errorf("default shading mode = %v; want ShadingFlat", m.GetShadingMode())
+	}
+
+	m.SetShadingMode(ShadingGouraud)
+	if m.GetShadingMode() != ShadingGouraud {
+		t.Errorf("shading mode = %v; want ShadingGouraud", m.GetShadingMode())
+	}
+	if !m.hasVertexNormals || m.vertexNormalIndices == nil {
+		t.Error("SetShadingMode(ShadingGouraud) did not compute vertex normals")
+	}
+}
+
+func TestModel_GouraudBSP(t *testing.T) {
+	m := NewModel()
+	m.AddFacesFromObject(NewCube())
+	m.BuildBSP()
+	m.Compile()
+
+	m.ComputeVertexNormals(math.Pi)
+	if m.root == nil {
+		t.Fatal("model has no BSP tree")
+	}
+
+	var check func(n *BspNode)
+	check = func(n *BspNode) {
+		if n == nil {
+			return
+		}
+		if len(n.vertexNormalIndices) != len(n.facePointIndices) {
+			t.Errorf("node has %d vertex normals for %d corners", len(n.vertexNormalIndices), len(n.facePointIndices))
+		}
+		check(n.Left)
+		check(n.Right)
+	}
+	check(m.root)
+}
+
+func TestWorld_PaintObjectsGouraud(t *testing.T) {
+	const size = 200
+
+	render := func(mode ShadingMode) *image.RGBA {
+		w := NewWorld3d()
+		w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+		m := NewUVSphere(80, 16, 12, color.RGBA{R: 200, G: 200, B: 200, A: 255}, color.RGBA{R: 200, G: 200, B: 200, A: 255}, 0)
+		m.SetShadingMode(mode)
+		w.AddObject(&Entity{Model: m})
+		w.SetAmbientLight(color.RGBA{A: 255})
+		w.AddLight(NewDirectionalLight(NewVector3(1, 0, 1), color.RGBA{R: 255, G: 255, B: 255, A: 255}, 1))
+
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		w.PaintObjects(img, size, size)
+		return img
+	}
+
+	// The largest brightness step between neighbouring pixels inside the
+	// sphere. Flat shading jumps at every facet edge.
+	maxStep := func(img *image.RGBA) int {
+		step := 0
+		for x := 61; x < 140; x++ {
+			a, b := int(img.RGBAAt(x-1, size/2-10).R), int(img.RGBAAt(x, size/2-10).R)
+			step = max(step, a-b, b-a)
+		}
+		return step
+	}
+
+	flat := maxStep(render(ShadingFlat))
+	smooth := maxStep(render(ShadingGouraud))
+	if smooth*2 > flat {
+		t.Errorf("largest Gouraud step %d, flat %d; want Gouraud much smoother", smooth, flat)
+	}
+}
@@ -0,0 +1,156 @@
+package si3d
+
+import (
+	"image"
+	"math"
+)
+
+// lanczosLobes is the support of the Lanczos filter, in output pixels either
+// side of a pixel's centre.
+const lanczosLobes = 3
+
+// MaxSupersample is the largest RenderOptions.Supersample factor; larger
+// ones are reduced to it. At 8 a 1920x1080 render already paints a
+// 15360x8640 image of about 530MB.
+const MaxSupersample = 8
+
+// paintSupersampled paints the world as seen by cam over img at n times its
+// size in each direction and filters the result back down into img.
+func (w *World) paintSupersampled(img *image.RGBA, cam *Camera, n int, filter DownsampleFilter) {
+	bounds := img.Bounds()
+	big := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*n, bounds.Dy()*n))
+	upsample(big, img, n)
+
+	prev := w.ctx.pixelScale
+	w.ctx.pixelScale = w.ctx.scale() * float32(n)
+	defer func() { w.ctx.pixelScale = prev }()
+
+	w.paintCamera(big, cam, big.Bounds().Dx(), big.Bounds().Dy())
+
+	switch filter {
+	case DownsampleLanczos:
+		downsampleLanczos(img, big, n)
+	default:
+		downsampleBox(img, big, n)
+	}
+}
+
+// upsample fills dst, n times the size of src, with each pixel of src
+// repeated n by n times.
+func upsample(dst, src *image.RGBA, n int) {
+	bounds := src.Bounds()
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		srcRow := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y/n):]
+		dstRow := dst.Pix[dst.PixOffset(0, y):]
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			copy(dstRow[x*4:x*4+4], srcRow[(x/n)*4:(x/n)*4+4])
+		}
+	}
+}
+
+// downsampleBox sets each pixel of dst to the average of the n by n block of
+// src it covers.
+func downsampleBox(dst, src *image.RGBA, n int) {
+	bounds := dst.Bounds()
+	count := uint32(n * n)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			var sum [4]uint32
+			for sy := y * n; sy < (y+1)*n; sy++ {
+				row := src.Pix[src.PixOffset(x*n, sy):]
+				for i := 0; i < n*4; i += 4 {
+					sum[0] += uint32(row[i])
+					sum[1] += uint32(row[i+1])
+					sum[2] += uint32(row[i+2])
+					sum[3] += uint32(row[i+3])
+				}
+			}
+			pix := dst.Pix[dst.PixOffset(bounds.Min.X+x, bounds.Min.Y+y):]
+			for c := 0; c < 4; c++ {
+				pix[c] = uint8((sum[c] + count/2) / count)
+			}
+		}
+	}
+}
+
+// downsampleLanczos reduces src to dst, n times smaller, with a separable
+// Lanczos-3 filter. Samples beyond the edges repeat the edge pixels. The
+// filter rings next to hard edges, so results are clamped to valid
+// premultiplied colours.
+func downsampleLanczos(dst, src *image.RGBA, n int) {
+	bounds := dst.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	srcWidth, srcHeight := width*n, height*n
+
+	// The output pixel centres fall at the same place within every block of
+	// n samples, so one set of weights serves every pixel.
+	offset, weights := lanczosWeights(n)
+
+	// Filter the rows, then the columns of the result.
+	rows := make([]float32, width*srcHeight*4)
+	for sy := 0; sy < srcHeight; sy++ {
+		row := src.Pix[src.PixOffset(0, sy):]
+		for x := 0; x < width; x++ {
+			var acc [4]float32
+			for k, wt := range weights {
+				sx := min(max(x*n+offset+k, 0), srcWidth-1)
+				for c := 0; c < 4; c++ {
+					acc[c] += wt * float32(row[sx*4+c])
+				}
+			}
+			copy(rows[(sy*width+x)*4:], acc[:])
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var acc [4]float32
+			for k, wt := range weights {
+				sy := min(max(y*n+offset+k, 0), srcHeight-1)
+				i := (sy*width + x) * 4
+				for c := 0; c < 4; c++ {
+					acc[c] += wt * rows[i+c]
+				}
+			}
+			a := min(max(acc[3], 0), 255)
+			pix := dst.Pix[dst.PixOffset(bounds.Min.X+x, bounds.Min.Y+y):]
+			for c := 0; c < 3; c++ {
+				pix[c] = uint8(min(max(acc[c], 0), a) + 0.5)
+			}
+			pix[3] = uint8(a + 0.5)
+		}
+	}
+}
+
+// lanczosWeights returns the normalised Lanczos-3 weights for reducing by a
+// factor of n, and the offset of the first weight's sample from the start
+// of an output pixel's block of samples.
+func lanczosWeights(n int) (int, []float32) {
+	offset := -lanczosLobes*n + (n+1)/2
+	var weights []float32
+	var sum float64
+	for k := offset; k < lanczosLobes*n+n/2; k++ {
+		// Distance of the sample's centre from the output pixel's centre,
+		// in output pixels.
+		d := (float64(k) + 0.5 - float64(n)/2) / float64(n)
+		wt := lanczos(d)
+		weights = append(weights, float32(wt))
+		sum += wt
+	}
+	for i := range weights {
+		weights[i] /= float32(sum)
+	}
+	return offset, weights
+}
+
+// lanczos is the Lanczos-3 kernel.
+func lanczos(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	if math.Abs(x) >= lanczosLobes {
+		return 0
+	}
+	px := math.Pi * x
+	return lanczosLobes * math.Sin(px) * math.Sin(px/lanczosLobes) / (px * px)
+}
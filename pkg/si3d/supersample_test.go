@@ -0,0 +1,149 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestDownsampleBox(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			src.SetRGBA(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	src.SetRGBA(2, 0, color.RGBA{G: 100, A: 100})
+
+	dst := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	downsampleBox(dst, src, 2)
+	if got, want := dst.RGBAAt(0, 0), (color.RGBA{R: 200, A: 255}); got != want {
+		t.Errorf("covered pixel = %v, want %v", got, want)
+	}
+	if got, want := dst.RGBAAt(1, 0), (color.RGBA{G: 25, A: 25}); got != want {
+		t.Errorf("quarter covered pixel = %v, want %v", got, want)
+	}
+}
+
+func TestDownsampleLanczos(t *testing.T) {
+	// A flat image stays flat, edges included.
+	flat := color.RGBA{R: 30, G: 60, B: 90, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 12, 9))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] = flat.R, flat.G, flat.B, flat.A
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	downsampleLanczos(dst, src, 3)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			if got := dst.RGBAAt(x, y); got != flat {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, flat)
+			}
+		}
+	}
+
+	for _, n := range []int{2, 3, 4} {
+		_, weights := lanczosWeights(n)
+		var sum float64
+		for i, wt := range weights {
+			sum += float64(wt)
+			if mirror := weights[len(weights)-1-i]; math.Abs(float64(wt-mirror)) > 1e-6 {
+				t.Errorf("n = %d: weights %v not symmetric", n, weights)
+				break
+			}
+		}
+		if math.Abs(sum-1) > 1e-5 {
+			t.Errorf("n = %d: weights sum to %v", n, sum)
+		}
+	}
+}
+
+func TestWorld_RenderSupersampled(t *testing.T) {
+	// A turned, textured cube, whose edges the scanline fill leaves jagged.
+	cube := NewCube()
+	cube.SetTexture(newTopBottomTexture())
+	e := NewEntity(cube)
+	e.Transform.Rotate(NewVector3(0, 1, 0), 0.4)
+	e.Transform.Rotate(NewVector3(1, 0, 0), 0.3)
+
+	render := func(opts ...RenderOptions) *image.RGBA {
+		return renderWorld(newTestWorld(e), opts...)
+	}
+	plain := render()
+
+	if !compareImages(t, render(RenderOptions{Supersample: 1}), plain) {
+		t.Error("a supersample factor of 1 changed the render")
+	}
+
+	// Factors above the cap render as the cap, rather than painting a
+	// 200000x150000 image.
+	if !compareImages(t, render(RenderOptions{Supersample: 1000}), render(RenderOptions{Supersample: MaxSupersample})) {
+		t.Error("a supersample factor above MaxSupersample was not capped")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter DownsampleFilter
+	}{{"box", DownsampleBox}, {"Lanczos", DownsampleLanczos}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := render(RenderOptions{Supersample: 4, Downsample: tc.filter})
+			if got.Bounds() != plain.Bounds() {
+				t.Fatalf("bounds %v, want %v", got.Bounds(), plain.Bounds())
+			}
+
+			// The cube covers the same area, give or take its edge pixels,
+			// which are now blended with the background.
+			var covered, plainCovered, partial int
+			for i := 0; i < len(got.Pix); i += 4 {
+				if plain.Pix[i] != 0 || plain.Pix[i+1] != 0 || plain.Pix[i+2] != 0 {
+					plainCovered++
+				}
+				if got.Pix[i] != 0 || got.Pix[i+1] != 0 || got.Pix[i+2] != 0 {
+					covered++
+				}
+				if diff := int(got.Pix[i]) - int(plain.Pix[i]); diff > 8 || diff < -8 {
+					partial++
+				}
+			}
+			if math.Abs(float64(covered-plainCovered)) > float64(plainCovered)/10 {
+				t.Errorf("supersampled cube covers %d pixels, %d without", covered, plainCovered)
+			}
+			if partial == 0 {
+				t.Error("supersampling did not change any edge pixels")
+			}
+			if p, want := got.RGBAAt(100, 65), plain.RGBAAt(100, 65); math.Abs(float64(p.R)-float64(want.R)) > 8 {
+				t.Errorf("centre pixel %v, want about %v", p, want)
+			}
+		})
+	}
+}
+
+func TestWorld_RenderSupersampledOffset(t *testing.T) {
+	// The principal point offset is in output pixels, so the supersampled
+	// cube lands in the same place.
+	centre := func(img *image.RGBA) float64 {
+		var sum, count float64
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			for x := 0; x < img.Bounds().Dx(); x++ {
+				if p := img.RGBAAt(x, y); p.R != 0 || p.G != 0 || p.B != 0 {
+					sum += float64(x)
+					count++
+				}
+			}
+		}
+		return sum / count
+	}
+
+	cam := NewCamera(0, 0, -300, 0, 0, 0)
+	cam.PrincipalOffsetX = 30
+	w := NewWorld3d()
+	w.AddCamera(cam, 0, 0, -300)
+	w.AddObject(&Entity{Model: NewCube()})
+
+	plain := centre(w.Render(200, 150, color.RGBA{A: 255}))
+	got := centre(w.Render(200, 150, color.RGBA{A: 255}, RenderOptions{Supersample: 3}))
+	if math.Abs(got-plain) > 1 {
+		t.Errorf("supersampled cube centred at x = %v, want %v", got, plain)
+	}
+}
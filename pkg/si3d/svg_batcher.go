@@ -0,0 +1,93 @@
+package si3d
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// SVGBatcher is a PolygonBatcher that records polygons as SVG elements
+// instead of rasterising them. Each Draw appends the batch, in draw order,
+// to the document; WriteSVG writes it out. Polygons are clipped to the
+// screen as by DefaultBatcher, so the SVG is a vector equivalent of the
+// raster render. Gouraud shaded and textured polygons are filled with their
+// average colour, as SVG cannot interpolate colours across a polygon.
+type SVGBatcher struct {
+	vectorBatcher
+}
+
+func NewSVGBatcher(initialCap int) *SVGBatcher {
+	return &SVGBatcher{vectorBatcher: newVectorBatcher(initialCap)}
+}
+
+// WriteSVG writes an SVG document of the given size holding every polygon
+// drawn since the last call, over a background of bg, and then clears the
+// document.
+func (b *SVGBatcher) WriteSVG(w io.Writer, width, height int, bg color.Color) error {
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	if bg != nil {
+		doc.WriteString(`<rect width="100%" height="100%"`)
+		writeSVGPaint(&doc, "fill", color.RGBAModel.Convert(bg).(color.RGBA))
+		doc.WriteString("/>\n")
+	}
+	// gg, used by DefaultBatcher, strokes with round joins and caps.
+	doc.WriteString(`<g stroke-linejoin="round" stroke-linecap="round">` + "\n")
+
+	for _, cmd := range b.takeDocument(width, height) {
+		writeSVGPolygon(&doc, cmd)
+	}
+	if _, err := w.Write(doc.Bytes()); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "</g>\n</svg>\n")
+	return err
+}
+
+// writeSVGPolygon writes cmd as an SVG polygon element.
+func writeSVGPolygon(buf *bytes.Buffer, cmd polygonCommand) {
+	if len(cmd.xp) == 0 {
+		return
+	}
+
+	buf.WriteString(`<polygon points="`)
+	for i := range cmd.xp {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(formatCoord(cmd.xp[i]))
+		buf.WriteByte(',')
+		buf.WriteString(formatCoord(cmd.yp[i]))
+	}
+	buf.WriteByte('"')
+
+	if cmd.hasFill {
+		writeSVGPaint(buf, "fill", cmd.flatFill())
+	} else {
+		buf.WriteString(` fill="none"`)
+	}
+
+	if cmd.hasStroke {
+		writeSVGPaint(buf, "stroke", cmd.strokeClr)
+		fmt.Fprintf(buf, ` stroke-width="%s"`, formatCoord(cmd.strokeW))
+	}
+	buf.WriteString("/>\n")
+}
+
+// writeSVGPaint writes a fill or stroke attribute for a premultiplied
+// colour, with an opacity attribute when it is translucent.
+func writeSVGPaint(buf *bytes.Buffer, attr string, c color.RGBA) {
+	if c.A == 0 {
+		fmt.Fprintf(buf, ` %s="none"`, attr)
+		return
+	}
+
+	c = unpremultiply(c)
+	fmt.Fprintf(buf, ` %s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+	if c.A < 255 {
+		fmt.Fprintf(buf, ` %s-opacity="%s"`, attr, strconv.FormatFloat(float64(c.A)/255, 'f', 4, 64))
+	}
+}
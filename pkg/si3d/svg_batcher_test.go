@@ -0,0 +1,164 @@
+package si3d
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSVGBatcher_Polygons(t *testing.T) {
+	b := NewSVGBatcher(10)
+	var _ ShadedPolygonBatcher = b
+
+	xp, yp := square(1, 2, 10.5, 20)
+	b.AddPolygon(xp, yp, color.RGBA{R: 255, A: 255})
+	b.AddPolygonOutline(xp, yp, 2, color.RGBA{G: 64, A: 128})
+	b.AddPolygonAndOutline(xp, yp, color.RGBA{B: 255, A: 255}, color.RGBA{A: 255}, 0.5)
+	b.AddShadedPolygon(xp, yp, nil, []color.RGBA{{R: 200, A: 255}, {R: 100, A: 255}, {R: 200, A: 255}, {R: 100, A: 255}}, color.RGBA{}, 0, false)
+	b.Draw(nil)
+
+	var buf bytes.Buffer
+	if err := b.WriteSVG(&buf, 32, 24, color.RGBA{R: 16, G: 32, B: 48, A: 255}); err != nil {
+		t.Fatalf("WriteSVG: %v", err)
+	}
+	out := buf.String()
+
+	want := []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="32" height="24" viewBox="0 0 32 24">`,
+		`<rect width="100%" height="100%" fill="#102030"/>`,
+		`<polygon points="1,2 10.5,2 10.5,20 1,20" fill="#ff0000"/>`,
+		// Translucent colours are unpremultiplied.
+		`<polygon points="1,2 10.5,2 10.5,20 1,20" fill="none" stroke="#007f00" stroke-opacity="0.5020" stroke-width="2"/>`,
+		`<polygon points="1,2 10.5,2 10.5,20 1,20" fill="#0000ff" stroke="#000000" stroke-width="0.5"/>`,
+		`<polygon points="1,2 10.5,2 10.5,20 1,20" fill="#960000"/>`,
+	}
+	last := -1
+	for _, w := range want {
+		i := strings.Index(out, w)
+		if i < 0 {
+			t.Errorf("SVG lacks %s\n%s", w, out)
+			continue
+		}
+		if i < last {
+			t.Errorf("%s is out of draw order", w)
+		}
+		last = i
+	}
+
+	// WriteSVG starts the next document afresh.
+	buf.Reset()
+	if err := b.WriteSVG(&buf, 32, 24, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<polygon") || strings.Contains(buf.String(), "<rect") {
+		t.Errorf("second document is not empty:\n%s", buf.String())
+	}
+}
+
+// countingBatcher counts the polygons drawn by a DefaultBatcher.
+type countingBatcher struct {
+	DefaultBatcher
+	count int
+}
+
+func (b *countingBatcher) Draw(target *image.RGBA) {
+	b.count += len(b.commands)
+	b.DefaultBatcher.Draw(target)
+}
+
+func TestWorld_RenderToSVG(t *testing.T) {
+	w := NewWorld3d()
+	counter := &countingBatcher{DefaultBatcher: *NewDefaultBatcher(100)}
+	w.SetPolygonBatcher(counter)
+
+	cam := NewCamera(0, 0, -300, 0, 0, 0)
+	w.AddCamera(cam, 0, 0, -300)
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(0, 1, 0), 0.5)
+	w.AddObject(&Entity{Model: cube})
+
+	var buf bytes.Buffer
+	if err := w.RenderToSVG(&buf, 200, 150, color.RGBA{A: 255}); err != nil {
+		t.Fatalf("RenderToSVG: %v", err)
+	}
+
+	if w.batcher != counter {
+		t.Error("RenderToSVG did not restore the world's batcher")
+	}
+	if counter.count != 0 {
+		t.Error("RenderToSVG drew through the world's batcher")
+	}
+
+	// The document is well formed and holds one polygon per raster polygon.
+	dec := xml.NewDecoder(&buf)
+	polygons := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("invalid SVG: %v", err)
+			}
+			break
+		}
+		if el, ok := tok.(xml.StartElement); ok && el.Name.Local == "polygon" {
+			polygons++
+		}
+	}
+
+	w.Render(200, 150, color.RGBA{A: 255})
+	if polygons == 0 || polygons != counter.count {
+		t.Errorf("SVG has %d polygons; the raster render drew %d", polygons, counter.count)
+	}
+}
+
+// newEdgeWorld returns a world whose Gouraud sphere and textured cube both
+// cross the right edge of a 200 by 150 render.
+func newEdgeWorld() *World {
+	sphere := NewUVSphere(50, 12, 8, color.RGBA{R: 255, A: 255}, color.RGBA{R: 255, A: 255}, 2)
+	sphere.SetShadingMode(ShadingGouraud)
+	cube := NewCube()
+	cube.SetTexture(newTopBottomTexture())
+
+	w := NewWorld3d()
+	w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+	w.AddObject(&Entity{Model: sphere, X: 120, Y: -40})
+	w.AddObject(&Entity{Model: cube, X: 130, Y: 60})
+	return w
+}
+
+// pointsOutside counts the coordinate pairs matched by the first two groups
+// of point in out that lie beyond the width by height screen DefaultBatcher
+// clips to.
+func pointsOutside(t *testing.T, out string, point *regexp.Regexp, width, height float64) int {
+	t.Helper()
+	matches := point.FindAllStringSubmatch(out, -1)
+	if len(matches) == 0 {
+		t.Fatal("no points in the document")
+	}
+	outside := 0
+	for _, m := range matches {
+		x, _ := strconv.ParseFloat(m[1], 64)
+		y, _ := strconv.ParseFloat(m[2], 64)
+		if x < 0 || x > width+1 || y < 0 || y > height+1 {
+			outside++
+		}
+	}
+	return outside
+}
+
+func TestWorld_RenderToSVGClipsShaded(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newEdgeWorld().RenderToSVG(&buf, 200, 150, color.RGBA{A: 255}); err != nil {
+		t.Fatal(err)
+	}
+	point := regexp.MustCompile(`(-?[\d.]+),(-?[\d.]+)`)
+	if n := pointsOutside(t, buf.String(), point, 200, 150); n > 0 {
+		t.Errorf("%d SVG points lie off the screen", n)
+	}
+}
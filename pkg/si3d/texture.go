@@ -0,0 +1,247 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	imgdraw "image/draw"
+	"math"
+	"slices"
+)
+
+// UV is a texture coordinate. U runs from 0 at the left edge of the image to
+// 1 at the right, and V from 0 at the top to 1 at the bottom. Coordinates
+// outside 0..1 repeat the image.
+type UV struct {
+	U, V float64
+}
+
+// TextureFilter selects how a Texture is sampled between texel centres.
+type TextureFilter int
+
+const (
+	// TextureNearest takes the colour of the texel under the sample point.
+	TextureNearest TextureFilter = iota
+	// TextureBilinear blends the four texels nearest the sample point.
+	TextureBilinear
+)
+
+// Texture is an image mapped onto the faces of a model by their UVs.
+type Texture struct {
+	Filter TextureFilter
+
+	pix     *image.RGBA // premultiplied copy of the image, origin at 0, 0
+	average color.RGBA
+	opaque  bool
+}
+
+// NewTexture returns a texture holding a copy of img, so later changes to
+// img are not seen.
+func NewTexture(img image.Image, filter TextureFilter) *Texture {
+	bounds := img.Bounds()
+	pix := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	imgdraw.Draw(pix, pix.Bounds(), img, bounds.Min, imgdraw.Src)
+
+	t := &Texture{Filter: filter, pix: pix, opaque: pix.Opaque()}
+	if n := len(pix.Pix) / 4; n > 0 {
+		var sum [4]int
+		for i, v := range pix.Pix {
+			sum[i%4] += int(v)
+		}
+		t.average = color.RGBA{R: uint8(sum[0] / n), G: uint8(sum[1] / n), B: uint8(sum[2] / n), A: uint8(sum[3] / n)}
+	}
+	return t
+}
+
+// Bounds returns the size of the texture's image.
+func (t *Texture) Bounds() image.Rectangle {
+	return t.pix.Bounds()
+}
+
+// Sample returns the alpha-premultiplied colour of the texture at (u, v)
+// using its Filter.
+func (t *Texture) Sample(u, v float64) color.RGBA {
+	return t.sample(float32(u), float32(v))
+}
+
+func (t *Texture) sample(u, v float32) color.RGBA {
+	w, h := t.pix.Rect.Dx(), t.pix.Rect.Dy()
+	if w == 0 || h == 0 {
+		return color.RGBA{}
+	}
+
+	if t.Filter != TextureBilinear {
+		x := wrapTexel(floorInt(u*float32(w)), w)
+		y := wrapTexel(floorInt(v*float32(h)), h)
+		return t.pix.RGBAAt(x, y)
+	}
+
+	// Texel centres are at half-integer positions.
+	fx := u*float32(w) - 0.5
+	fy := v*float32(h) - 0.5
+	x0, y0 := floorInt(fx), floorInt(fy)
+	tx, ty := fx-float32(x0), fy-float32(y0)
+	x1, y1 := wrapTexel(x0+1, w), wrapTexel(y0+1, h)
+	x0, y0 = wrapTexel(x0, w), wrapTexel(y0, h)
+
+	c00, c10 := t.pix.RGBAAt(x0, y0), t.pix.RGBAAt(x1, y0)
+	c01, c11 := t.pix.RGBAAt(x0, y1), t.pix.RGBAAt(x1, y1)
+	lerp := func(a, b, c, d uint8) uint8 {
+		top := float32(a) + tx*(float32(b)-float32(a))
+		bottom := float32(c) + tx*(float32(d)-float32(c))
+		return uint8(top + ty*(bottom-top) + 0.5)
+	}
+	return color.RGBA{
+		R: lerp(c00.R, c10.R, c01.R, c11.R),
+		G: lerp(c00.G, c10.G, c01.G, c11.G),
+		B: lerp(c00.B, c10.B, c01.B, c11.B),
+		A: lerp(c00.A, c10.A, c01.A, c11.A),
+	}
+}
+
+// floorInt returns the floor of v, saturating far outside the int32 range so
+// that wrapping stays cheap.
+func floorInt(v float32) int {
+	f := math.Floor(float64(v))
+	if !(f > math.MinInt32) {
+		return 0
+	}
+	return int(min(f, math.MaxInt32))
+}
+
+// wrapTexel wraps a texel index into [0, n) so that the image repeats.
+func wrapTexel(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+// modulate scales an alpha-premultiplied texel by the light falling on it,
+// given as the colour a white surface is lit to. The texel keeps its alpha.
+func modulate(texel, light color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: uint8((uint32(texel.R)*uint32(light.R) + 127) / 255),
+		G: uint8((uint32(texel.G)*uint32(light.G) + 127) / 255),
+		B: uint8((uint32(texel.B)*uint32(light.B) + 127) / 255),
+		A: texel.A,
+	}
+}
+
+// SetTexture maps tex onto the model's faces that have a UV per corner, in
+// place of their colour. Faces without UVs keep their colour, and nil
+// removes the texture. Textured faces are lit like coloured ones, flat or
+// Gouraud shaded as the shading mode selects. Batchers that cannot map
+// textures fill them with the texture's average colour.
+func (o *Model) SetTexture(tex *Texture) {
+	o.texture = tex
+}
+
+func (o *Model) GetTexture() *Texture {
+	return o.texture
+}
+
+// textured reports whether a face with the given UVs and corner count is
+// drawn with the model's texture.
+func (o *Model) textured(uvs []UV, corners int) bool {
+	return o.texture != nil && !o.drawLinesOnly && len(uvs) == corners
+}
+
+// lightTexturedVertices returns the attributes of a textured polygon's
+// corners: its UV, its fog visibility and the light falling on it, as the
+// colour opaque white is lit to. Corners are lit with their vertex normals when vertexNormals
+// is non-nil and all alike with the face normal otherwise; unshaded
+// polygons get full light.
+func lightTexturedVertices(points []Vector3, uvs []UV, normal Vector3, vertexNormals []int, transNormals []Vector3, shade bool, ctx *RenderContext) []vertexAttrs {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	light := white
+	if shade && vertexNormals == nil {
+		light = ctx.shade(points[0], normal, white)
+	}
+
+	attrs := ctx.vertexAttrs[:0]
+	for i, point := range points {
+		c := light
+		if shade && vertexNormals != nil {
+			c = ctx.shade(point, transNormals[vertexNormals[i]], white)
+		}
+		attrs = append(attrs, vertexAttrs{
+			r: float64(c.R), g: float64(c.G), b: float64(c.B), a: float64(c.A),
+			u: uvs[i].U, v: uvs[i].V,
+			fog: ctx.fog.visibility(point.Z),
+		})
+	}
+	ctx.vertexAttrs = attrs
+	return attrs
+}
+
+// addTexturedPolygon frustum clips a textured camera-space polygon and adds
+// it to the batcher. Batchers that cannot map textures get a polygon shaded
+// with the texture's average colour.
+func addTexturedPolygon(batcher PolygonBatcher, points []Vector3, attrs []vertexAttrs, tex *Texture, proj *Projection, screenWidth, screenHeight float32, strokeClr color.RGBA, strokeWidth float32, hasStroke bool, ctx *RenderContext) {
+	if ctx.translucent != nil && !tex.opaque {
+		attrs := slices.Clone(attrs)
+		ctx.translucent.add(points, proj, func(points []Vector3, batcher PolygonBatcher, ctx *RenderContext) {
+			addTexturedPolygon(batcher, points, attrs, tex, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
+		})
+		return
+	}
+
+	clipped, clippedAttrs := proj.Frustum.clipPolygonAttrs(points, attrs, ctx)
+	if len(clipped) < 3 {
+		return
+	}
+
+	light := make([]color.RGBA, len(clipped))
+	for i, a := range clippedAttrs {
+		light[i] = color.RGBA{
+			R: uint8(math.Round(a.r)),
+			G: uint8(math.Round(a.g)),
+			B: uint8(math.Round(a.b)),
+			A: uint8(math.Round(a.a)),
+		}
+	}
+
+	var fog []float32
+	var fogClr color.RGBA
+	if ctx.fog != nil {
+		fogClr = ctx.fog.Color
+		fog = make([]float32, len(clipped))
+		for i, a := range clippedAttrs {
+			fog[i] = float32(a.fog)
+		}
+	}
+
+	texBatcher, ok := batcher.(TexturedPolygonBatcher)
+	if !ok {
+		for i, l := range light {
+			light[i] = modulate(tex.average, l)
+			if fog != nil {
+				light[i] = fogBlend(light[i], fogClr, float64(fog[i]))
+			}
+		}
+		addShadedPolygon(batcher, clipped, light, proj, screenWidth, screenHeight, strokeClr, strokeWidth, hasStroke, ctx)
+		return
+	}
+
+	xp := make([]float32, len(clipped))
+	yp := make([]float32, len(clipped))
+	uvs := make([]UV, len(clipped))
+	invZ := make([]float32, len(clipped))
+	for i, point := range clipped {
+		xp[i] = proj.ToScreenX(point.X, point.Z)
+		yp[i] = proj.ToScreenY(point.Y, point.Z)
+		uvs[i] = UV{U: clippedAttrs[i].u, V: clippedAttrs[i].v}
+		invZ[i] = proj.perspectiveWeight(point.Z)
+	}
+
+	var zp []float32
+	if _, ok := batcher.(DepthPolygonBatcher); ok {
+		zp = make([]float32, len(clipped))
+		for i, point := range clipped {
+			zp[i] = proj.Depth(point.Z)
+		}
+	}
+
+	texBatcher.AddTexturedPolygon(xp, yp, zp, uvs, invZ, light, fog, fogClr, tex, strokeClr, strokeWidth, hasStroke)
+}
@@ -0,0 +1,212 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// newStripeTexture returns a width by height texture whose columns are
+// shades of red, so the column a sample came from can be read back from R.
+func newStripeTexture(width, height int, filter TextureFilter) *Texture {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 20), A: 255})
+		}
+	}
+	return NewTexture(img, filter)
+}
+
+func TestTexture_Sample(t *testing.T) {
+	img := image.NewRGBA(image.Rect(5, 5, 7, 7))
+	img.SetRGBA(5, 5, color.RGBA{R: 200, A: 255})
+	img.SetRGBA(6, 5, color.RGBA{G: 200, A: 255})
+	img.SetRGBA(5, 6, color.RGBA{B: 200, A: 255})
+	img.SetRGBA(6, 6, color.RGBA{R: 200, G: 200, B: 200, A: 255})
+
+	tex := NewTexture(img, TextureNearest)
+	if got, want := tex.Sample(0.25, 0.25), (color.RGBA{R: 200, A: 255}); got != want {
+		t.Errorf("nearest top left = %v, want %v", got, want)
+	}
+	if got, want := tex.Sample(0.75, 0.25), (color.RGBA{G: 200, A: 255}); got != want {
+		t.Errorf("nearest top right = %v, want %v", got, want)
+	}
+	// Coordinates outside 0..1 repeat the image.
+	if got, want := tex.Sample(-0.25, 1.75), tex.Sample(0.75, 0.75); got != want {
+		t.Errorf("wrapped sample = %v, want %v", got, want)
+	}
+
+	// Bilinear filtering blends evenly between the four texel centres at the
+	// middle of the image.
+	tex.Filter = TextureBilinear
+	if got, want := tex.Sample(0.5, 0.5), (color.RGBA{R: 100, G: 100, B: 100, A: 255}); got != want {
+		t.Errorf("bilinear centre = %v, want %v", got, want)
+	}
+	// At a texel centre it is the texel itself.
+	if got, want := tex.Sample(0.25, 0.75), (color.RGBA{B: 200, A: 255}); got != want {
+		t.Errorf("bilinear texel centre = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultBatcher_TexturedPerspective(t *testing.T) {
+	tex := newStripeTexture(10, 1, TextureNearest)
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	// A quad whose right edge is four times as far away as its left edge.
+	// The centre of pixel 60 is t = 0.605 of the way across the screen, where
+	// the U interpolated with 1/z weights is 0.605*0.25 / (0.395 + 0.605*0.25),
+	// about 0.28, rather than the 0.605 of an affine mapping.
+	b := NewDefaultBatcher(1)
+	b.AddTexturedPolygon(
+		[]float32{0, 100, 100, 0}, []float32{0, 0, 2, 2}, nil,
+		[]UV{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, []float32{1, 0.25, 0.25, 1},
+		[]color.RGBA{white, white, white, white}, nil, color.RGBA{}, tex, color.RGBA{}, 0, false)
+	img := image.NewRGBA(image.Rect(0, 0, 100, 2))
+	b.Draw(img)
+
+	if got, want := img.RGBAAt(60, 0).R, uint8(2*20); got != want {
+		t.Errorf("middle pixel from texture column %d, want column %d", got/20, want/20)
+	}
+	// The light scales the texture.
+	half := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	b.AddTexturedPolygon(
+		[]float32{0, 100, 100, 0}, []float32{0, 0, 2, 2}, nil,
+		[]UV{{0.95, 0}, {0.95, 0}, {0.95, 1}, {0.95, 1}}, []float32{1, 1, 1, 1},
+		[]color.RGBA{half, half, half, half}, nil, color.RGBA{}, tex, color.RGBA{}, 0, false)
+	b.Draw(img)
+	if got, want := img.RGBAAt(10, 1).R, uint8((180*128+127)/255); got != want {
+		t.Errorf("half lit texel R = %d, want %d", got, want)
+	}
+}
+
+// newTopBottomTexture returns a texture that is red in its top half and blue
+// in its bottom half.
+func newTopBottomTexture() *Texture {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if y >= 2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return NewTexture(img, TextureNearest)
+}
+
+func TestModel_SetTexture(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		model *Model
+	}{
+		{"face list", NewCube()},
+		{"BSP", newBSPCube()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plain := renderEntities(&Entity{Model: tc.model})
+			tc.model.SetTexture(newTopBottomTexture())
+			got := renderEntities(&Entity{Model: tc.model})
+
+			// The front face shows the texture upright: red above the
+			// centre of the screen and blue below it.
+			above, below := got.RGBAAt(100, 65), got.RGBAAt(100, 85)
+			if above.R == 0 || above.B != 0 || below.B == 0 || below.R != 0 {
+				t.Errorf("front face above centre %v, below %v; want red then blue", above, below)
+			}
+			if differingPixels(got, plain) == 0 {
+				t.Error("textured cube renders the same as the plain one")
+			}
+
+			// Clones share the texture; clearing it restores the face colours.
+			if tc.model.Clone().GetTexture() == nil {
+				t.Error("clone lost the texture")
+			}
+			tc.model.SetTexture(nil)
+			if !compareImages(t, renderEntities(&Entity{Model: tc.model}), plain) {
+				t.Error("cube without its texture renders differently")
+			}
+		})
+	}
+}
+
+func TestModel_TextureDepthBatcher(t *testing.T) {
+	cube := NewCube()
+	cube.SetTexture(newTopBottomTexture())
+
+	render := func(batcher PolygonBatcher) *image.RGBA {
+		w := NewWorld3d()
+		w.SetPolygonBatcher(batcher)
+		w.AddCamera(NewCamera(0, 0, -300, 0, 0, 0), 0, 0, -300)
+		w.AddObject(&Entity{Model: cube})
+		return w.Render(200, 150, color.RGBA{A: 255})
+	}
+
+	got := render(NewDepthBatcher(100))
+	if above, below := got.RGBAAt(100, 65), got.RGBAAt(100, 85); above.R == 0 || below.B == 0 {
+		t.Errorf("depth batcher front face above centre %v, below %v; want red then blue", above, below)
+	}
+
+	// Vector batchers fill textured polygons with the average colour.
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	cmd := polygonCommand{colors: []color.RGBA{white, white, white}, tex: cube.GetTexture()}
+	if got, want := cmd.flatFill(), (color.RGBA{R: 127, B: 127, A: 255}); got != want {
+		t.Errorf("flat fill of a textured polygon = %v, want %v", got, want)
+	}
+}
+
+func TestPlane_SplitFaceUVs(t *testing.T) {
+	face := NewFace(nil, color.RGBA{A: 255}, Vector3{})
+	face.AddPointUV(-10, 0, 0, 0, 0)
+	face.AddPointUV(30, 0, 0, 1, 0)
+	face.AddPointUV(30, 10, 0, 1, 1)
+	face.AddPointUV(-10, 10, 0, 0, 1)
+	face.Finished(FACE_NORMAL)
+
+	// The plane x = 0 crosses a quarter of the way along U.
+	plane := NewPlaneFromPoint(NewVector3(0, 0, 0), NewVector3(1, 0, 0))
+	parts := plane.SplitFace(face)
+	if parts[0] == nil || parts[1] == nil {
+		t.Fatal("face was not split")
+	}
+	for _, part := range parts {
+		if len(part.UVs) != len(part.Points) {
+			t.Fatalf("split part has %d UVs for %d points", len(part.UVs), len(part.Points))
+		}
+		for i, p := range part.Points {
+			if want := (p.X + 10) / 40; math.Abs(part.UVs[i].U-want) > 1e-9 {
+				t.Errorf("point %v has U %v, want %v", p, part.UVs[i].U, want)
+			}
+		}
+	}
+}
+
+func TestGeneratorUVs(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		model *Model
+	}{
+		{"cube", NewCube()},
+		{"plane", NewSubdividedPlane(100, 100, color.RGBA{A: 255}, 3, false)},
+		{"plane triangles", NewSubdividedPlane(100, 100, color.RGBA{A: 255}, 3, true)},
+		{"sphere", NewUVSphere(50, 12, 8, color.RGBA{A: 255}, color.RGBA{A: 255}, 2)},
+		{"cylinder", NewCylinder(20, 50, 8, color.RGBA{A: 255})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			faces := tc.model.faces
+			for i := 0; i < faces.FaceCount(); i++ {
+				f := faces.GetFace(i)
+				if len(f.UVs) != len(f.Points) {
+					t.Fatalf("face %d has %d UVs for %d points", i, len(f.UVs), len(f.Points))
+				}
+				for _, uv := range f.UVs {
+					if uv.U < -1e-9 || uv.U > 1+1e-9 || uv.V < -1e-9 || uv.V > 1+1e-9 {
+						t.Fatalf("face %d has UV %v outside 0..1", i, uv)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package si3d
+
+import (
+	"image/color"
+	"slices"
+	"sort"
+)
+
+// translucentPolygon is a polygon held back by the transparency pass, with
+// the key it is sorted on and a function that draws it.
+type translucentPolygon struct {
+	depth float64
+	draw  func(batcher PolygonBatcher, ctx *RenderContext)
+}
+
+// translucentQueue collects the translucent polygons of a render so they can
+// be drawn after the opaque ones, farthest first. While a queue is set on
+// RenderContext.translucent the paint paths add translucent fills to it
+// instead of the batcher.
+type translucentQueue struct {
+	polys []translucentPolygon
+}
+
+// add queues draw to be called with a copy of the camera-space polygon
+// points. draw must copy any other slices it keeps. The depth key is the
+// squared distance of the polygon's centre from the camera for perspective
+// projections and its depth along the view axis for orthographic ones, as
+// distBetweenEntityAndCamera uses for entities.
+func (q *translucentQueue) add(points []Vector3, proj *Projection, draw func(points []Vector3, batcher PolygonBatcher, ctx *RenderContext)) {
+	if len(points) == 0 {
+		return
+	}
+	points = slices.Clone(points)
+
+	var cx, cy, cz float64
+	for _, p := range points {
+		cx += p.X
+		cy += p.Y
+		cz += p.Z
+	}
+	n := float64(len(points))
+	cx, cy, cz = cx/n, cy/n, cz/n
+
+	depth := cz
+	if !proj.Orthographic {
+		depth = cx*cx + cy*cy + cz*cz
+	}
+	q.polys = append(q.polys, translucentPolygon{
+		depth: depth,
+		draw: func(batcher PolygonBatcher, ctx *RenderContext) {
+			draw(points, batcher, ctx)
+		},
+	})
+}
+
+// flush draws the queued polygons into batcher, farthest first, and empties
+// the queue. Polygons at the same depth keep the order they were queued in.
+func (q *translucentQueue) flush(batcher PolygonBatcher, ctx *RenderContext) {
+	sort.SliceStable(q.polys, func(i, j int) bool {
+		return q.polys[i].depth > q.polys[j].depth
+	})
+	for _, p := range q.polys {
+		p.draw(batcher, ctx)
+	}
+	clear(q.polys)
+	q.polys = q.polys[:0]
+}
+
+// anyTranslucent reports whether any of colors is not fully opaque.
+func anyTranslucent(colors []color.RGBA) bool {
+	for _, c := range colors {
+		if c.A != 255 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package si3d
+
+import (
+	"image/color"
+	"testing"
+)
+
+// newGlassBox returns a box of translucent green, premultiplied.
+func newGlassBox(size float64) *Model {
+	return NewRectangle(size, size, size, color.RGBA{G: 100, A: 100})
+}
+
+func TestWorld_TranslucentEnclosure(t *testing.T) {
+	part := NewCube()
+	bare := renderEntities(&Entity{Model: part})
+
+	// The glass box's centre is farther from the camera than the part's, so
+	// ordering whole entities would draw the glass first and the part over
+	// it, untinted.
+	glass := &Entity{Model: newGlassBox(160), Z: 10}
+	got := renderEntities(&Entity{Model: part}, glass)
+
+	p, want := got.RGBAAt(100, 75), bare.RGBAAt(100, 75)
+	if p == want {
+		t.Fatalf("part inside the glass box drawn untinted as %v", p)
+	}
+	if p.G <= want.G {
+		t.Errorf("part seen through green glass is %v, want greener than %v", p, want)
+	}
+
+	// Where nothing lies behind the glass it blends over the background.
+	if edge := got.RGBAAt(100, 30); edge.G <= edge.R {
+		t.Errorf("glass over the background is %v, want a dim green", edge)
+	}
+}
+
+func TestWorld_TranslucentNested(t *testing.T) {
+	// A red glass box inside a green one. The green box's centre is the
+	// farther of the two, but its front wall is nearer than the red box, so
+	// sorting polygons rather than entities puts green over red.
+	red := &Entity{Model: NewRectangle(40, 40, 40, color.RGBA{R: 128, A: 128})}
+	green := &Entity{Model: newGlassBox(160), Z: 10}
+
+	// Seen through the green wall the red box is dimmed; drawn over the
+	// wall it would be at least as red as on its own.
+	alone := renderEntities(red).RGBAAt(100, 75)
+	got := renderEntities(red, green)
+	if p := got.RGBAAt(100, 75); p.R >= alone.R || p.G <= alone.G {
+		t.Errorf("overlap is %v, red box alone %v; want the green front wall over the red box", p, alone)
+	}
+}
+
+func TestTranslucentQueue_Flush(t *testing.T) {
+	proj := NewProjection(100, 100, 0, 0, 0, 0, 1, 0)
+	var order []string
+	q := &translucentQueue{}
+	for _, tc := range []struct {
+		name string
+		z    float64
+	}{{"middle", 50}, {"near", 10}, {"far", 90}} {
+		name := tc.name
+		points := []Vector3{NewVector3(0, 0, tc.z), NewVector3(1, 0, tc.z), NewVector3(0, 1, tc.z)}
+		q.add(points, proj, func(points []Vector3, batcher PolygonBatcher, ctx *RenderContext) {
+			order = append(order, name)
+		})
+	}
+
+	q.flush(nil, nil)
+	if len(order) != 3 || order[0] != "far" || order[1] != "middle" || order[2] != "near" {
+		t.Errorf("drawn in order %v, want far, middle, near", order)
+	}
+	if len(q.polys) != 0 {
+		t.Error("flush left polygons queued")
+	}
+}
@@ -0,0 +1,90 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+)
+
+// vectorBatcher is the DefaultBatcher the vector batchers record polygons
+// with. Vector formats cannot interpolate colours or map textures, so
+// shaded and textured polygons, which the paint paths hand over without
+// clipping them to the screen, are reduced to their flat fill and clipped
+// to the document when it is written, like the ordinary polygons the paint
+// paths clip themselves.
+type vectorBatcher struct {
+	DefaultBatcher
+	// doc holds the polygons drawn since the document was last written.
+	doc []polygonCommand
+}
+
+func newVectorBatcher(initialCap int) vectorBatcher {
+	return vectorBatcher{DefaultBatcher: *NewDefaultBatcher(initialCap)}
+}
+
+// AddShadedPolygon adds a polygon filled with the average of its vertex
+// colours. zp is ignored.
+func (b *vectorBatcher) AddShadedPolygon(xp, yp, zp []float32, colors []color.RGBA, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	b.addUnclipped(xp, yp, averageColor(colors), strokeClr, strokeWidth, hasStroke)
+}
+
+// AddTexturedPolygon adds a polygon filled with the average colour of tex,
+// lit and fogged by the average of its vertices' light and fog. zp, uvs and
+// invZ are ignored.
+func (b *vectorBatcher) AddTexturedPolygon(xp, yp, zp []float32, uvs []UV, invZ []float32, light []color.RGBA, fog []float32, fogClr color.RGBA, tex *Texture, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	textured := polygonCommand{colors: light, tex: tex, fog: fog, fogClr: fogClr}
+	b.addUnclipped(xp, yp, textured.flatFill(), strokeClr, strokeWidth, hasStroke)
+}
+
+// addUnclipped adds a flat polygon that still has to be clipped to the
+// screen.
+func (b *vectorBatcher) addUnclipped(xp, yp []float32, fillClr, strokeClr color.RGBA, strokeWidth float32, hasStroke bool) {
+	if len(xp) < 3 {
+		return
+	}
+	b.commands = append(b.commands, polygonCommand{
+		xp:        xp,
+		yp:        yp,
+		fillClr:   fillClr,
+		strokeClr: strokeClr,
+		strokeW:   strokeWidth,
+		hasFill:   true,
+		hasStroke: hasStroke,
+		unclipped: true,
+	})
+}
+
+// Draw appends the batched polygons to the document. target is not used and
+// may be nil.
+func (b *vectorBatcher) Draw(target *image.RGBA) {
+	b.doc = append(b.doc, b.commands...)
+	b.commands = b.commands[:0]
+}
+
+// takeDocument returns the polygons of a width by height document, in draw
+// order, with those added unclipped clipped to it, and starts the next
+// document afresh.
+func (b *vectorBatcher) takeDocument(width, height int) []polygonCommand {
+	doc := b.doc
+	b.doc = nil
+	for i := range doc {
+		cmd := &doc[i]
+		if !cmd.unclipped {
+			continue
+		}
+		points := make([]Point, len(cmd.xp))
+		for j := range cmd.xp {
+			points[j] = Point{X: cmd.xp[j], Y: cmd.yp[j]}
+		}
+		clipped := b.ClipPolygon(points, float32(width), float32(height))
+		if len(clipped) < 3 {
+			clipped = nil
+		}
+		cmd.xp = make([]float32, len(clipped))
+		cmd.yp = make([]float32, len(clipped))
+		for j, p := range clipped {
+			cmd.xp[j], cmd.yp[j] = p.X, p.Y
+		}
+		cmd.unclipped = false
+	}
+	return doc
+}
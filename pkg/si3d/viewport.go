@@ -0,0 +1,72 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	imgdraw "image/draw"
+)
+
+// Viewport is a camera and the rectangle of the target image it renders
+// into, as used for split-screen and multi-view renders.
+type Viewport struct {
+	Camera *Camera
+	// Rect is the viewport's area of the target image. The camera's
+	// projection is fitted to its size, and drawing is clipped to it.
+	Rect image.Rectangle
+	// Background, if not nil, fills Rect before the viewport is drawn.
+	// Otherwise the viewport is drawn over what is already there.
+	Background color.Color
+}
+
+// NewQuadViewports splits a width by height image into four viewports, in
+// reading order: top left, top right, bottom left and bottom right.
+func NewQuadViewports(width, height int, topLeft, topRight, bottomLeft, bottomRight *Camera) []Viewport {
+	midX, midY := width/2, height/2
+	return []Viewport{
+		{Camera: topLeft, Rect: image.Rect(0, 0, midX, midY)},
+		{Camera: topRight, Rect: image.Rect(midX, 0, width, midY)},
+		{Camera: bottomLeft, Rect: image.Rect(0, midY, midX, height)},
+		{Camera: bottomRight, Rect: image.Rect(midX, midY, width, height)},
+	}
+}
+
+// RenderViewports draws the world from each viewport's camera into its
+// rectangle of a new image of the given size, filled with bgColor first.
+// Viewports are drawn in order, so later ones cover earlier ones where they
+// overlap. At most one RenderOptions may be given; it applies to every
+// viewport.
+func (w *World) RenderViewports(width, height int, bgColor color.Color, viewports []Viewport, opts ...RenderOptions) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	imgdraw.Draw(img, img.Bounds(), &image.Uniform{bgColor}, image.Point{}, imgdraw.Src)
+
+	w.PaintViewports(img, viewports, opts...)
+	return img
+}
+
+// PaintViewports draws the world from each viewport's camera into its
+// rectangle of target. Each viewport is rendered as a separate image of its
+// own size, so nothing is drawn outside its rectangle, and the part of it
+// inside target is then copied in. Viewports without a camera are skipped.
+// Options are as for RenderViewports.
+func (w *World) PaintViewports(target *image.RGBA, viewports []Viewport, opts ...RenderOptions) {
+	o, restore := w.useOptions(opts)
+	defer restore()
+
+	for _, vp := range viewports {
+		visible := vp.Rect.Intersect(target.Bounds())
+		if vp.Camera == nil || visible.Empty() {
+			continue
+		}
+
+		size := vp.Rect.Size()
+		img := image.NewRGBA(image.Rectangle{Max: size})
+		if vp.Background != nil {
+			imgdraw.Draw(img, img.Bounds(), &image.Uniform{vp.Background}, image.Point{}, imgdraw.Src)
+		} else {
+			imgdraw.Draw(img, visible.Sub(vp.Rect.Min), target, visible.Min, imgdraw.Src)
+		}
+
+		w.paintImage(img, vp.Camera, o)
+		imgdraw.Draw(target, visible, img, visible.Min.Sub(vp.Rect.Min), imgdraw.Src)
+	}
+}
@@ -0,0 +1,130 @@
+package si3d
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newViewportTestWorld returns a world holding a rotated cube and four
+// cameras looking at it from the front, top, side and at an angle.
+func newViewportTestWorld() *World {
+	w := NewWorld3d()
+
+	front := NewCamera(0, 0, 0, 0, 0, 0)
+	front.SetOrthographic(300)
+	w.AddCamera(front, 0, 0, -400)
+
+	top := NewCamera(0, 0, 0, 0, 0, 0)
+	top.SetOrthographic(300)
+	w.AddCamera(top, 0, -400, 0)
+	top.LookAt(NewVector3(0, 0, 0), NewVector3(0, 0, 1))
+
+	side := NewCamera(0, 0, 0, 0, 0, 0)
+	side.SetOrthographic(300)
+	w.AddCamera(side, 400, 0, 0)
+	side.LookAt(NewVector3(0, 0, 0), NewVector3(0, -1, 0))
+
+	persp := NewCamera(0, 0, 0, 0, 0, 0)
+	w.AddCamera(persp, 250, -200, -250)
+	persp.LookAt(NewVector3(0, 0, 0), NewVector3(0, -1, 0))
+
+	cube := NewCube()
+	cube.Transform.Rotate(NewVector3(0, 1, 0), 0.3)
+	w.AddObject(&Entity{Model: cube})
+	return w
+}
+
+func TestWorld_SetCurrentCamera(t *testing.T) {
+	w := NewWorld3d()
+	if w.CurrentCamera() != nil {
+		t.Error("a world without cameras has a current camera")
+	}
+
+	a := NewCamera(0, 0, 0, 0, 0, 0)
+	b := NewCamera(0, 0, 0, 0, 0, 0)
+	w.AddCamera(a, 0, 0, -300)
+	w.AddCamera(b, 0, 0, -300)
+	if w.CurrentCamera() != b {
+		t.Error("AddCamera did not select the new camera")
+	}
+
+	w.SetCurrentCamera(0)
+	if w.CurrentCamera() != a {
+		t.Error("SetCurrentCamera(0) did not select the first camera")
+	}
+	w.SetCurrentCamera(2)
+	if w.CurrentCamera() != a {
+		t.Error("SetCurrentCamera accepted an index out of range")
+	}
+	if cams := w.Cameras(); len(cams) != 2 || cams[0] != a || cams[1] != b {
+		t.Errorf("Cameras() = %v", cams)
+	}
+}
+
+func TestWorld_RenderViewports(t *testing.T) {
+	const width, height = 160, 120
+	bg := color.RGBA{R: 20, G: 20, B: 40, A: 255}
+	w := newViewportTestWorld()
+	cams := w.Cameras()
+
+	viewports := NewQuadViewports(width, height, cams[0], cams[1], cams[2], cams[3])
+	got := w.RenderViewports(width, height, bg, viewports)
+
+	// Each quadrant matches a render of that size from its camera, so the
+	// projection is fitted to the viewport and nothing spills into the others.
+	for i, vp := range viewports {
+		w.SetCurrentCamera(i)
+		want := w.Render(vp.Rect.Dx(), vp.Rect.Dy(), bg)
+		quadrant := image.NewRGBA(image.Rectangle{Max: vp.Rect.Size()})
+		for y := 0; y < vp.Rect.Dy(); y++ {
+			for x := 0; x < vp.Rect.Dx(); x++ {
+				quadrant.SetRGBA(x, y, got.RGBAAt(vp.Rect.Min.X+x, vp.Rect.Min.Y+y))
+			}
+		}
+		if !compareImages(t, quadrant, want) {
+			t.Errorf("viewport %d differs from a render from its camera", i)
+		}
+		if isUniform(want) {
+			t.Errorf("viewport %d shows nothing", i)
+		}
+	}
+}
+
+func TestWorld_PaintViewportsClipping(t *testing.T) {
+	bg := color.RGBA{R: 20, G: 20, B: 40, A: 255}
+	w := newViewportTestWorld()
+	w.SetCurrentCamera(3)
+	full := w.Render(120, 90, bg)
+
+	// A viewport hanging off the top left of the image shows the matching
+	// part of a full render, and its background stays inside it.
+	target := image.NewRGBA(image.Rect(0, 0, 100, 80))
+	vp := Viewport{Camera: w.Cameras()[3], Rect: image.Rect(-30, -20, 90, 70), Background: bg}
+	w.PaintViewports(target, []Viewport{vp})
+
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 100; x++ {
+			got := target.RGBAAt(x, y)
+			want := color.RGBA{}
+			if image.Pt(x, y).In(vp.Rect) {
+				want = full.RGBAAt(x+30, y+20)
+			}
+			if got != want {
+				t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func isUniform(img *image.RGBA) bool {
+	first := img.RGBAAt(0, 0)
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.RGBAAt(x, y) != first {
+				return false
+			}
+		}
+	}
+	return true
+}
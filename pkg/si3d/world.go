@@ -2,6 +2,8 @@ package si3d
 
 import (
 	"image"
+	"image/color"
+	"iter"
 	"sort"
 )
 
@@ -12,6 +14,37 @@ type RenderContext struct {
 	BufferPoints []Point
 	BufferFloatX []float32
 	BufferFloatY []float32
+	ClipBufA     []Vector3
+	ClipBufB     []Vector3
+	attrBufA     []vertexAttrs
+	attrBufB     []vertexAttrs
+	vertexAttrs  []vertexAttrs
+	vertexColors []color.RGBA
+	// transPoints and transNormals hold the camera-space points and normals
+	// of the model being painted.
+	transPoints  []Vector3
+	transNormals []Vector3
+	// texture is the texture of the model being painted, or nil.
+	texture *Texture
+	// translucent collects translucent polygons during the transparency
+	// pass; when it is nil they are drawn straight away.
+	translucent *translucentQueue
+	// fog is the fog of the current render, or nil for none.
+	fog *Fog
+	// pixelScale is the number of pixels drawn per output pixel in each
+	// direction, above 1 in supersampled renders. Outline widths and
+	// principal point offsets are scaled by it. Zero, as in a RenderContext
+	// not made by NewRenderContext, is taken as 1; see scale.
+	pixelScale float32
+	// Lighting is the camera-space lighting of the current render. nil
+	// selects the built-in camera light.
+	Lighting *Lighting
+	// workers is the number of goroutines the faces of large models without
+	// a BSP tree are painted with; see World.SetParallelism.
+	workers int
+	// Options are the options of the current render. World.Render and
+	// RenderToImage set them; callers of Model.PaintObject set them here.
+	Options RenderOptions
 }
 
 func NewRenderContext() *RenderContext {
@@ -20,14 +53,81 @@ func NewRenderContext() *RenderContext {
 		BufferPoints: make([]Point, 0, 100),
 		BufferFloatX: make([]float32, 0, 100),
 		BufferFloatY: make([]float32, 0, 100),
+		ClipBufA:     make([]Vector3, 0, 100),
+		ClipBufB:     make([]Vector3, 0, 100),
+		attrBufA:     make([]vertexAttrs, 0, 100),
+		attrBufB:     make([]vertexAttrs, 0, 100),
+		vertexAttrs:  make([]vertexAttrs, 0, 100),
+		vertexColors: make([]color.RGBA, 0, 100),
+		pixelScale:   1,
 	}
 }
 
-type Entity struct {
-	Model   *Model
-	X, Y, Z float64
+// outlineWidth returns the width polygon outlines are stroked with.
+func (ctx *RenderContext) outlineWidth() float32 {
+	width := ctx.Options.OutlineWidth
+	if width <= 0 {
+		width = 1
+	}
+	return width * ctx.scale()
+}
+
+// scale returns the number of pixels drawn per output pixel in each
+// direction, treating an unset pixelScale as 1.
+func (ctx *RenderContext) scale() float32 {
+	if ctx.pixelScale <= 0 {
+		return 1
+	}
+	return ctx.pixelScale
+}
+
+// outline returns the colour, width and presence of polygon outlines, given
+// the paint path's default colour.
+func (ctx *RenderContext) outline(defaultClr color.RGBA) (color.RGBA, float32, bool) {
+	if ctx.Options.NoOutlines {
+		return color.RGBA{}, 0, false
+	}
+	clr := defaultClr
+	if ctx.Options.OutlineColor != nil {
+		clr = *ctx.Options.OutlineColor
+	}
+	return clr, ctx.outlineWidth(), true
 }
 
+// wireframeColor returns the colour of the lines of a lines-only polygon
+// whose shaded colour is polyColor, at depth z.
+func (ctx *RenderContext) wireframeColor(polyColor color.RGBA, z float64) color.RGBA {
+	if ctx.Options.WireframeColor == nil {
+		return polyColor
+	}
+	return ctx.fog.apply(*ctx.Options.WireframeColor, z)
+}
+
+// shade lights a surface as Lighting.Shade does, with the ambient light and
+// minimum of the built-in light taken from the render options, or returns
+// base unchanged when shading is turned off.
+func (ctx *RenderContext) shade(point, normal Vector3, base color.RGBA) color.RGBA {
+	if ctx.Options.NoShading {
+		return base
+	}
+	if ctx.Lighting == nil {
+		ambient, minimum := defaultAmbient, uint8(defaultShadingMinimum)
+		if ctx.Options.Ambient != nil {
+			ambient = *ctx.Options.Ambient
+		}
+		if ctx.Options.ShadingMinimum != nil {
+			minimum = *ctx.Options.ShadingMinimum
+		}
+		return getColor(point, normal, base, ambient, int(minimum))
+	}
+	return ctx.Lighting.Shade(point, normal, base)
+}
+
+// World holds the entities, cameras and lights of a scene. A World renders
+// one frame at a time through its own batcher and RenderContext, so render
+// concurrent views with one World each. Models, and the entities holding
+// them, may be shared by Worlds rendering at the same time, as rendering
+// transforms them into the World's RenderContext and leaves them unchanged.
 type World struct {
 	entities      []*Entity
 	cameras       []*Camera
@@ -38,6 +138,11 @@ type World struct {
 	entitiesDrawLast []*Entity
 	batcher          PolygonBatcher
 	ctx              *RenderContext
+	lights           []*Light
+	ambientLight     color.RGBA
+	// translucent is the transparency pass queue, reused between renders.
+	translucent translucentQueue
+	fog         Fog
 }
 
 func NewWorld3d() *World {
@@ -45,60 +150,236 @@ func NewWorld3d() *World {
 		currentCamera: -1,
 		batcher:       NewDefaultBatcher(5000),
 		ctx:           NewRenderContext(),
+		ambientLight:  DefaultAmbientLight,
 	}
 }
 
+// AddObject adds e to the world's main draw list. Only root entities are
+// added: a nil e, one already in the world and one attached to a parent,
+// which draws it, are ignored, here and by AddObjectDrawFirst and
+// AddObjectDrawLast.
 func (w *World) AddObject(e *Entity) {
-	w.entities = append(w.entities, e)
+	w.addObject(&w.entities, e)
 }
 
 // AddObjectDrawFirst
 func (w *World) AddObjectDrawFirst(e *Entity) {
-	w.entitiesDrawFirst = append(w.entitiesDrawFirst, e)
+	w.addObject(&w.entitiesDrawFirst, e)
 }
 
 // AddObjectDrawLast
 func (w *World) AddObjectDrawLast(e *Entity) {
-	w.entitiesDrawLast = append(w.entitiesDrawLast, e)
+	w.addObject(&w.entitiesDrawLast, e)
+}
+
+// addObject appends e to list if it is a root entity not yet in the world.
+func (w *World) addObject(list *[]*Entity, e *Entity) {
+	if e == nil || e.parent != nil || w.contains(e) {
+		return
+	}
+	e.assignID()
+	*list = append(*list, e)
+}
+
+// RemoveEntity removes an entity, with its children, from whichever draw
+// list holds it, or detaches it from its parent if it is a child. It
+// reports whether the entity was found; it never finds a nil e.
+func (w *World) RemoveEntity(e *Entity) bool {
+	if e == nil {
+		return false
+	}
+	found := false
+	for _, list := range []*[]*Entity{&w.entitiesDrawFirst, &w.entities, &w.entitiesDrawLast} {
+		for i, other := range *list {
+			if other == e {
+				*list = append((*list)[:i], (*list)[i+1:]...)
+				found = true
+				break
+			}
+		}
+	}
+	if p := e.parent; p != nil && w.contains(p) {
+		p.RemoveChild(e)
+		found = true
+	}
+	return found
+}
+
+// contains reports whether e is in the world, directly or below another
+// entity.
+func (w *World) contains(e *Entity) bool {
+	for e.parent != nil {
+		e = e.parent
+	}
+	for root := range w.Entities() {
+		if root == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Entities iterates over the entities added to the world, in the order they
+// are drawn: the draw-first list, the main list, then the draw-last list.
+// Children are reached through Entity.Children. Entities attached to
+// another entity since they were added are left to their parent.
+func (w *World) Entities() iter.Seq[*Entity] {
+	return func(yield func(*Entity) bool) {
+		for _, list := range [][]*Entity{w.entitiesDrawFirst, w.entities, w.entitiesDrawLast} {
+			for _, e := range list {
+				if e.parent != nil {
+					continue
+				}
+				if !yield(e) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// FindByName returns the first entity called name, searching each entity
+// and then its descendants in the order of Entities, or nil if there is
+// none.
+func (w *World) FindByName(name string) *Entity {
+	return w.find(func(e *Entity) bool { return e.Name == name })
+}
+
+// FindByID returns the entity in the world with the given ID, or nil.
+func (w *World) FindByID(id uint64) *Entity {
+	if id == 0 {
+		return nil
+	}
+	return w.find(func(e *Entity) bool { return e.id == id })
+}
+
+func (w *World) find(match func(*Entity) bool) *Entity {
+	for e := range w.Entities() {
+		if found := e.find(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// AddLight registers a light. While a World has no lights it is lit by the
+// built-in spotlight attached to the camera.
+func (w *World) AddLight(l *Light) {
+	w.lights = append(w.lights, l)
+}
+
+// RemoveLight unregisters a light previously added with AddLight.
+func (w *World) RemoveLight(l *Light) {
+	for i, light := range w.lights {
+		if light == l {
+			w.lights = append(w.lights[:i], w.lights[i+1:]...)
+			return
+		}
+	}
+}
+
+// ClearLights removes all lights, returning to the built-in camera light.
+func (w *World) ClearLights() {
+	w.lights = nil
+}
+
+// Lights returns the registered lights.
+func (w *World) Lights() []*Light {
+	return w.lights
+}
+
+// SetAmbientLight sets the colour of the light reaching every surface
+// regardless of the registered lights. It has no effect without lights.
+func (w *World) SetAmbientLight(c color.RGBA) {
+	w.ambientLight = c
 }
 
 func (w *World) SetPolygonBatcher(b PolygonBatcher) {
 	w.batcher = b
 }
 
+// SetParallelism spreads rendering over up to workers goroutines; workers
+// <= 1, the default, renders serially. The output is pixel-identical to a
+// serial render.
+//
+// Models without a BSP tree and with thousands of faces, such as terrain,
+// are split into runs of faces that are culled, clipped, shaded and
+// projected concurrently, each run with its own RenderContext and batcher,
+// and joined back in order. This applies to this package's batchers; a
+// batcher of another type set with SetPolygonBatcher is always fed
+// serially. BSP models are traversed serially, as their draw order depends
+// on the camera.
+//
+// The batch is then rasterised in horizontal bands, each clipped to its own
+// rows, if the world's batcher is a *DefaultBatcher; see
+// DefaultBatcher.SetParallelism. A DepthBatcher rasterises serially.
+func (w *World) SetParallelism(workers int) {
+	w.ctx.workers = workers
+	if b, ok := w.batcher.(*DefaultBatcher); ok {
+		b.SetParallelism(workers)
+	}
+}
+
 func (w *World) AddCamera(c *Camera, x, y, z float64) {
 	c.SetCameraPosition(x, y, z)
 	w.cameras = append(w.cameras, c)
 	w.currentCamera = len(w.cameras) - 1
 }
 
-func paint(batcher PolygonBatcher, xsize, ysize int, e *Entity, cam *Camera, ctx *RenderContext) {
-	objToWorld := TransMatrix(e.X, e.Y, e.Z)
+// SetCurrentCamera selects the camera, by the order cameras were added in,
+// that Render and PaintObjects draw from. Out of range indices are ignored.
+func (w *World) SetCurrentCamera(index int) {
+	if index >= 0 && index < len(w.cameras) {
+		w.currentCamera = index
+	}
+}
+
+// CurrentCamera returns the camera the world renders from, or nil if it has
+// none.
+func (w *World) CurrentCamera() *Camera {
+	if w.currentCamera == -1 || len(w.cameras) == 0 {
+		return nil
+	}
+	return w.cameras[w.currentCamera]
+}
 
-	objToCam := cam.camMatrixRev.MultiplyBy(objToWorld)
-	e.Model.ApplyMatrixTemp(objToCam)
-	e.Model.PaintObject(batcher, xsize/2, ysize/2, true, float32(xsize), float32(ysize), cam.GetNearPlane(), ctx)
+// Cameras returns the cameras added with AddCamera.
+func (w *World) Cameras() []*Camera {
+	return w.cameras
 }
 
+func paint(batcher PolygonBatcher, xsize, ysize int, e *Entity, cam *Camera, proj *Projection, ctx *RenderContext) {
+	objToCam := cam.camMatrixRev.MultiplyBy(e.WorldMatrix())
+	transPoints, transNormals := e.Model.transformInto(objToCam, ctx)
+	e.Model.paintTransformed(batcher, xsize/2, ysize/2, true, float32(xsize), float32(ysize), proj, ctx, transPoints, transNormals)
+}
+
+// distBetweenEntityAndCamera returns the key entities are sorted on, larger
+// values being drawn first. For perspective cameras this is the squared
+// distance to the camera; orthographic view rays are parallel, so there it is
+// the depth along the view axis instead.
 func distBetweenEntityAndCamera(e *Entity, cam *Camera) float64 {
 	objX, objY, objZ := e.X, e.Y, e.Z
+	if e.Transform != nil || e.parent != nil {
+		pos := e.WorldPosition()
+		objX, objY, objZ = pos.X, pos.Y, pos.Z
+	}
+
+	if cam.Orthographic {
+		m := cam.camMatrixRev
+		return m.ThisMatrix[0][2]*objX + m.ThisMatrix[1][2]*objY + m.ThisMatrix[2][2]*objZ + m.ThisMatrix[3][2]
+	}
+
 	pos := cam.GetPosition()
 	camX, camY, camZ := pos.X, pos.Y, pos.Z
 
 	return (objX-camX)*(objX-camX) + (objY-camY)*(objY-camY) + (objZ-camZ)*(objZ-camZ)
 }
 
-func draw(batcher PolygonBatcher, xsize, ysize int, entities []*Entity, cam *Camera, ctx *RenderContext) {
+func draw(batcher PolygonBatcher, xsize, ysize int, entities []*Entity, cam *Camera, proj *Projection, ctx *RenderContext) {
 	// draw background objects
 	for _, e := range entities {
-		objToWorld := TransMatrix(
-			e.X,
-			e.Y,
-			e.Z,
-		)
-		objToCam := cam.camMatrixRev.MultiplyBy(objToWorld)
-		e.Model.ApplyMatrixTemp(objToCam)
-		e.Model.PaintObject(batcher, xsize/2, ysize/2, true, float32(xsize), float32(ysize), cam.GetNearPlane(), ctx)
+		paint(batcher, xsize, ysize, e, cam, proj, ctx)
 	}
 
 }
@@ -126,15 +407,30 @@ func sortObjects(entities []*Entity, cam *Camera) {
 	}
 }
 
+// PaintObjects paints the world from the current camera onto a screen of
+// xsize by ysize pixels and draws the batch into target. Entities added
+// with AddObject are painted farthest first, except that their translucent
+// polygons are held back until all the opaque ones are drawn and are then
+// drawn farthest first in a pass of their own. Entities added with
+// AddObjectDrawFirst and AddObjectDrawLast keep their own ordering around
+// them.
 func (w *World) PaintObjects(target *image.RGBA, xsize, ysize int) {
 
 	if w.currentCamera == -1 || len(w.cameras) == 0 {
 		return
 	}
-	cam := w.cameras[w.currentCamera]
+	w.paintCamera(target, w.cameras[w.currentCamera], xsize, ysize)
+}
+
+// paintCamera paints the world as seen by cam onto a screen of xsize by ysize
+// pixels, drawing the batch into target.
+func (w *World) paintCamera(target *image.RGBA, cam *Camera, xsize, ysize int) {
+	proj := cam.scaledProjection(float32(xsize), float32(ysize), float64(w.ctx.scale()))
+	w.ctx.Lighting = NewLighting(w.lights, w.ambientLight, cam.camMatrixRev)
+	w.ctx.fog = w.fog.active()
 
-	entitiesToDraw := make([]*Entity, len(w.entities))
-	copy(entitiesToDraw, w.entities)
+	entitiesToDraw := drawableEntities(w.entities)
+	drawFirst := drawableEntities(w.entitiesDrawFirst)
 
 	type sortableEntity struct {
 		e      *Entity
@@ -158,36 +454,35 @@ func (w *World) PaintObjects(target *image.RGBA, xsize, ysize int) {
 	}
 
 	// // Draw objects that should be drawn first (that dont have a direction vector)
-	for _, e := range w.entitiesDrawFirst {
+	for _, e := range drawFirst {
 		if e.Model.hasObjectDirection {
 			continue
 		}
 
-		paint(w.batcher, xsize, ysize, e, cam, w.ctx)
+		paint(w.batcher, xsize, ysize, e, cam, proj, w.ctx)
 	}
 
 	// get objects which are poing at and from the camera. objects pointing towards the camera are drawn first
 	backgroundObjects := make([]*Entity, 0)
 	foregroundObjects := make([]*Entity, 0)
-	for _, e := range w.entitiesDrawFirst {
+	for _, e := range drawFirst {
 		if !e.Model.hasObjectDirection {
 			continue
 		}
 
-		objToWorld := TransMatrix(e.X, e.Y, e.Z)
-		objToCam := cam.camMatrixRev.MultiplyBy(objToWorld)
+		objToCam := cam.camMatrixRev.MultiplyBy(e.WorldMatrix())
 
 		// Transform the direction vector (the direction the cusion is pointing) to camera space
 		direction := objToCam.RotateVector3(e.Model.objectDirection)
 		direction = direction.Normalize()
 
-		e.Model.ApplyMatrixTemp(objToCam)
-		pointX := e.Model.transFaceMesh.Points[0].X
-		pointY := e.Model.transFaceMesh.Points[0].Y
-		pointZ := e.Model.transFaceMesh.Points[0].Z
+		transPoints, _ := e.Model.transformInto(objToCam, w.ctx)
+		pointX := transPoints[0].X
+		pointY := transPoints[0].Y
+		pointZ := transPoints[0].Z
 
 		plane := NewPlaneFromPoint(NewVector3(pointX, pointY, pointZ), direction)
-		where := plane.PointOnPlane(0, 0, 0)
+		where := proj.EyeSide(plane)
 
 		if where > 0 {
 			backgroundObjects = append(backgroundObjects, e)
@@ -198,31 +493,25 @@ func (w *World) PaintObjects(target *image.RGBA, xsize, ysize int) {
 
 	// sort background objects by distance to camera and draw them
 	sortObjects(backgroundObjects, cam)
-	draw(w.batcher, xsize, ysize, backgroundObjects, cam, w.ctx)
+	draw(w.batcher, xsize, ysize, backgroundObjects, cam, proj, w.ctx)
 
+	// Translucent polygons of the main entities are held back until all
+	// their opaque geometry is drawn, then drawn farthest first, so they
+	// blend over whatever lies behind them whichever entity it belongs to.
+	w.ctx.translucent = &w.translucent
 	for _, e := range entitiesToDraw {
-		// object space to world space trans
-		objToWorld := TransMatrix(
-			e.X,
-			e.Y,
-			e.Z,
-		)
-
-		// objToCam := objToWorld.MultiplyBy(cam.camMatrixRev)
-		objToCam := cam.camMatrixRev.MultiplyBy(objToWorld)
-
-		// then, world space to camera space
-		e.Model.ApplyMatrixTemp(objToCam)
-		e.Model.PaintObject(w.batcher, xsize/2, ysize/2, true, float32(xsize), float32(ysize), cam.GetNearPlane(), w.ctx)
+		paint(w.batcher, xsize, ysize, e, cam, proj, w.ctx)
 	}
+	w.ctx.translucent = nil
+	w.translucent.flush(w.batcher, w.ctx)
 
 	// draw foreground objects
 	sortObjects(foregroundObjects, cam)
-	draw(w.batcher, xsize, ysize, foregroundObjects, cam, w.ctx)
+	draw(w.batcher, xsize, ysize, foregroundObjects, cam, proj, w.ctx)
 
 	// Draw objects that should be drawn last
-	for _, e := range w.entitiesDrawLast {
-		paint(w.batcher, xsize, ysize, e, cam, w.ctx)
+	for _, e := range drawableEntities(w.entitiesDrawLast) {
+		paint(w.batcher, xsize, ysize, e, cam, proj, w.ctx)
 	}
 
 	w.batcher.Draw(target)
@@ -127,3 +127,138 @@ func TestWorld_SetPolygonBatcher(t *testing.T) {
 		t.Error("SetPolygonBatcher: expected MockBatcher.Draw to be called during PaintObjects")
 	}
 }
+
+func TestWorld_PaintObjectsOrthographic(t *testing.T) {
+	w := NewWorld3d()
+	cam := NewOrthographicCamera(OrthoFront, NewVector3(0, 0, 0), 500, 200)
+	pos := cam.GetPosition()
+	w.AddCamera(cam, pos.X, pos.Y, pos.Z)
+	w.AddObject(&Entity{Model: NewCube(), X: 0, Y: 0, Z: 0})
+
+	bg := color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	img := w.Render(200, 200, bg)
+
+	// The 80 unit cube must cover exactly 80x80 pixels, independent of depth.
+	minX, maxX, minY, maxY := 200, -1, 200, -1
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			if img.RGBAAt(x, y) != bg {
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
+		}
+	}
+	if width, height := maxX-minX+1, maxY-minY+1; width < 79 || width > 82 || height < 79 || height > 82 {
+		t.Errorf("expected an 80x80 silhouette, got %dx%d", width, height)
+	}
+}
+
+func TestWorld_EntityManagement(t *testing.T) {
+	w := NewWorld3d()
+	ground := &Entity{Model: NewModel(), Name: "ground"}
+	car := &Entity{Name: "car"}
+	wheel := &Entity{Model: NewCube(), Name: "wheel"}
+	car.AddChild(wheel)
+	sky := &Entity{Model: NewModel(), Name: "sky"}
+	w.AddObject(car)
+	w.AddObjectDrawFirst(ground)
+	w.AddObjectDrawLast(sky)
+
+	ids := map[uint64]bool{}
+	for _, e := range []*Entity{ground, car, wheel, sky} {
+		if e.ID() == 0 || ids[e.ID()] {
+			t.Errorf("entity %q has ID %d", e.Name, e.ID())
+		}
+		ids[e.ID()] = true
+	}
+
+	var order []string
+	for e := range w.Entities() {
+		order = append(order, e.Name)
+	}
+	if len(order) != 3 || order[0] != "ground" || order[1] != "car" || order[2] != "sky" {
+		t.Errorf("Entities() = %v, want [ground car sky]", order)
+	}
+
+	if w.FindByName("wheel") != wheel || w.FindByName("sky") != sky || w.FindByName("boat") != nil {
+		t.Error("FindByName did not find entities by name")
+	}
+	if w.FindByID(wheel.ID()) != wheel || w.FindByID(0) != nil {
+		t.Error("FindByID did not find entities by ID")
+	}
+
+	// Removing a child detaches it; removing a root drops its subtree.
+	if !w.RemoveEntity(wheel) || wheel.Parent() != nil || w.FindByName("wheel") != nil {
+		t.Error("RemoveEntity did not detach the child")
+	}
+	car.AddChild(wheel)
+	if !w.RemoveEntity(car) || w.FindByName("car") != nil || w.FindByName("wheel") != nil {
+		t.Error("RemoveEntity did not remove the entity and its children")
+	}
+	if w.RemoveEntity(car) || w.RemoveEntity(wheel) {
+		t.Error("RemoveEntity reported removing an entity not in the world")
+	}
+	if len(w.entitiesDrawFirst) != 1 || len(w.entities) != 0 || len(w.entitiesDrawLast) != 1 {
+		t.Error("RemoveEntity changed the other draw lists")
+	}
+
+	// nil entities are ignored rather than added or looked for.
+	w.AddObject(nil)
+	w.AddObjectDrawFirst(nil)
+	w.AddObjectDrawLast(nil)
+	if w.RemoveEntity(nil) {
+		t.Error("RemoveEntity reported removing nil")
+	}
+	if len(w.entitiesDrawFirst) != 1 || len(w.entities) != 0 || len(w.entitiesDrawLast) != 1 {
+		t.Error("nil entities were added to the draw lists")
+	}
+}
+
+func TestWorld_EntitiesDrawnOnce(t *testing.T) {
+	drawn := func(w *World) []*Entity {
+		var all []*Entity
+		for _, list := range [][]*Entity{w.entitiesDrawFirst, w.entities, w.entitiesDrawLast} {
+			all = append(all, drawableEntities(list)...)
+		}
+		return all
+	}
+	roots := func(w *World) int {
+		n := 0
+		for range w.Entities() {
+			n++
+		}
+		return n
+	}
+
+	// A child added to the world after its parent, or an entity added
+	// twice, is ignored.
+	car := &Entity{Model: NewCube()}
+	wheel := &Entity{Model: NewCube(), X: 60}
+	car.AddChild(wheel)
+	w := NewWorld3d()
+	w.AddObject(car)
+	w.AddObject(wheel)
+	w.AddObjectDrawLast(wheel)
+	w.AddObjectDrawFirst(car)
+	if got := drawn(w); len(got) != 2 || roots(w) != 1 {
+		t.Errorf("parent then child: %d entities drawn from %d roots, want 2 from 1", len(got), roots(w))
+	}
+	if !compareImages(t, renderEntities(car, wheel), renderEntities(car)) {
+		t.Error("adding a child to the world changed the render")
+	}
+
+	// An entity in the world later attached to another is drawn once,
+	// through its new parent.
+	car = &Entity{Model: NewCube()}
+	wheel = &Entity{Model: NewCube(), X: 60}
+	w = NewWorld3d()
+	w.AddObject(wheel)
+	w.AddObject(car)
+	car.AddChild(wheel)
+	if got := drawn(w); len(got) != 2 || roots(w) != 1 {
+		t.Errorf("child then parent: %d entities drawn from %d roots, want 2 from 1", len(got), roots(w))
+	}
+	if !w.RemoveEntity(wheel) || wheel.Parent() != nil || len(w.entities) != 1 {
+		t.Error("RemoveEntity did not drop the attached entity from the world")
+	}
+}